@@ -0,0 +1,27 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cfoust/cy/pkg/cy"
+)
+
+// checkConfig validates the configuration file at `path`, or the one cy would
+// load if `path` is empty, without connecting to a server.
+func checkConfig(path string) error {
+	if len(path) == 0 {
+		path = cy.FindConfig()
+	}
+
+	if len(path) == 0 {
+		return fmt.Errorf("no configuration file found")
+	}
+
+	return cy.CheckConfig(context.Background(), cy.Options{
+		Config:     path,
+		PluginDir:  cy.FindPluginDir(),
+		Shell:      getShell(),
+		HideSplash: true,
+	})
+}
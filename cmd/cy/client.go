@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/cfoust/cy/pkg/geom"
+	"github.com/cfoust/cy/pkg/geom/tty"
 	P "github.com/cfoust/cy/pkg/io/protocol"
 	"github.com/cfoust/cy/pkg/io/ws"
 	"github.com/cfoust/cy/pkg/mux"
@@ -103,7 +104,26 @@ func getEnv() map[string]string {
 	return env
 }
 
-func buildHandshake(profile termenv.Profile) (*P.HandshakeMessage, error) {
+// getCapabilities converts the capabilities found by probing the terminal
+// into the form sent in the handshake.
+func getCapabilities(caps tty.Capabilities) *P.Capabilities {
+	if !caps.Probed {
+		return nil
+	}
+
+	return &P.Capabilities{
+		Version:             caps.Version,
+		Attributes:          caps.Attributes,
+		SecondaryAttributes: caps.SecondaryAttributes,
+		Sixel:               caps.Sixel,
+		KittyGraphics:       caps.KittyGraphics,
+		KittyKeyboard:       caps.KittyKeyboard,
+		BracketedPaste:      caps.BracketedPaste,
+		SyncUpdate:          caps.SyncUpdate,
+	}
+}
+
+func buildHandshake(caps tty.Capabilities) (*P.HandshakeMessage, error) {
 	columns, rows, err := term.GetSize(int(os.Stdin.Fd()))
 	if err != nil {
 		return nil, err
@@ -116,14 +136,19 @@ func buildHandshake(profile termenv.Profile) (*P.HandshakeMessage, error) {
 			R: rows,
 			C: columns,
 		},
-		Profile: profile,
+		Profile:      caps.Profile,
+		Capabilities: getCapabilities(caps),
 	}, nil
 }
 
 func poll(conn Connection) error {
 	output := termenv.NewOutput(os.Stdout)
 
-	handshake, err := buildHandshake(output.Profile)
+	// Probing is best-effort; if it fails, the server guesses what the
+	// terminal supports
+	caps, input, _ := cli.Probe(os.Stdin, os.Stdout, output.Profile)
+
+	handshake, err := buildHandshake(caps)
 	if err != nil {
 		return err
 	}
@@ -164,6 +189,8 @@ func poll(conn Connection) error {
 		writer,
 		os.Stdin,
 		os.Stdout,
+		cli.WithInput(input),
+		cli.WithCapabilities(caps),
 	)
 }
 
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"net/http"
 	"os"
 	"runtime/pprof"
@@ -16,10 +17,17 @@ var CLI struct {
 
 	CPU   string `help:"Save a CPU performance report to the given path." name:"perf-file" optional:"" default:""`
 	Trace string `help:"Save a trace report to the given path." name:"trace-file" optional:"" default:""`
+
+	Connect struct {
+	} `cmd:"" default:"1" help:"Connect to the cy server, starting it if necessary."`
+
+	CheckConfig struct {
+		File string `arg:"" optional:"" type:"path" help:"The configuration file to check. Defaults to the one cy would load."`
+	} `cmd:"" help:"Check a configuration file for errors without connecting to a server."`
 }
 
 func main() {
-	kong.Parse(&CLI,
+	ctx := kong.Parse(&CLI,
 		kong.Name("cy"),
 		kong.Description("the time traveling terminal multiplexer"),
 		kong.UsageOnError(),
@@ -28,6 +36,15 @@ func main() {
 			Summary: true,
 		}))
 
+	if ctx.Command() == "check-config" || ctx.Command() == "check-config <file>" {
+		err := checkConfig(CLI.CheckConfig.File)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	var socketPath string
 
 	if envPath, ok := os.LookupEnv(CY_SOCKET_ENV); ok {
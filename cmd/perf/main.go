@@ -56,6 +56,7 @@ func main() {
 		if err != nil {
 			panic(err)
 		}
+		defer reader.Close()
 
 		for {
 			event, err := reader.Read()
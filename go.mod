@@ -3,33 +3,39 @@ module github.com/cfoust/cy
 go 1.21
 
 require (
+	github.com/alecthomas/kong v0.8.1
 	github.com/charmbracelet/bubbles v0.16.1
 	github.com/charmbracelet/bubbletea v0.24.2
+	github.com/charmbracelet/glamour v0.6.0
 	github.com/charmbracelet/lipgloss v0.12.1
+	github.com/creack/pty v1.1.18
 	github.com/danielgatis/go-vte v1.0.4
+	github.com/iancoleman/strcase v0.3.0
 	github.com/mattn/go-runewidth v0.0.15
 	github.com/muesli/cancelreader v0.2.2
 	github.com/muesli/termenv v0.15.2
+	github.com/rivo/uniseg v0.4.7
 	github.com/rs/zerolog v1.29.1
 	github.com/sasha-s/go-deadlock v0.3.1
+	github.com/sevlyar/go-daemon v0.1.6
+	github.com/stretchr/testify v1.8.3
+	github.com/ugorji/go/codec v1.2.11
 	github.com/xo/terminfo v0.0.0-20220910002029-abceb7e1c41e
 	golang.org/x/sync v0.1.0
+	golang.org/x/term v0.13.0
+	nhooyr.io/websocket v1.8.7
 )
 
 require (
 	github.com/alecthomas/chroma v0.10.0 // indirect
-	github.com/alecthomas/kong v0.8.1 // indirect
 	github.com/atotto/clipboard v0.1.4 // indirect
 	github.com/aymanbagabas/go-osc52/v2 v2.0.1 // indirect
 	github.com/aymerick/douceur v0.2.0 // indirect
-	github.com/charmbracelet/glamour v0.6.0 // indirect
 	github.com/charmbracelet/x/ansi v0.1.4 // indirect
 	github.com/containerd/console v1.0.4-0.20230313162750-1ae8d489ac81 // indirect
-	github.com/creack/pty v1.1.18 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/dlclark/regexp2 v1.4.0 // indirect
 	github.com/gorilla/css v1.0.0 // indirect
-	github.com/iancoleman/strcase v0.3.0 // indirect
 	github.com/kardianos/osext v0.0.0-20190222173326-2bc1f35cddc0 // indirect
 	github.com/klauspost/compress v1.10.3 // indirect
 	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
@@ -42,17 +48,11 @@ require (
 	github.com/olekukonko/tablewriter v0.0.5 // indirect
 	github.com/petermattis/goid v0.0.0-20230516130339-69c5d00fc54d // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	github.com/rivo/uniseg v0.4.7 // indirect
-	github.com/sevlyar/go-daemon v0.1.6 // indirect
-	github.com/stretchr/testify v1.8.3 // indirect
-	github.com/ugorji/go/codec v1.2.11 // indirect
 	github.com/yuin/goldmark v1.6.0 // indirect
 	github.com/yuin/goldmark-emoji v1.0.1 // indirect
 	golang.org/x/net v0.17.0 // indirect
 	golang.org/x/sys v0.19.0 // indirect
-	golang.org/x/term v0.13.0 // indirect
 	golang.org/x/text v0.13.0 // indirect
 	gopkg.in/sevlyar/go-daemon.v0 v0.1.6 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
-	nhooyr.io/websocket v1.8.7 // indirect
 )
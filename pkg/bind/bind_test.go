@@ -93,3 +93,105 @@ func TestIdle(t *testing.T) {
 
 	assert.Equal(t, engine.getState(), []string{})
 }
+
+func TestPaste(t *testing.T) {
+	engine := NewEngine[int]()
+	go engine.Poll(context.Background())
+
+	scope := NewScope[int](nil)
+	scope.Set(
+		[]interface{}{"a"},
+		2,
+	)
+
+	engine.SetScopes(scope)
+	<-engine.Recv()
+
+	// A paste split across two writes should produce a single message
+	// that does not trigger any binds
+	go func() {
+		engine.Input([]byte("\x1b[200~a"))
+		engine.Input([]byte("b\x1b[201~"))
+	}()
+
+	event := <-engine.Recv()
+	assert.Equal(t, taro.KeyMsg{
+		Type:  taro.KeyRunes,
+		Runes: []rune("ab"),
+		Paste: true,
+	}, event)
+}
+
+// recvKey returns the next key that the engine passes through.
+func recvKey(engine *Engine[int]) taro.KeyMsg {
+	for event := range engine.Recv() {
+		if msg, ok := event.(taro.KeyMsg); ok {
+			return msg
+		}
+	}
+	return taro.KeyMsg{}
+}
+
+func TestPasteTimeout(t *testing.T) {
+	engine := NewEngine[int]()
+	go engine.Poll(context.Background())
+	engine.SetScopes(NewScope[int](nil))
+	<-engine.Recv()
+
+	// A paste that does not end in time is passed on as pasted text
+	go engine.Input([]byte("\x1b[200~b"))
+
+	assert.Equal(t, taro.KeyMsg{
+		Type:  taro.KeyRunes,
+		Runes: []rune("b"),
+		Paste: true,
+	}, recvKey(engine))
+
+	// The rest of it is too, and its end is dropped
+	go engine.Input([]byte("c\x1b[201~d"))
+
+	assert.Equal(t, taro.KeyMsg{
+		Type:  taro.KeyRunes,
+		Runes: []rune("c"),
+		Paste: true,
+	}, recvKey(engine))
+	assert.Equal(t, taro.KeyMsg{
+		Type:  taro.KeyRunes,
+		Runes: []rune("d"),
+	}, recvKey(engine))
+}
+
+func TestPasteLimit(t *testing.T) {
+	engine := NewEngine[int]()
+	go engine.Poll(context.Background())
+	engine.SetScopes(NewScope[int](nil))
+	<-engine.Recv()
+
+	// So is one that is too large to hold
+	data := make([]byte, MAX_PASTE_BYTES)
+	for i := range data {
+		data[i] = 'b'
+	}
+	go engine.Input(append([]byte("\x1b[200~"), data...))
+
+	msg := recvKey(engine)
+	assert.True(t, msg.Paste)
+	assert.Equal(t, MAX_PASTE_BYTES, len(msg.Runes))
+	assert.Empty(t, engine.paste)
+
+	// The end of the paste may be split across writes
+	go func() {
+		engine.Input([]byte("cc\x1b[2"))
+		engine.Input([]byte("01~d"))
+	}()
+
+	assert.Equal(t, taro.KeyMsg{
+		Type:  taro.KeyRunes,
+		Runes: []rune("cc"),
+		Paste: true,
+	}, recvKey(engine))
+	assert.Equal(t, taro.KeyMsg{
+		Type:  taro.KeyRunes,
+		Runes: []rune("d"),
+	}, recvKey(engine))
+}
@@ -1,6 +1,7 @@
 package bind
 
 import (
+	"bytes"
 	"context"
 	"time"
 
@@ -11,6 +12,15 @@ import (
 	"github.com/sasha-s/go-deadlock"
 )
 
+const (
+	// The largest bracketed paste that will be held while waiting for
+	// the rest of it to arrive
+	MAX_PASTE_BYTES = 1024 * 1024
+	// How long to wait for the end of a bracketed paste before treating
+	// it as normal input
+	PASTE_TIMEOUT = 500 * time.Millisecond
+)
+
 func NewScope[T any](source interface{}) *trie.Trie[T] {
 	return trie.New[T](source)
 }
@@ -59,13 +69,22 @@ type Engine[T any] struct {
 
 	// Holds the sequence of keys the user has entered
 	state []string
+
+	// Holds the beginning of a bracketed paste that has not yet finished
+	paste []byte
+	// Track the timeout for the rest of a bracketed paste to arrive
+	pasteTimeout util.Lifetime
+	// Whether a bracketed paste was passed on before it ended, in which
+	// case input up to the end of the paste is also pasted text
+	pasteOpen bool
 }
 
 func NewEngine[T any]() *Engine[T] {
 	return &Engine[T]{
-		in:         make(chan input),
-		out:        make(chan Event, 100),
-		keyTimeout: util.NewLifetime(context.Background()),
+		in:           make(chan input),
+		out:          make(chan Event, 100),
+		keyTimeout:   util.NewLifetime(context.Background()),
+		pasteTimeout: util.NewLifetime(context.Background()),
 	}
 }
 
@@ -134,6 +153,12 @@ func (e *Engine[T]) processKey(ctx context.Context, in input) (consumed bool) {
 		return
 	}
 
+	// Pasted text should never trigger a binding
+	if key.Paste {
+		e.out <- in
+		return
+	}
+
 	e.RLock()
 	state := e.state
 	scopes := e.scopes
@@ -221,9 +246,154 @@ func (e *Engine[T]) Poll(ctx context.Context) {
 	}
 }
 
+// sendPaste produces a single event for `data`, text that was pasted.
+func (e *Engine[T]) sendPaste(data []byte) {
+	if len(data) == 0 {
+		return
+	}
+
+	e.in <- taro.KeyMsg{
+		Type:  taro.KeyRunes,
+		Runes: []rune(string(data)),
+		Paste: true,
+	}
+}
+
+// startPasteTimeout calls `onTimeout` if no more input arrives within
+// PASTE_TIMEOUT. It must be called with the engine locked.
+func (e *Engine[T]) startPasteTimeout(onTimeout func()) {
+	if !e.pasteTimeout.IsDone() {
+		e.pasteTimeout.Cancel()
+	}
+	e.pasteTimeout = util.NewLifetime(context.Background())
+	timeout := e.pasteTimeout
+
+	go func() {
+		timer := time.NewTimer(PASTE_TIMEOUT)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			onTimeout()
+		case <-timeout.Ctx().Done():
+			return
+		}
+	}()
+}
+
+// flushPaste passes on the beginning of a bracketed paste that has not
+// finished in time as pasted text. The rest of the paste may still arrive.
+func (e *Engine[T]) flushPaste() {
+	e.Lock()
+	paste := e.paste
+	e.paste = nil
+	e.Unlock()
+
+	if len(paste) == 0 {
+		return
+	}
+
+	e.sendPaste(taro.TrimPasteStart(paste))
+	e.openPaste()
+}
+
+// holdPaste keeps `data`, the beginning of a bracketed paste, until the rest
+// of the paste arrives.
+func (e *Engine[T]) holdPaste(data []byte) {
+	e.Lock()
+	e.paste = append([]byte{}, data...)
+	e.startPasteTimeout(e.flushPaste)
+	e.Unlock()
+}
+
+// openPaste records that a bracketed paste was passed on before it ended.
+// Input up to the end of the paste is also treated as pasted text, unless
+// none arrives within PASTE_TIMEOUT.
+func (e *Engine[T]) openPaste() {
+	e.Lock()
+	e.pasteOpen = true
+	e.startPasteTimeout(e.closePaste)
+	e.Unlock()
+}
+
+// closePaste stops waiting for the end of a bracketed paste that was
+// passed on before it ended.
+func (e *Engine[T]) closePaste() {
+	e.Lock()
+	e.pasteOpen = false
+	rest := e.paste
+	e.paste = nil
+	e.Unlock()
+
+	e.sendPaste(rest)
+}
+
+// continuePaste handles input received while a bracketed paste is open. It
+// returns the input that follows the end of the paste, if it ended.
+func (e *Engine[T]) continuePaste(data []byte) (rest []byte, ended bool) {
+	end := bytes.Index(data, []byte(taro.PASTE_END))
+	if end != -1 {
+		e.Lock()
+		e.pasteOpen = false
+		e.pasteTimeout.Cancel()
+		e.Unlock()
+
+		e.sendPaste(data[:end])
+		return data[end+len(taro.PASTE_END):], true
+	}
+
+	// The end of the paste may be split across writes
+	held := 0
+	for n := len(taro.PASTE_END) - 1; n > 0; n-- {
+		if bytes.HasSuffix(data, []byte(taro.PASTE_END[:n])) {
+			held = n
+			break
+		}
+	}
+
+	e.sendPaste(data[:len(data)-held])
+
+	e.Lock()
+	e.paste = append([]byte{}, data[len(data)-held:]...)
+	e.startPasteTimeout(e.closePaste)
+	e.Unlock()
+	return nil, false
+}
+
 // Process input and produce events.
 func (e *Engine[T]) Input(data []byte) {
+	e.Lock()
+	if len(e.paste) > 0 {
+		data = append(e.paste, data...)
+		e.paste = nil
+	}
+	if !e.pasteTimeout.IsDone() {
+		e.pasteTimeout.Cancel()
+	}
+	pasteOpen := e.pasteOpen
+	e.Unlock()
+
+	if pasteOpen {
+		var ended bool
+		data, ended = e.continuePaste(data)
+		if !ended {
+			return
+		}
+	}
+
 	for i, w := 0, 0; i < len(data); i += w {
+		// Bracketed pastes can be split across several writes, but we
+		// don't wait forever for a paste that never ends
+		if taro.IsPartialPaste(data[i:]) {
+			if len(data)-i <= MAX_PASTE_BYTES {
+				e.holdPaste(data[i:])
+				return
+			}
+
+			e.sendPaste(taro.TrimPasteStart(data[i:]))
+			e.openPaste()
+			return
+		}
+
 		var msg taro.Msg
 		w, msg = taro.DetectOneMsg(data[i:])
 		e.in <- msg
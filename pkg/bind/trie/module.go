@@ -356,6 +356,54 @@ func (t *Trie[T]) Remap(from, to []interface{}) {
 	}
 }
 
+// copy returns a deep copy of `t`. The caller must hold `t`'s lock.
+func (t *Trie[T]) copy() *Trie[T] {
+	result := New[T](t.source)
+
+	for key, next := range t.next {
+		if child, ok := next.(*Trie[T]); ok {
+			next = child.copy()
+		}
+		result.next[key] = next
+	}
+
+	for pattern, re := range t.nextRe {
+		next := re.next
+		if child, ok := next.(*Trie[T]); ok {
+			next = child.copy()
+		}
+		result.nextRe[pattern] = &Regex{
+			Pattern:  re.Pattern,
+			compiled: re.compiled,
+			next:     next,
+		}
+	}
+
+	return result
+}
+
+// Clone returns a copy of `t` that does not share any of its mappings, so
+// that changes to one do not affect the other.
+func (t *Trie[T]) Clone() *Trie[T] {
+	t.RLock()
+	defer t.RUnlock()
+
+	return t.copy()
+}
+
+// Restore replaces all of the mappings in `t` with those in `other`, which is
+// typically a Trie produced by Clone.
+func (t *Trie[T]) Restore(other *Trie[T]) {
+	other.RLock()
+	result := other.copy()
+	other.RUnlock()
+
+	t.Lock()
+	t.next = result.next
+	t.nextRe = result.nextRe
+	t.Unlock()
+}
+
 func (t *Trie[T]) Source() interface{} {
 	return t.source
 }
@@ -94,3 +94,33 @@ func TestRegex(t *testing.T) {
 	})
 	require.Equal(t, false, matched)
 }
+
+func TestClone(t *testing.T) {
+	trie := New[int](nil)
+	trie.Set([]interface{}{"one", "two"}, 1)
+	trie.Set([]interface{}{re("[abc]"), "t"}, 2)
+
+	clone := trie.Clone()
+	require.Equal(t, 2, len(clone.Leaves()))
+
+	// Changes to the original do not affect the clone
+	trie.Set([]interface{}{"one", "three"}, 3)
+	trie.Clear([]interface{}{re("[abc]")})
+	require.Equal(t, 2, len(trie.Leaves()))
+	_, _, matched := clone.Get([]string{"a", "t"})
+	require.True(t, matched)
+	_, _, matched = clone.Get([]string{"one", "three"})
+	require.False(t, matched)
+
+	trie.Restore(clone)
+	value, _, matched := trie.Get([]string{"b", "t"})
+	require.True(t, matched)
+	require.Equal(t, 2, value)
+	_, _, matched = trie.Get([]string{"one", "three"})
+	require.False(t, matched)
+
+	// Restoring copies the mappings
+	trie.Set([]interface{}{"four"}, 4)
+	_, _, matched = clone.Get([]string{"four"})
+	require.False(t, matched)
+}
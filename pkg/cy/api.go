@@ -1,7 +1,12 @@
 package cy
 
 import (
+	"context"
+	"fmt"
+	"time"
+
 	"github.com/cfoust/cy/pkg/janet"
+	"github.com/cfoust/cy/pkg/taro"
 )
 
 import _ "embed"
@@ -36,16 +41,88 @@ func (c *CyModule) ReloadConfig() error {
 	return c.cy.reloadConfig()
 }
 
-func (c *CyModule) Paste(user interface{}) {
+type PasteParams struct {
+	Register string
+}
+
+func (c *CyModule) Paste(
+	user interface{},
+	named *janet.Named[PasteParams],
+) {
 	client, ok := user.(*Client)
 	if !ok {
 		return
 	}
 
 	buffer := client.buffer
+	if register := named.Values().Register; len(register) > 0 {
+		buffer, _ = c.cy.registers.Get(register)
+	}
+
 	if len(buffer) == 0 {
 		return
 	}
 
-	client.binds.Input([]byte(buffer))
+	client.binds.InputMessage(taro.KeyMsg{
+		Type:  taro.KeyRunes,
+		Runes: []rune(buffer),
+		Paste: true,
+	})
+}
+
+type RestoreStateParams struct {
+	Screens bool
+}
+
+func (c *CyModule) SaveState(ctx context.Context, path string) error {
+	if c.cy.dryRun {
+		return nil
+	}
+
+	return c.cy.saveState(ctx, path)
+}
+
+func (c *CyModule) RestoreState(
+	ctx context.Context,
+	path string,
+	named *janet.Named[RestoreStateParams],
+) error {
+	params := named.Values()
+	if c.cy.dryRun {
+		return nil
+	}
+
+	return c.cy.restoreState(ctx, path, params.Screens)
+}
+
+type AutosaveStateParams struct {
+	Interval int
+}
+
+func (c *CyModule) AutosaveState(
+	path *string,
+	named *janet.Named[AutosaveStateParams],
+) error {
+	params := named.WithDefault(AutosaveStateParams{
+		Interval: 60000,
+	})
+
+	if path == nil {
+		c.cy.setAutosave("", 0)
+		return nil
+	}
+
+	if params.Interval <= 0 {
+		return fmt.Errorf("interval must be greater than zero")
+	}
+
+	if c.cy.dryRun {
+		return nil
+	}
+
+	c.cy.setAutosave(
+		*path,
+		time.Duration(params.Interval)*time.Millisecond,
+	)
+	return nil
 }
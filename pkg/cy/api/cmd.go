@@ -2,15 +2,24 @@ package api
 
 import (
 	"fmt"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/cfoust/cy/pkg/bind"
 	"github.com/cfoust/cy/pkg/cy/cmd"
+	"github.com/cfoust/cy/pkg/emu"
 	"github.com/cfoust/cy/pkg/janet"
 	"github.com/cfoust/cy/pkg/mux/screen/tree"
 	"github.com/cfoust/cy/pkg/mux/stream"
 	"github.com/cfoust/cy/pkg/replay"
 	"github.com/cfoust/cy/pkg/replay/detect"
+	"github.com/cfoust/cy/pkg/replay/player"
+	"github.com/cfoust/cy/pkg/sessions"
+	"github.com/cfoust/cy/pkg/sessions/search"
 	"github.com/cfoust/cy/pkg/util"
+
+	"github.com/sasha-s/go-deadlock"
 )
 
 type CmdParams struct {
@@ -20,10 +29,28 @@ type CmdParams struct {
 	Path    string
 }
 
+// MAX_CACHED_FILES is the number of .borg files whose commands
+// (cmd/file-history) keeps in memory.
+const MAX_CACHED_FILES = 32
+
 type CmdModule struct {
 	Lifetime             util.Lifetime
 	Tree                 *tree.Tree
 	TimeBinds, CopyBinds *bind.BindScope
+	// If true, (cmd/new) creates panes that do not run a command
+	DryRun bool
+
+	fileLock deadlock.Mutex
+	// The commands in .borg files that were read recently, with the most
+	// recently used file last in fileOrder
+	files     map[string]fileHistory
+	fileOrder []string
+}
+
+type fileHistory struct {
+	modTime  time.Time
+	size     int64
+	commands []Command
 }
 
 func (c *CmdModule) New(
@@ -47,19 +74,32 @@ func (c *CmdModule) New(
 		Command: command,
 	})
 
-	replayable, err := cmd.New(
-		c.Lifetime.Ctx(),
-		stream.CmdOptions{
-			Command:   values.Command,
-			Args:      values.Args,
-			Directory: values.Path,
-		},
-		group.Params().DataDirectory(),
-		c.TimeBinds,
-		c.CopyBinds,
-	)
-	if err != nil {
-		return 0, err
+	var replayable *replay.Replayable
+	if c.DryRun {
+		reader := stream.NewReader()
+		replayable = replay.NewReplayable(
+			c.Lifetime.Ctx(),
+			reader,
+			reader,
+			c.TimeBinds,
+			c.CopyBinds,
+		)
+	} else {
+		replayable, err = cmd.New(
+			c.Lifetime.Ctx(),
+			stream.CmdOptions{
+				Command:   values.Command,
+				Args:      values.Args,
+				Directory: values.Path,
+			},
+			group.Params().DataDirectory(),
+			c.TimeBinds,
+			c.CopyBinds,
+			emu.WithScrollback(group.Params().ScrollbackLines()),
+		)
+		if err != nil {
+			return 0, err
+		}
 	}
 
 	pane := group.NewPane(c.Lifetime.Ctx(), replayable)
@@ -112,3 +152,209 @@ func (c *CmdModule) Commands(id *janet.Value) (*[]detect.Command, error) {
 	commands := r.Commands()
 	return &commands, nil
 }
+
+// Command is a command that was run in the pane with ID `Id`, along with
+// everything cy knows about it.
+type Command struct {
+	Id tree.NodeID
+	// The index of the command in (cmd/commands)
+	Index  int
+	Text   string
+	Input  []search.Selection
+	Output search.Selection
+	// The text the command printed
+	OutputText string
+	Pending    bool
+	Prompted   int
+	Executed   int
+	Completed  int
+	// The times at which the command was executed and finished
+	StartTime string
+	EndTime   string
+	// The number of seconds the command took to run
+	Duration  float64
+	ExitCode  *int
+	Directory string
+}
+
+func newCommand(
+	id tree.NodeID,
+	index int,
+	events []sessions.Event,
+	command detect.Command,
+	output []string,
+) Command {
+	result := Command{
+		Id:         id,
+		Index:      index,
+		Text:       command.Text,
+		Input:      command.Input,
+		Output:     command.Output,
+		OutputText: strings.Join(output, "\n"),
+		Pending:    command.Pending,
+		Prompted:   command.Prompted,
+		Executed:   command.Executed,
+		Completed:  command.Completed,
+		ExitCode:   command.ExitCode,
+		Directory:  command.Directory,
+	}
+
+	isValid := func(index int) bool {
+		return index >= 0 && index < len(events)
+	}
+	if !isValid(command.Executed) || !isValid(command.Completed) {
+		return result
+	}
+
+	start := events[command.Executed].Stamp
+	end := events[command.Completed].Stamp
+	result.StartTime = start.Format(time.RFC3339)
+	result.EndTime = end.Format(time.RFC3339)
+	result.Duration = end.Sub(start).Seconds()
+	return result
+}
+
+func (c *CmdModule) History(id *janet.Value) ([]Command, error) {
+	defer id.Free()
+
+	pane, err := resolvePane(c.Tree, id)
+	if err != nil {
+		return nil, err
+	}
+
+	r, ok := pane.Screen().(*replay.Replayable)
+	if !ok {
+		return nil, fmt.Errorf("pane was not a cmd")
+	}
+
+	return getHistory(
+		pane.Id(),
+		r.Events(),
+		r.Commands(),
+		r.Outputs,
+	), nil
+}
+
+// getHistory converts the `commands` detected in `events` into Commands.
+// `getOutputs` is used to read the output of all of the commands at once.
+func getHistory(
+	id tree.NodeID,
+	events []sessions.Event,
+	commands []detect.Command,
+	getOutputs func([]detect.Command) [][]string,
+) []Command {
+	outputs := getOutputs(commands)
+	history := make([]Command, 0, len(commands))
+	for index, command := range commands {
+		var output []string
+		if !command.Pending {
+			output = outputs[index]
+		}
+
+		history = append(history, newCommand(
+			id,
+			index,
+			events,
+			command,
+			output,
+		))
+	}
+
+	return history
+}
+
+// getCachedFile returns the commands in the .borg file at `path` if it has
+// not changed since it was last read.
+func (c *CmdModule) getCachedFile(path string, info os.FileInfo) ([]Command, bool) {
+	c.fileLock.Lock()
+	defer c.fileLock.Unlock()
+
+	cached, ok := c.files[path]
+	if !ok || !cached.modTime.Equal(info.ModTime()) || cached.size != info.Size() {
+		return nil, false
+	}
+
+	c.touchFile(path)
+	return cached.commands, true
+}
+
+// touchFile marks `path` as the most recently used file. The caller must
+// hold fileLock.
+func (c *CmdModule) touchFile(path string) {
+	for i, other := range c.fileOrder {
+		if other == path {
+			c.fileOrder = append(c.fileOrder[:i], c.fileOrder[i+1:]...)
+			break
+		}
+	}
+	c.fileOrder = append(c.fileOrder, path)
+}
+
+func (c *CmdModule) cacheFile(path string, info os.FileInfo, commands []Command) {
+	c.fileLock.Lock()
+	defer c.fileLock.Unlock()
+
+	if c.files == nil {
+		c.files = make(map[string]fileHistory)
+	}
+
+	c.files[path] = fileHistory{
+		modTime:  info.ModTime(),
+		size:     info.Size(),
+		commands: commands,
+	}
+	c.touchFile(path)
+
+	for len(c.fileOrder) > MAX_CACHED_FILES {
+		delete(c.files, c.fileOrder[0])
+		c.fileOrder = c.fileOrder[1:]
+	}
+}
+
+func (c *CmdModule) FileHistory(path string) ([]Command, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if commands, ok := c.getCachedFile(path, info); ok {
+		return commands, nil
+	}
+
+	events, err := sessions.ReadAll(path)
+	if err != nil {
+		return nil, err
+	}
+
+	p := player.FromEvents(events)
+	commands := getHistory(0, events, p.Commands(), p.Outputs)
+	c.cacheFile(path, info, commands)
+	return commands, nil
+}
+
+func (c *CmdModule) LastOutput(id *janet.Value) (*string, error) {
+	defer id.Free()
+
+	pane, err := resolvePane(c.Tree, id)
+	if err != nil {
+		return nil, err
+	}
+
+	r, ok := pane.Screen().(*replay.Replayable)
+	if !ok {
+		return nil, fmt.Errorf("pane was not a cmd")
+	}
+
+	commands := r.Commands()
+	for i := len(commands) - 1; i >= 0; i-- {
+		command := commands[i]
+		if command.Pending {
+			continue
+		}
+
+		output := strings.Join(r.Output(command), "\n")
+		return &output, nil
+	}
+
+	return nil, nil
+}
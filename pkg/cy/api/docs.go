@@ -78,6 +78,15 @@ func (i *ViewportModule) Documentation() string {
 	return DOCS_VIEWPORT
 }
 
+//go:embed docs-theme.md
+var DOCS_THEME string
+
+var _ janet.Documented = (*ThemeModule)(nil)
+
+func (i *ThemeModule) Documentation() string {
+	return DOCS_THEME
+}
+
 //go:embed docs-cmd.md
 var DOCS_CMD string
 
@@ -113,3 +122,21 @@ var _ janet.Documented = (*MsgModule)(nil)
 func (m *MsgModule) Documentation() string {
 	return DOCS_MSG
 }
+
+//go:embed docs-repl.md
+var DOCS_REPL string
+
+var _ janet.Documented = (*ReplModule)(nil)
+
+func (r *ReplModule) Documentation() string {
+	return DOCS_REPL
+}
+
+//go:embed docs-timer.md
+var DOCS_TIMER string
+
+var _ janet.Documented = (*TimerModule)(nil)
+
+func (t *TimerModule) Documentation() string {
+	return DOCS_TIMER
+}
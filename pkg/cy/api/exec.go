@@ -1,9 +1,99 @@
 package api
 
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+
+	"github.com/cfoust/cy/pkg/janet"
+	"github.com/cfoust/cy/pkg/util"
+
+	"github.com/rs/zerolog"
+)
+
 type ExecModule struct {
-	Server Server
+	Lifetime util.Lifetime
+	Server   Server
+	// If true, (exec/async) does not run the command
+	DryRun bool
 }
 
 func (e *ExecModule) File(path string) error {
 	return e.Server.ExecuteJanet(path)
 }
+
+type AsyncParams struct {
+	Path string
+}
+
+// AsyncResult is passed to the callback provided to (exec/async) when the
+// command exits.
+type AsyncResult struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+}
+
+func (e *ExecModule) Async(
+	user interface{},
+	command string,
+	callback *janet.Function,
+	named *janet.Named[AsyncParams],
+) error {
+	params := named.Values()
+
+	if e.DryRun {
+		callback.Free()
+		return nil
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(
+		e.Lifetime.Ctx(),
+		"/bin/sh",
+		"-c",
+		command,
+	)
+	cmd.Dir = params.Path
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Start()
+	if err != nil {
+		callback.Free()
+		return err
+	}
+
+	go func() {
+		defer callback.Free()
+
+		result := AsyncResult{}
+		err := cmd.Wait()
+
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			result.ExitCode = exitErr.ExitCode()
+		} else if err != nil {
+			result.ExitCode = -1
+			stderr.WriteString(err.Error())
+		}
+
+		result.Stdout = stdout.String()
+		result.Stderr = stderr.String()
+
+		err = callback.CallContext(
+			e.Lifetime.Ctx(),
+			liveUser(user),
+			result,
+		)
+		if err != nil && e.Lifetime.Ctx().Err() == nil {
+			e.Server.Log(
+				zerolog.ErrorLevel,
+				fmt.Sprintf("callback for %s failed: %s", command, err),
+			)
+		}
+	}()
+
+	return nil
+}
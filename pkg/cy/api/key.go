@@ -7,6 +7,7 @@ import (
 	"github.com/cfoust/cy/pkg/bind/trie"
 	"github.com/cfoust/cy/pkg/janet"
 	"github.com/cfoust/cy/pkg/mux/screen/tree"
+	"github.com/cfoust/cy/pkg/taro"
 )
 
 type KeyModule struct {
@@ -34,12 +35,10 @@ func getKeySequence(value *janet.Value) (result []interface{}, err error) {
 	for i, item := range array {
 		strErr := item.Unmarshal(&str)
 		if strErr == nil {
-			// TODO(cfoust): 07/14/24 this is probably not the best place to do this
-			if str == "ctrl+i" {
-				str = "tab"
-			}
-
-			result = append(result, str)
+			// Modifiers can be specified in any order, and some
+			// combinations (like ctrl+i) are aliases for other
+			// keys (tab)
+			result = append(result, taro.NormalizeKeyName(str))
 			continue
 		}
 
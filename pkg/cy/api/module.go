@@ -1,6 +1,7 @@
 package api
 
 import (
+	"github.com/cfoust/cy/pkg/emu"
 	"github.com/cfoust/cy/pkg/frames"
 	"github.com/cfoust/cy/pkg/mux/screen"
 	"github.com/cfoust/cy/pkg/mux/screen/toasts"
@@ -22,6 +23,7 @@ type Client interface {
 	Frame() *frames.Framer
 	Binds() []Binding
 	Toast(toasts.Toast)
+	SetTheme(emu.Theme)
 }
 
 type Server interface {
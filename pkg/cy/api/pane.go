@@ -1,10 +1,20 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
 
+	P "github.com/cfoust/cy/pkg/io/protocol"
 	"github.com/cfoust/cy/pkg/janet"
+	"github.com/cfoust/cy/pkg/mux"
 	"github.com/cfoust/cy/pkg/mux/screen/tree"
+	"github.com/cfoust/cy/pkg/replay"
+	"github.com/cfoust/cy/pkg/taro"
 )
 
 type PaneModule struct {
@@ -69,10 +79,214 @@ func (p *PaneModule) Screen(id *janet.Value) ([]string, error) {
 	}
 
 	state := pane.Screen().State()
-	lines := make([]string, len(state.Image))
+	lines := make([]string, 0, len(state.Image))
 	for _, line := range state.Image {
 		lines = append(lines, line.String())
 	}
 
 	return lines, nil
 }
+
+// paneInput returns the Screen that receives input for the program running
+// in `pane`. For panes that support replay mode, this skips the replay layer
+// so that input always reaches the program.
+func paneInput(pane *tree.Pane) mux.Screen {
+	screen := pane.Screen()
+	if r, ok := screen.(*replay.Replayable); ok {
+		return r.Screen()
+	}
+
+	return screen
+}
+
+func (p *PaneModule) SendKeys(id *janet.Value, keys ...*janet.Value) error {
+	defer id.Free()
+	defer func() {
+		for _, key := range keys {
+			key.Free()
+		}
+	}()
+
+	pane, err := resolvePane(p.Tree, id)
+	if err != nil {
+		return err
+	}
+
+	var msgs []taro.KeyMsg
+	for _, key := range keys {
+		var text string
+		if err := key.Unmarshal(&text); err == nil {
+			msgs = append(msgs, taro.KeyMsg{
+				Type:  taro.KeyRunes,
+				Runes: []rune(text),
+			})
+			continue
+		}
+
+		var name janet.Keyword
+		if err := key.Unmarshal(&name); err != nil {
+			return fmt.Errorf("keys must be strings or keywords")
+		}
+
+		if msg, ok := taro.ParseKeyName(string(name)); ok {
+			msgs = append(msgs, msg)
+			continue
+		}
+
+		if utf8.RuneCountInString(string(name)) != 1 {
+			return fmt.Errorf("unknown key: %s", name)
+		}
+
+		msgs = append(msgs, taro.KeyMsg{
+			Type:  taro.KeyRunes,
+			Runes: []rune(string(name)),
+		})
+	}
+
+	input := paneInput(pane)
+	for _, msg := range msgs {
+		input.Send(msg)
+	}
+
+	return nil
+}
+
+func (p *PaneModule) SendText(id *janet.Value, text string) error {
+	defer id.Free()
+
+	pane, err := resolvePane(p.Tree, id)
+	if err != nil {
+		return err
+	}
+
+	paneInput(pane).Send(taro.KeyMsg{
+		Type:  taro.KeyRunes,
+		Runes: []rune(text),
+		Paste: true,
+	})
+	return nil
+}
+
+type WaitForParams struct {
+	Timeout *int
+}
+
+// escapeRe matches the escape sequences a program can write to a terminal,
+// such as those that change colors or move the cursor.
+var escapeRe = regexp.MustCompile(
+	`\x1b(\[[\x30-\x3f]*[\x20-\x2f]*[\x40-\x7e]|\][^\x07\x1b]*(\x07|\x1b\\)|[PX^_][^\x1b]*\x1b\\|.)|\r`,
+)
+
+// WAIT_FOR_OVERLAP is the number of bytes of output that (pane/wait-for)
+// searches again after new output arrives.
+const WAIT_FOR_OVERLAP = 4096
+
+// getOverlap returns the end of `output` that should be searched again
+// along with any new output.
+func getOverlap(output []byte) []byte {
+	if len(output) <= WAIT_FOR_OVERLAP {
+		return output
+	}
+
+	// Don't begin in the middle of a character
+	start := len(output) - WAIT_FOR_OVERLAP
+	for start < len(output) && !utf8.RuneStart(output[start]) {
+		start++
+	}
+
+	return append([]byte{}, output[start:]...)
+}
+
+func (p *PaneModule) WaitFor(
+	ctx context.Context,
+	id *janet.Value,
+	pattern string,
+	named *janet.Named[WaitForParams],
+) (string, error) {
+	defer id.Free()
+
+	params := named.Values()
+	timeout := 10000
+	if params.Timeout != nil {
+		timeout = *params.Timeout
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", err
+	}
+
+	pane, err := resolvePane(p.Tree, id)
+	if err != nil {
+		return "", err
+	}
+
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(
+			ctx,
+			time.Duration(timeout)*time.Millisecond,
+		)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	screen := pane.Screen()
+	updates := screen.Subscribe(ctx)
+	defer updates.Done()
+
+	// Output is only available for panes that record it
+	replayable, _ := screen.(*replay.Replayable)
+	numEvents := 0
+	if replayable != nil {
+		numEvents = len(replayable.Events())
+	}
+
+	// Only the output that arrived since the last update is searched,
+	// along with the end of the output before it so that matches can
+	// span several writes
+	var overlap []byte
+
+	for {
+		var lines []string
+		for _, line := range screen.State().Image {
+			lines = append(lines, strings.TrimRight(line.String(), " "))
+		}
+
+		text := strings.Join(lines, "\n")
+		if loc := re.FindStringIndex(text); loc != nil {
+			return text[loc[0]:loc[1]], nil
+		}
+
+		if replayable != nil {
+			events := replayable.Events()
+			output := overlap
+			for _, event := range events[numEvents:] {
+				if msg, ok := event.Message.(P.OutputMessage); ok {
+					output = append(output, msg.Data...)
+				}
+			}
+			numEvents = len(events)
+
+			text = escapeRe.ReplaceAllString(string(output), "")
+			if loc := re.FindStringIndex(text); loc != nil {
+				return text[loc[0]:loc[1]], nil
+			}
+
+			overlap = getOverlap(output)
+		}
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return "", fmt.Errorf(
+					"timed out waiting for %s",
+					pattern,
+				)
+			}
+			return "", ctx.Err()
+		case <-updates.Recv():
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"github.com/cfoust/cy/pkg/bind"
+	"github.com/cfoust/cy/pkg/cy/repl"
+	"github.com/cfoust/cy/pkg/emu"
+	"github.com/cfoust/cy/pkg/janet"
+	"github.com/cfoust/cy/pkg/mux/screen/tree"
+	"github.com/cfoust/cy/pkg/util"
+)
+
+type ReplParams struct {
+	Name string
+}
+
+type ReplModule struct {
+	Lifetime             util.Lifetime
+	Tree                 *tree.Tree
+	VM                   *janet.VM
+	TimeBinds, CopyBinds *bind.BindScope
+}
+
+func (r *ReplModule) New(
+	user interface{},
+	groupId *janet.Value,
+	replParams *janet.Named[ReplParams],
+) (tree.NodeID, error) {
+	defer groupId.Free()
+
+	group, err := resolveGroup(r.Tree, groupId)
+	if err != nil {
+		return 0, err
+	}
+
+	values := replParams.WithDefault(ReplParams{
+		Name: "repl",
+	})
+
+	replayable := repl.New(
+		r.Lifetime.Ctx(),
+		r.VM,
+		user,
+		r.TimeBinds,
+		r.CopyBinds,
+		emu.WithScrollback(group.Params().ScrollbackLines()),
+	)
+
+	pane := group.NewPane(r.Lifetime.Ctx(), replayable)
+	pane.SetName(values.Name)
+
+	return pane.Id(), nil
+}
@@ -3,15 +3,18 @@ package api
 import (
 	_ "embed"
 	"fmt"
-	"io"
+	"time"
 
 	"github.com/cfoust/cy/pkg/bind"
 	"github.com/cfoust/cy/pkg/geom"
 	"github.com/cfoust/cy/pkg/janet"
 	"github.com/cfoust/cy/pkg/mux/screen/tree"
 	"github.com/cfoust/cy/pkg/replay"
+	"github.com/cfoust/cy/pkg/replay/detect"
+	"github.com/cfoust/cy/pkg/replay/diff"
 	"github.com/cfoust/cy/pkg/replay/player"
 	"github.com/cfoust/cy/pkg/sessions"
+	"github.com/cfoust/cy/pkg/sessions/search"
 	"github.com/cfoust/cy/pkg/taro"
 	"github.com/cfoust/cy/pkg/util"
 )
@@ -148,6 +151,14 @@ func (m *ReplayModule) Select(context interface{}) error {
 	return m.sendAction(context, replay.ActionSelect)
 }
 
+func (m *ReplayModule) SelectLine(context interface{}) error {
+	return m.sendAction(context, replay.ActionSelectLine)
+}
+
+func (m *ReplayModule) SelectBlock(context interface{}) error {
+	return m.sendAction(context, replay.ActionSelectBlock)
+}
+
 func (m *ReplayModule) JumpAgain(context interface{}) error {
 	return m.sendAction(context, replay.ActionJumpAgain)
 }
@@ -260,9 +271,133 @@ func (m *ReplayModule) BigWordEndBackward(context interface{}) error {
 	return m.sendAction(context, replay.ActionBigWordEndBackward)
 }
 
+func (m *ReplayModule) Count(context interface{}, digit string) error {
+	return m.sendArg(context, replay.ActionCount, digit)
+}
+
+func (m *ReplayModule) Yank(context interface{}) error {
+	return m.sendAction(context, replay.ActionYank)
+}
+
+func (m *ReplayModule) InnerObject(context interface{}, char string) error {
+	return m.sendArg(context, replay.ActionInnerObject, char)
+}
+
+func (m *ReplayModule) AroundObject(context interface{}, char string) error {
+	return m.sendArg(context, replay.ActionAroundObject, char)
+}
+
+func (m *ReplayModule) Register(context interface{}, name string) error {
+	return m.sendArg(context, replay.ActionRegister, name)
+}
+
+func (m *ReplayModule) ToggleTimeline(context interface{}) error {
+	return m.sendAction(context, replay.ActionToggleTimeline)
+}
+
+func (m *ReplayModule) Mark(context interface{}, name string) error {
+	return m.sendArg(context, replay.ActionMark, name)
+}
+
+func (m *ReplayModule) JumpMark(context interface{}, name string) error {
+	return m.sendArg(context, replay.ActionJumpMark, name)
+}
+
+// Mark is a mark or bookmark in the timeline of the pane with ID `Id`.
+type Mark struct {
+	Id      tree.NodeID
+	Name    string
+	Label   string
+	Address search.Address
+	Time    string
+}
+
+func newMark(id tree.NodeID, mark replay.Mark) Mark {
+	return Mark{
+		Id:      id,
+		Name:    mark.Name,
+		Label:   mark.Label,
+		Address: mark.Address,
+		Time:    mark.Time.Format(time.RFC3339),
+	}
+}
+
+func resolveReplayable(
+	t *tree.Tree,
+	id *janet.Value,
+) (*tree.Pane, *replay.Replayable, error) {
+	pane, err := resolvePane(t, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	r, ok := pane.Screen().(*replay.Replayable)
+	if !ok {
+		return nil, nil, fmt.Errorf("node not replayable")
+	}
+
+	return pane, r, nil
+}
+
+func (m *ReplayModule) Marks(id *janet.Value) ([]Mark, error) {
+	defer id.Free()
+
+	pane, r, err := resolveReplayable(m.Tree, id)
+	if err != nil {
+		return nil, err
+	}
+
+	marks := make([]Mark, 0)
+	for _, mark := range r.Marks().All() {
+		marks = append(marks, newMark(pane.Id(), mark))
+	}
+	return marks, nil
+}
+
+func (m *ReplayModule) Bookmark(id *janet.Value, label string) (Mark, error) {
+	defer id.Free()
+
+	pane, r, err := resolveReplayable(m.Tree, id)
+	if err != nil {
+		return Mark{}, err
+	}
+
+	mark, err := r.Bookmark(label)
+	if err != nil {
+		return Mark{}, err
+	}
+
+	return newMark(pane.Id(), mark), nil
+}
+
+func (m *ReplayModule) Bookmarks() []Mark {
+	bookmarks := make([]Mark, 0)
+	for _, node := range m.Tree.Leaves() {
+		pane, ok := node.(*tree.Pane)
+		if !ok {
+			continue
+		}
+
+		r, ok := pane.Screen().(*replay.Replayable)
+		if !ok {
+			continue
+		}
+
+		for _, mark := range r.Marks().Bookmarks() {
+			bookmarks = append(bookmarks, newMark(pane.Id(), mark))
+		}
+	}
+	return bookmarks
+}
+
+type OpenFileParams struct {
+	Address *search.Address
+}
+
 func (m *ReplayModule) OpenFile(
 	groupId *janet.Value,
 	path string,
+	named *janet.Named[OpenFileParams],
 ) (tree.NodeID, error) {
 	defer groupId.Free()
 
@@ -271,21 +406,24 @@ func (m *ReplayModule) OpenFile(
 		return 0, err
 	}
 
-	reader, err := sessions.Open(path)
+	events, err := sessions.ReadAll(path)
 	if err != nil {
 		return 0, err
 	}
 
-	events := make([]sessions.Event, 0)
-	for {
-		event, err := reader.Read()
-		if err == io.EOF || err == io.ErrUnexpectedEOF {
-			break
-		}
-		if err != nil {
-			return 0, err
-		}
-		events = append(events, event)
+	marks, err := replay.LoadMarks(replay.MarksPath(path))
+	if err != nil {
+		return 0, err
+	}
+
+	options := []replay.Option{
+		replay.WithNoQuit,
+		replay.WithMarks(marks),
+	}
+
+	params := named.Values()
+	if params.Address != nil {
+		options = append(options, replay.WithAddress(*params.Address))
 	}
 
 	// TODO(cfoust): 03/04/24 open progress
@@ -295,7 +433,7 @@ func (m *ReplayModule) OpenFile(
 		player.FromEvents(events),
 		m.TimeBinds,
 		m.CopyBinds,
-		replay.WithNoQuit,
+		options...,
 	)
 
 	pane := group.NewPane(ctx, replay)
@@ -306,6 +444,8 @@ type ReplayParams struct {
 	Main     bool
 	Copy     bool
 	Location *geom.Vec2
+	Address  *search.Address
+	Timeline bool
 }
 
 func (m *ReplayModule) Open(
@@ -314,7 +454,7 @@ func (m *ReplayModule) Open(
 ) error {
 	defer id.Free()
 
-	pane, err := resolvePane(m.Tree, id)
+	_, r, err := resolveReplayable(m.Tree, id)
 	if err != nil {
 		return err
 	}
@@ -330,17 +470,196 @@ func (m *ReplayModule) Open(
 		options = append(options, replay.WithCopyMode)
 	}
 
+	if params.Timeline {
+		options = append(options, replay.WithTimeline)
+	}
+
+	// The address must be applied first, since moving in time resets
+	// the location of the cursor
+	if params.Address != nil {
+		options = append(options, replay.WithAddress(
+			*params.Address,
+		))
+	}
+
 	if params.Location != nil {
 		options = append(options, replay.WithLocation(
 			*params.Location,
 		))
 	}
 
-	r, ok := pane.Screen().(*replay.Replayable)
-	if !ok {
-		return fmt.Errorf("node not replayable")
-	}
-
 	r.EnterReplay(options...)
 	return nil
 }
+
+type DiffParams struct {
+	Unified bool
+}
+
+// diffSide returns the lines and the title for one side of a diff.
+// `moment` is either the index of a command, which selects that command's
+// output, or an address, which selects everything in the terminal at that
+// moment.
+func diffSide(
+	p *player.Player,
+	moment *janet.Value,
+) (lines []string, title string, err error) {
+	defer moment.Free()
+
+	var index int
+	if err := moment.Unmarshal(&index); err == nil {
+		commands := p.Commands()
+
+		// Negative indices count back from the most recent command
+		if index < 0 {
+			index += len(commands)
+		}
+
+		if index < 0 || index >= len(commands) {
+			return nil, "", fmt.Errorf("command not found: %d", index)
+		}
+
+		command := commands[index]
+		return detect.Output(p, command), command.Text, nil
+	}
+
+	var address search.Address
+	if err := moment.Unmarshal(&address); err != nil {
+		return nil, "", fmt.Errorf(
+			"moment must be a command index or an address",
+		)
+	}
+
+	events := p.Events()
+	if address.Index < 0 || address.Index >= len(events) {
+		return nil, "", fmt.Errorf(
+			"address out of range: %d",
+			address.Index,
+		)
+	}
+
+	return diff.Snapshot(events, address),
+		events[address.Index].Stamp.Format(time.RFC3339),
+		nil
+}
+
+func (m *ReplayModule) Diff(
+	id *janet.Value,
+	from, to *janet.Value,
+	named *janet.Named[DiffParams],
+) (tree.NodeID, error) {
+	defer id.Free()
+
+	pane, r, err := resolveReplayable(m.Tree, id)
+	if err != nil {
+		from.Free()
+		to.Free()
+		return 0, err
+	}
+
+	// Replay the pane's events separately so that we don't disturb
+	// replay mode, if it is open
+	p := player.FromEvents(r.Events())
+
+	oldLines, oldTitle, err := diffSide(p, from)
+	if err != nil {
+		to.Free()
+		return 0, err
+	}
+
+	newLines, newTitle, err := diffSide(p, to)
+	if err != nil {
+		return 0, err
+	}
+
+	settings := []diff.Setting{
+		diff.WithTitles(oldTitle, newTitle),
+	}
+
+	if named.Values().Unified {
+		settings = append(settings, diff.WithUnified())
+	}
+
+	// Put the diff next to the pane it came from
+	group := m.Tree.Root()
+	path := m.Tree.PathTo(pane)
+	if len(path) >= 2 {
+		if parent, ok := path[len(path)-2].(*tree.Group); ok {
+			group = parent
+		}
+	}
+
+	ctx := m.Lifetime.Ctx()
+	diffPane := group.NewPane(
+		ctx,
+		diff.New(ctx, oldLines, newLines, settings...),
+	)
+	diffPane.SetName("diff")
+	return diffPane.Id(), nil
+}
+
+type ExtractParams struct {
+	From    *search.Address
+	To      *search.Address
+	Command *int
+	Path    string
+}
+
+func (m *ReplayModule) Extract(
+	id *janet.Value,
+	named *janet.Named[ExtractParams],
+) error {
+	defer id.Free()
+
+	_, r, err := resolveReplayable(m.Tree, id)
+	if err != nil {
+		return err
+	}
+
+	params := named.Values()
+	if len(params.Path) == 0 {
+		return fmt.Errorf("path must be provided")
+	}
+
+	events := r.Events()
+	if len(events) == 0 {
+		return fmt.Errorf("pane has no events")
+	}
+
+	// By default, extract the entire history of the pane
+	from := search.Address{Index: 0, Offset: 0}
+	to := search.Address{Index: len(events) - 1, Offset: -1}
+
+	if params.Command != nil {
+		commands := r.Commands()
+		index := *params.Command
+
+		// Negative indices count back from the most recent command
+		if index < 0 {
+			index += len(commands)
+		}
+
+		if index < 0 || index >= len(commands) {
+			return fmt.Errorf("command not found: %d", *params.Command)
+		}
+
+		command := commands[index]
+		from = search.Address{Index: command.Prompted, Offset: 0}
+		to = search.Address{Index: command.Completed, Offset: -1}
+	}
+
+	if params.From != nil {
+		from = *params.From
+	}
+
+	if params.To != nil {
+		to = *params.To
+	}
+
+	extracted, err := replay.Extract(events, from, to)
+	if err != nil {
+		return err
+	}
+
+	return replay.WriteEvents(params.Path, extracted)
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/cfoust/cy/pkg/emu"
+	"github.com/cfoust/cy/pkg/janet"
+)
+
+type ThemeModule struct {
+}
+
+type ThemeColors struct {
+	Black         *string
+	Red           *string
+	Green         *string
+	Yellow        *string
+	Blue          *string
+	Magenta       *string
+	Cyan          *string
+	White         *string
+	BrightBlack   *string
+	BrightRed     *string
+	BrightGreen   *string
+	BrightYellow  *string
+	BrightBlue    *string
+	BrightMagenta *string
+	BrightCyan    *string
+	BrightWhite   *string
+	Foreground    *string
+	Background    *string
+	Cursor        *string
+}
+
+func (t *ThemeModule) Set(context interface{}, value *janet.Value) error {
+	defer value.Free()
+
+	client, ok := context.(Client)
+	if !ok {
+		return fmt.Errorf("missing client context")
+	}
+
+	// Colors are optional, which the janet package only supports when
+	// unmarshaling a value directly
+	var colors ThemeColors
+	err := value.Unmarshal(&colors)
+	if err != nil {
+		return err
+	}
+
+	specs := map[emu.Color]*string{
+		emu.Black:         colors.Black,
+		emu.Red:           colors.Red,
+		emu.Green:         colors.Green,
+		emu.Yellow:        colors.Yellow,
+		emu.Blue:          colors.Blue,
+		emu.Magenta:       colors.Magenta,
+		emu.Cyan:          colors.Cyan,
+		emu.LightGrey:     colors.White,
+		emu.DarkGrey:      colors.BrightBlack,
+		emu.LightRed:      colors.BrightRed,
+		emu.LightGreen:    colors.BrightGreen,
+		emu.LightYellow:   colors.BrightYellow,
+		emu.LightBlue:     colors.BrightBlue,
+		emu.LightMagenta:  colors.BrightMagenta,
+		emu.LightCyan:     colors.BrightCyan,
+		emu.White:         colors.BrightWhite,
+		emu.DefaultFG:     colors.Foreground,
+		emu.DefaultBG:     colors.Background,
+		emu.DefaultCursor: colors.Cursor,
+	}
+
+	theme := make(emu.Theme)
+	for color, spec := range specs {
+		if spec == nil {
+			continue
+		}
+
+		rgb, err := emu.ParseColor(*spec)
+		if err != nil {
+			return err
+		}
+		theme[color] = rgb
+	}
+
+	client.SetTheme(theme)
+	return nil
+}
@@ -0,0 +1,208 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cfoust/cy/pkg/janet"
+	"github.com/cfoust/cy/pkg/util"
+
+	"github.com/rs/zerolog"
+	"github.com/sasha-s/go-deadlock"
+)
+
+// Clock creates the timers used by (timer/after) and (timer/every). It is
+// only replaced in tests, which need to control the passage of time.
+type Clock interface {
+	// NewTimer returns a channel that receives the time after `d` has
+	// elapsed and a function that stops the timer.
+	NewTimer(d time.Duration) (<-chan time.Time, func())
+}
+
+type realClock struct{}
+
+func (realClock) NewTimer(d time.Duration) (<-chan time.Time, func()) {
+	timer := time.NewTimer(d)
+	return timer.C, func() { timer.Stop() }
+}
+
+// liveUser returns `user` unless it is a client that has since
+// disconnected, in which case callbacks are run without a client.
+func liveUser(user interface{}) interface{} {
+	if client, ok := user.(interface{ IsDone() bool }); ok && client.IsDone() {
+		return nil
+	}
+
+	return user
+}
+
+type TimerModule struct {
+	Lifetime util.Lifetime
+	Server   Server
+	// Defaults to the system clock
+	Clock Clock
+	// If true, timers are never started
+	DryRun bool
+
+	lock   deadlock.Mutex
+	nextId int
+	timers map[int]context.CancelFunc
+}
+
+// schedule calls `callback` after `delay` and, if `repeat` is true, every
+// `delay` thereafter until the timer is cancelled. Calls to the callback go
+// through the VM's request queue like any other function call, so a timer
+// never blocks the goroutine that created it.
+func (t *TimerModule) schedule(
+	user interface{},
+	delay time.Duration,
+	repeat bool,
+	callback *janet.Function,
+) int {
+	if t.DryRun {
+		callback.Free()
+
+		t.lock.Lock()
+		defer t.lock.Unlock()
+		t.nextId++
+		return t.nextId
+	}
+
+	ctx, cancel := context.WithCancel(t.Lifetime.Ctx())
+
+	t.lock.Lock()
+	if t.timers == nil {
+		t.timers = make(map[int]context.CancelFunc)
+	}
+	t.nextId++
+	id := t.nextId
+	t.timers[id] = cancel
+	t.lock.Unlock()
+
+	clock := t.Clock
+	if clock == nil {
+		clock = realClock{}
+	}
+
+	go func() {
+		defer callback.Free()
+		defer t.remove(id)
+
+		for {
+			fired, stop := clock.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				stop()
+				return
+			case <-fired:
+			}
+
+			// The timer may have been cancelled at the same moment
+			// that it fired
+			if ctx.Err() != nil {
+				return
+			}
+
+			err := callback.CallContext(ctx, liveUser(user))
+			if err != nil && ctx.Err() == nil {
+				t.Server.Log(
+					zerolog.ErrorLevel,
+					fmt.Sprintf("timer %d failed: %s", id, err),
+				)
+			}
+
+			if !repeat {
+				return
+			}
+		}
+	}()
+
+	return id
+}
+
+func (t *TimerModule) remove(id int) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if cancel, ok := t.timers[id]; ok {
+		cancel()
+		delete(t.timers, id)
+	}
+}
+
+func (t *TimerModule) After(
+	user interface{},
+	ms int,
+	callback *janet.Function,
+) (int, error) {
+	if ms < 0 {
+		callback.Free()
+		return 0, fmt.Errorf("delay must not be negative")
+	}
+
+	return t.schedule(
+		user,
+		time.Duration(ms)*time.Millisecond,
+		false,
+		callback,
+	), nil
+}
+
+func (t *TimerModule) Every(
+	user interface{},
+	ms int,
+	callback *janet.Function,
+) (int, error) {
+	if ms <= 0 {
+		callback.Free()
+		return 0, fmt.Errorf("interval must be positive")
+	}
+
+	return t.schedule(
+		user,
+		time.Duration(ms)*time.Millisecond,
+		true,
+		callback,
+	), nil
+}
+
+func (t *TimerModule) Cancel(id int) {
+	t.remove(id)
+}
+
+// LastID returns the ID of the most recently created timer, or 0 if no
+// timers have been created.
+func (t *TimerModule) LastID() int {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.nextId
+}
+
+// CancelSince cancels every timer created after the timer with ID `id`.
+func (t *TimerModule) CancelSince(id int) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for timerId, cancel := range t.timers {
+		if timerId <= id {
+			continue
+		}
+		cancel()
+		delete(t.timers, timerId)
+	}
+}
+
+// Active returns the IDs of the timers created after the timer with ID
+// `id` that have not yet finished or been cancelled.
+func (t *TimerModule) Active(id int) (ids []int) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for timerId := range t.timers {
+		if timerId > id {
+			ids = append(ids, timerId)
+		}
+	}
+	return
+}
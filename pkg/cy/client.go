@@ -3,16 +3,21 @@ package cy
 import (
 	"context"
 	"fmt"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/cfoust/cy/pkg/bind"
 	"github.com/cfoust/cy/pkg/cy/api"
+	"github.com/cfoust/cy/pkg/emu"
 	"github.com/cfoust/cy/pkg/frames"
 	"github.com/cfoust/cy/pkg/geom"
+	"github.com/cfoust/cy/pkg/geom/tty"
 	P "github.com/cfoust/cy/pkg/io/protocol"
 	"github.com/cfoust/cy/pkg/janet"
 	"github.com/cfoust/cy/pkg/mux"
 	"github.com/cfoust/cy/pkg/mux/screen"
+	"github.com/cfoust/cy/pkg/mux/screen/hints"
 	"github.com/cfoust/cy/pkg/mux/screen/server"
 	"github.com/cfoust/cy/pkg/mux/screen/splash"
 	"github.com/cfoust/cy/pkg/mux/screen/toasts"
@@ -42,9 +47,15 @@ type Client struct {
 	// All of the environment variables in the client's original
 	// environment at connection time
 	env Environment
+	// The features supported by the client's terminal
+	caps tty.Capabilities
+	// The colors the client's screen is drawn with
+	theme emu.Theme
 
 	node  tree.Node
 	binds *bind.Engine[bind.Action]
+	// Identifies the most recent set of hints sent to the hinter
+	hintID atomic.Int32
 
 	// the text the client has copied
 	buffer string
@@ -55,6 +66,7 @@ type Client struct {
 	muxClient *server.Client
 	toast     *ToastLogger
 	toaster   *taro.Program
+	hinter    *taro.Program
 	margins   *screen.Margins
 	frame     *frames.Framer
 	// Layers inside of the margins
@@ -182,6 +194,12 @@ func (c *Client) pollEvents() {
 				continue
 			}
 
+			if partial, ok := event.(bind.PartialEvent[bind.Action]); ok {
+				id := int(c.hintID.Add(1))
+				go c.showHints(id, partial)
+				continue
+			}
+
 			// We only consider key presses to be an interaction
 			// We don't want mouse motion to trigger this
 			if _, ok := event.(taro.KeyMsg); ok {
@@ -224,11 +242,51 @@ func isSSH(e Environment) bool {
 	return e.IsSet("SSH_CONNECTION") || e.IsSet("SSH_CLIENT") || e.IsSet("SSH_TTY")
 }
 
+// getCapabilities gets the capabilities of a client's terminal. If the
+// client could not probe the terminal, such as when the terminal did not
+// respond in time, they are guessed based on its environment.
+func getCapabilities(e Environment, options ClientOptions) tty.Capabilities {
+	caps := tty.Capabilities{Profile: options.Profile}
+	if probed := options.Capabilities; probed != nil {
+		caps.Probed = true
+		caps.Version = probed.Version
+		caps.Attributes = probed.Attributes
+		caps.SecondaryAttributes = probed.SecondaryAttributes
+		caps.Sixel = probed.Sixel
+		caps.KittyGraphics = probed.KittyGraphics
+		caps.KittyKeyboard = probed.KittyKeyboard
+		caps.BracketedPaste = probed.BracketedPaste
+		caps.SyncUpdate = probed.SyncUpdate
+		return caps
+	}
+
+	term := e.Default("TERM", "")
+	program := e.Default("TERM_PROGRAM", "")
+
+	switch {
+	case e.IsSet("KITTY_WINDOW_ID"),
+		strings.Contains(term, "kitty"),
+		strings.Contains(term, "ghostty"),
+		program == "WezTerm",
+		program == "ghostty":
+		caps.KittyGraphics = true
+	case strings.HasPrefix(term, "foot"),
+		strings.Contains(term, "mlterm"),
+		strings.Contains(term, "sixel"),
+		program == "iTerm.app",
+		program == "mintty":
+		caps.Sixel = true
+	}
+
+	return caps
+}
+
 func (c *Client) initialize(options ClientOptions) error {
 	c.Lock()
 	defer c.Unlock()
 
 	c.env = Environment(options.Env)
+	c.caps = getCapabilities(c.env, options)
 
 	info, err := terminfo.Load(c.env.Default("TERM", "xterm-256color"))
 	if err != nil {
@@ -285,6 +343,13 @@ func (c *Client) initialize(options ClientOptions) error {
 		)
 	}
 
+	c.hinter = hints.New(c.Ctx())
+	c.outerLayers.NewLayer(
+		c.Ctx(),
+		c.hinter,
+		screen.PositionTop,
+	)
+
 	c.toaster = toasts.New(c.Ctx())
 	c.toast = NewToastLogger(c.sendToast)
 	c.outerLayers.NewLayer(
@@ -298,6 +363,7 @@ func (c *Client) initialize(options ClientOptions) error {
 		info,
 		options.Size,
 		c.outerLayers,
+		renderer.WithCapabilities(c.caps),
 	)
 
 	if isClientSSH {
@@ -373,6 +439,10 @@ func (c *Client) attach(node tree.Node) error {
 	c.binds.SetScopes(scopes...)
 	c.params.SetParent(node.Params())
 	c.interact(c.cy.visits, node.Id())
+
+	if themeable, ok := pane.Screen().(screen.Themeable); ok {
+		themeable.SetTheme(c.theme)
+	}
 	return nil
 }
 
@@ -492,6 +562,27 @@ func (c *Client) execute(code string) error {
 	})
 }
 
+// SetTheme changes the colors used to draw the client's screen. The pane
+// the client is attached to also reports these colors to programs that
+// ask for them.
+func (c *Client) SetTheme(theme emu.Theme) {
+	c.Lock()
+	c.theme = theme
+	node := c.node
+	c.Unlock()
+
+	c.renderer.SetTheme(theme)
+
+	pane, ok := node.(*tree.Pane)
+	if !ok {
+		return
+	}
+
+	if themeable, ok := pane.Screen().(screen.Themeable); ok {
+		themeable.SetTheme(theme)
+	}
+}
+
 func (c *Client) Toast(toast toasts.Toast) {
 	c.toaster.Send(toast)
 }
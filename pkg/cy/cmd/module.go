@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/cfoust/cy/pkg/bind"
+	"github.com/cfoust/cy/pkg/emu"
 	"github.com/cfoust/cy/pkg/geom"
 	"github.com/cfoust/cy/pkg/mux/stream"
 	"github.com/cfoust/cy/pkg/replay"
@@ -15,19 +16,48 @@ func New(
 	options stream.CmdOptions,
 	dataDir string,
 	timeBinds, copyBinds *bind.BindScope,
+	termOptions ...emu.TerminalOption,
+) (*replay.Replayable, error) {
+	return NewWithPrefix(
+		ctx,
+		options,
+		dataDir,
+		nil,
+		timeBinds,
+		copyBinds,
+		termOptions...,
+	)
+}
+
+// NewWithPrefix is like New, but the pane shows (and records) `prefix`
+// before any of the command's output. This is used to restore the contents
+// of panes that existed before the server restarted.
+func NewWithPrefix(
+	ctx context.Context,
+	options stream.CmdOptions,
+	dataDir string,
+	prefix []byte,
+	timeBinds, copyBinds *bind.BindScope,
+	termOptions ...emu.TerminalOption,
 ) (*replay.Replayable, error) {
 	cmd, err := stream.NewCmd(ctx, options, geom.DEFAULT_SIZE)
 	if err != nil {
 		return nil, err
 	}
 
+	var output stream.Stream = cmd
+	if len(prefix) > 0 {
+		output = stream.NewPrefixed(cmd, prefix)
+	}
+
 	if len(dataDir) == 0 {
 		replayable := replay.NewReplayable(
 			ctx,
 			cmd,
-			cmd,
+			output,
 			timeBinds,
 			copyBinds,
+			termOptions...,
 		)
 		return replayable, nil
 	}
@@ -42,11 +72,33 @@ func New(
 		return nil, err
 	}
 
-	return replay.NewReplayable(
+	replayable := replay.NewReplayable(
 		ctx,
 		cmd,
-		sessions.NewEventStream(cmd, recorder),
+		sessions.NewEventStream(output, recorder),
 		timeBinds,
 		copyBinds,
-	), nil
+		termOptions...,
+	)
+
+	// Marks are stored next to the recording so that they are still
+	// available when it is opened later
+	replayable.Marks().SetPath(replay.MarksPath(borgPath))
+	return replayable, nil
+}
+
+// Recording returns the path of the .borg file to which the output of the
+// command in `r` is being written, if there is one.
+func Recording(r *replay.Replayable) (path string, ok bool) {
+	events, ok := r.Stream().(*sessions.EventStream)
+	if !ok {
+		return "", false
+	}
+
+	recorder, ok := events.Handler().(*sessions.FileRecorder)
+	if !ok {
+		return "", false
+	}
+
+	return recorder.Filename(), true
 }
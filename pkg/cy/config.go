@@ -1,6 +1,7 @@
 package cy
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 )
@@ -14,7 +15,9 @@ func fileExists(path string) bool {
 
 // TODO(cfoust): 09/17/23 support XDG_CONFIG_DIRS and XDG_DATA_DIRS
 
-func FindConfig() string {
+// configRoots returns the directories that are searched for cy's
+// configuration, in order of precedence.
+func configRoots() []string {
 	roots := make([]string, 0)
 
 	if xdgConfig, ok := os.LookupEnv("XDG_CONFIG_HOME"); ok {
@@ -29,7 +32,11 @@ func FindConfig() string {
 		)
 	}
 
-	for _, root := range roots {
+	return roots
+}
+
+func FindConfig() string {
+	for _, root := range configRoots() {
 		if path := filepath.Join(root, "cy", "cyrc.janet"); fileExists(path) {
 			return path
 		}
@@ -46,6 +53,20 @@ func FindConfig() string {
 	return ""
 }
 
+// FindPluginDir returns the path to the first "cy/plugins" directory in any of
+// the directories searched for the configuration file, or an empty string if
+// there is none.
+func FindPluginDir() string {
+	for _, root := range configRoots() {
+		path := filepath.Join(root, "cy", "plugins")
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			return path
+		}
+	}
+
+	return ""
+}
+
 func FindDataDir() string {
 	if xdgData, ok := os.LookupEnv("XDG_DATA_HOME"); ok {
 		return filepath.Join(xdgData, "cy")
@@ -57,3 +78,27 @@ func FindDataDir() string {
 
 	return filepath.Join(home, ".local", "share", "cy")
 }
+
+// CheckConfig executes the configuration file in `options.Config` in a new,
+// temporary server that has no clients and reports any error that occurs.
+// Plugins in `options.PluginDir` are loaded first, just as they would be by a
+// real server. Input functions are skipped, `options.DataDir` is ignored,
+// and the server runs in dry-run mode, so no processes are started, no
+// timers fire, and nothing is written to disk.
+func CheckConfig(ctx context.Context, options Options) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	config := options.Config
+	options.Config = ""
+	options.DataDir = ""
+	options.SkipInput = true
+	options.DryRun = true
+
+	cy, err := Start(ctx, options)
+	if err != nil {
+		return err
+	}
+
+	return cy.executeConfig(config)
+}
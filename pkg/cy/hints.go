@@ -0,0 +1,86 @@
+package cy
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cfoust/cy/pkg/bind"
+	"github.com/cfoust/cy/pkg/mux/screen/hints"
+)
+
+// summarizeDoc gets the first line of a Janet docstring, skipping the
+// function signature that `defn` adds to the beginning.
+func summarizeDoc(doc string) string {
+	doc = strings.TrimSpace(doc)
+	if strings.HasPrefix(doc, "(") {
+		_, rest, ok := strings.Cut(doc, "\n\n")
+		if !ok {
+			return ""
+		}
+		doc = strings.TrimSpace(rest)
+	}
+
+	line, _, _ := strings.Cut(doc, "\n")
+	return strings.TrimSpace(line)
+}
+
+// describeAction gets a short description of what `action` does.
+func (c *Client) describeAction(action bind.Action) string {
+	if action.Callback == nil {
+		return ""
+	}
+
+	doc, err := c.cy.Docstring(c.Ctx(), action.Callback.Value)
+	if err != nil {
+		return ""
+	}
+
+	return summarizeDoc(doc)
+}
+
+// getHints gets the ways in which the client can complete the key sequence
+// in `event`.
+func (c *Client) getHints(event bind.PartialEvent[bind.Action]) (result []hints.Hint) {
+	// Matches from scopes that take precedence come first
+	seen := make(map[string]struct{})
+	for _, match := range event.Matches {
+		key := strings.Join(match.Bind.Path, " ")
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+
+		result = append(result, hints.Hint{
+			Keys:        match.Bind.Path,
+			Description: c.describeAction(match.Bind.Value),
+			Tag:         match.Bind.Value.Tag,
+		})
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return strings.Join(result[i].Keys, " ") <
+			strings.Join(result[j].Keys, " ")
+	})
+	return
+}
+
+// showHints shows the client the keys that complete the sequence they are
+// typing, or hides the hints if there is no such sequence. `id` must
+// increase with every call so that slow calls cannot replace the hints of
+// later ones.
+func (c *Client) showHints(id int, event bind.PartialEvent[bind.Action]) {
+	if len(event.Matches) == 0 || !c.params.ShowHints() {
+		c.hinter.Send(hints.Hints{ID: id})
+		return
+	}
+
+	c.hinter.Send(hints.Hints{
+		ID:     id,
+		Prefix: event.Prefix,
+		Hints:  c.getHints(event),
+		Delay: time.Duration(
+			c.params.HintDelay(),
+		) * time.Millisecond,
+	})
+}
@@ -0,0 +1,52 @@
+package cy
+
+import (
+	"testing"
+
+	"github.com/cfoust/cy/pkg/bind"
+	"github.com/cfoust/cy/pkg/geom"
+	"github.com/cfoust/cy/pkg/mux/screen/hints"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummarizeDoc(t *testing.T) {
+	require.Equal(t, "Create a new shell.", summarizeDoc(
+		"(action/new-shell)\n\nCreate a new shell.\n\nMore details.",
+	))
+	require.Equal(t, "Just a line.", summarizeDoc("Just a line.\nAnother."))
+	require.Equal(t, "", summarizeDoc("(no-docs)"))
+	require.Equal(t, "", summarizeDoc(""))
+}
+
+func TestHints(t *testing.T) {
+	server, create := setup(t)
+	client := create(geom.DEFAULT_SIZE)
+
+	require.NoError(t, client.execute(`
+(key/action action/hint-test "Do something for the test." nil)
+(key/bind :root ["ctrl+b" "x"] action/hint-test)
+(key/bind :root ["ctrl+b" "y" "z"] (fn []) :tag "nested")
+(key/bind :root ["ctrl+c"] action/hint-test)
+`))
+
+	prefix := []string{"ctrl+b"}
+	var matches []bind.Match[bind.Action]
+	for _, leaf := range server.tree.Root().Binds().Partial(prefix) {
+		matches = append(matches, bind.Match[bind.Action]{Bind: leaf})
+	}
+
+	require.Equal(t, []hints.Hint{
+		{
+			Keys:        []string{"x"},
+			Description: "Do something for the test.",
+		},
+		{
+			Keys: []string{"y", "z"},
+			Tag:  "nested",
+		},
+	}, client.getHints(bind.PartialEvent[bind.Action]{
+		Prefix:  prefix,
+		Matches: matches,
+	}))
+}
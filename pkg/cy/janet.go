@@ -20,15 +20,27 @@ func (c *Cy) initJanet(ctx context.Context) (*janet.VM, error) {
 		return nil, err
 	}
 
+	c.timers = &api.TimerModule{
+		Lifetime: util.NewLifetime(c.Ctx()),
+		Server:   c,
+		Clock:    c.clock,
+		DryRun:   c.dryRun,
+	}
+
 	modules := map[string]interface{}{
 		"cmd": &api.CmdModule{
 			Lifetime:  util.NewLifetime(c.Ctx()),
 			Tree:      c.tree,
 			TimeBinds: c.timeBinds,
 			CopyBinds: c.copyBinds,
+			DryRun:    c.dryRun,
+		},
+		"cy": &CyModule{cy: c},
+		"exec": &api.ExecModule{
+			Lifetime: util.NewLifetime(c.Ctx()),
+			Server:   c,
+			DryRun:   c.dryRun,
 		},
-		"cy":    &CyModule{cy: c},
-		"exec":  &api.ExecModule{Server: c},
 		"group": &api.GroupModule{Tree: c.tree},
 		"input": &api.InputModule{Tree: c.tree, Server: c.muxServer},
 		"msg":   &api.MsgModule{Server: c},
@@ -37,15 +49,26 @@ func (c *Cy) initJanet(ctx context.Context) (*janet.VM, error) {
 			TimeBinds: c.timeBinds,
 			CopyBinds: c.copyBinds,
 		},
-		"pane":  &api.PaneModule{Tree: c.tree},
-		"param": &api.ParamModule{Tree: c.tree},
-		"path":  &api.PathModule{},
+		"pane":     &api.PaneModule{Tree: c.tree},
+		"plugin":   &PluginModule{cy: c},
+		"param":    &api.ParamModule{Tree: c.tree},
+		"register": &RegisterModule{cy: c},
+		"path":     &api.PathModule{},
 		"replay": &api.ReplayModule{
 			Lifetime:  util.NewLifetime(c.Ctx()),
 			Tree:      c.tree,
 			TimeBinds: c.timeBinds,
 			CopyBinds: c.copyBinds,
 		},
+		"repl": &api.ReplModule{
+			Lifetime:  util.NewLifetime(c.Ctx()),
+			Tree:      c.tree,
+			VM:        vm,
+			TimeBinds: c.timeBinds,
+			CopyBinds: c.copyBinds,
+		},
+		"theme": &api.ThemeModule{},
+		"timer":    c.timers,
 		"tree":     &api.TreeModule{Tree: c.tree},
 		"viewport": &api.ViewportModule{},
 	}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/cfoust/cy/pkg/bind"
+	"github.com/cfoust/cy/pkg/cy/api"
 	"github.com/cfoust/cy/pkg/emu"
 	"github.com/cfoust/cy/pkg/events"
 	"github.com/cfoust/cy/pkg/janet"
@@ -36,6 +37,15 @@ type Options struct {
 	HideSplash bool
 	// Whether to skip blocking (input/*) API calls. Just for testing.
 	SkipInput bool
+	// The directory containing plugins, which are loaded before the
+	// config file.
+	PluginDir string
+	// The clock used by timers. Just for testing.
+	Clock api.Clock
+	// Whether API calls with side effects outside of the server, such
+	// as starting processes, running timers, and writing files, should
+	// do nothing. Used by CheckConfig.
+	DryRun bool
 }
 
 type historyEvent struct {
@@ -71,7 +81,9 @@ type Cy struct {
 	log zerolog.Logger
 
 	configPath string
-	showSplash bool
+	// The changes made by the last config that loaded successfully
+	configChanges *configChanges
+	showSplash    bool
 
 	toast        *ToastLogger
 	queuedToasts []toasts.Toast
@@ -81,6 +93,22 @@ type Cy struct {
 	// (tmux does the same thing)
 	lastWrite, lastVisit map[tree.NodeID]historyEvent
 	writes, visits       chan historyEvent
+
+	// The file to which the node tree is periodically saved, if any
+	autosavePath string
+	stopAutosave context.CancelFunc
+
+	pluginDir string
+	plugins   []*Plugin
+
+	// Text that users have copied, shared by all clients
+	registers *registers
+
+	// The clock used by timers, or nil for the system clock
+	clock  api.Clock
+	timers *api.TimerModule
+
+	dryRun bool
 }
 
 func (c *Cy) ExecuteJanet(path string) error {
@@ -92,14 +120,14 @@ func (c *Cy) Log(level zerolog.Level, message string) {
 }
 
 func (c *Cy) loadConfig() error {
-	err := c.ExecuteFile(c.Ctx(), c.configPath)
+	err := c.executeConfig(c.configPath)
 
 	// We want to make a lot of noise if this fails for some reason, even
 	// if this is being called in user code
 	if err != nil {
 		c.log.Error().Err(err).Msg("failed to execute config")
 		message := fmt.Sprintf(
-			"an error occurred while loading %s: %s",
+			"an error occurred while loading %s, so none of its changes were applied: %s",
 			c.configPath,
 			err.Error(),
 		)
@@ -149,6 +177,17 @@ func (c *Cy) getFirstClientPane(except *Client) tree.Node {
 }
 
 func (c *Cy) Shutdown() error {
+	// Save one last time so that nothing since the last autosave is lost
+	c.RLock()
+	autosavePath := c.autosavePath
+	c.RUnlock()
+	if len(autosavePath) > 0 {
+		err := c.saveState(c.Ctx(), autosavePath)
+		if err != nil {
+			c.log.Error().Err(err).Msg("failed to save state")
+		}
+	}
+
 	c.RLock()
 	defer c.RUnlock()
 	for _, client := range c.clients {
@@ -223,7 +262,7 @@ func (c *Cy) pollNodeEvents(ctx context.Context, events <-chan events.Msg) {
 
 			switch event := nodeEvent.Event.(type) {
 			case replay.CopyEvent:
-				client.buffer = event.Text
+				c.copyText(client, event.Register, event.Text)
 			case bind.BindEvent:
 				go client.runAction(event)
 			}
@@ -250,6 +289,9 @@ func Start(ctx context.Context, options Options) (*Cy, error) {
 		lastWrite:  make(map[tree.NodeID]historyEvent),
 		writes:     make(chan historyEvent),
 		visits:     make(chan historyEvent),
+		registers:  newRegisters(),
+		clock:      options.Clock,
+		dryRun:     options.DryRun,
 	}
 	cy.toast = NewToastLogger(cy.sendToast)
 
@@ -293,6 +335,11 @@ func Start(ctx context.Context, options Options) (*Cy, error) {
 
 	cy.VM = vm
 
+	if len(options.PluginDir) != 0 {
+		cy.pluginDir = options.PluginDir
+		cy.loadPlugins()
+	}
+
 	if len(options.Config) != 0 {
 		cy.configPath = options.Config
 		cy.loadConfig()
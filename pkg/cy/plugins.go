@@ -0,0 +1,315 @@
+package cy
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/cfoust/cy/pkg/janet"
+)
+
+// PLUGIN_MANIFEST is the name of the file that describes a plugin.
+const PLUGIN_MANIFEST = "plugin.json"
+
+// Plugin names become the prefix of the symbols they export, so they must be
+// valid in a Janet symbol.
+var pluginNameRe = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// A Plugin is a directory of Janet code that extends cy. Each plugin is
+// evaluated in its own environment and its public bindings are made
+// available as "[name]/[symbol]".
+type Plugin struct {
+	Name         string
+	Version      string
+	Description  string
+	Dependencies []string
+	// The directory containing the plugin.
+	Path string
+	// Whether the plugin's code was evaluated successfully.
+	Loaded bool
+	// The error that occurred when the plugin was last loaded, if any.
+	Error string
+
+	// The path to the file that is evaluated to load the plugin.
+	main string
+}
+
+type pluginManifest struct {
+	Name         string   `json:"name"`
+	Version      string   `json:"version"`
+	Description  string   `json:"description"`
+	Dependencies []string `json:"dependencies"`
+	// The file to evaluate, relative to the plugin's directory. Defaults
+	// to init.janet.
+	Main string `json:"main"`
+}
+
+// readPlugin reads the manifest of the plugin in `dir`.
+func readPlugin(dir string) (*Plugin, error) {
+	data, err := os.ReadFile(filepath.Join(dir, PLUGIN_MANIFEST))
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := pluginManifest{
+		Name: filepath.Base(dir),
+		Main: "init.janet",
+	}
+	err = json.Unmarshal(data, &manifest)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"invalid manifest for plugin in %s: %s",
+			dir,
+			err,
+		)
+	}
+
+	if !pluginNameRe.MatchString(manifest.Name) {
+		return nil, fmt.Errorf(
+			"invalid plugin name %q in %s",
+			manifest.Name,
+			dir,
+		)
+	}
+
+	return &Plugin{
+		Name:         manifest.Name,
+		Version:      manifest.Version,
+		Description:  manifest.Description,
+		Dependencies: manifest.Dependencies,
+		Path:         dir,
+		main:         filepath.Join(dir, manifest.Main),
+	}, nil
+}
+
+// readPlugins reads the manifests of all of the plugins in `dir`, which
+// contains one directory per plugin.
+func readPlugins(dir string) (plugins []*Plugin, errs []error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	names := make(map[string]struct{})
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		plugin, err := readPlugin(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if _, ok := names[plugin.Name]; ok {
+			errs = append(errs, fmt.Errorf(
+				"plugin %s in %s has the same name as another plugin",
+				plugin.Name,
+				plugin.Path,
+			))
+			continue
+		}
+		names[plugin.Name] = struct{}{}
+
+		plugins = append(plugins, plugin)
+	}
+
+	return
+}
+
+// sortPlugins orders `plugins` so that every plugin comes after its
+// dependencies. Plugins with missing or circular dependencies are still
+// included, but they fail to load because their dependencies are not loaded
+// before them.
+func sortPlugins(plugins []*Plugin) (order []*Plugin) {
+	byName := make(map[string]*Plugin)
+	for _, plugin := range plugins {
+		byName[plugin.Name] = plugin
+	}
+
+	sorted := make([]*Plugin, len(plugins))
+	copy(sorted, plugins)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	visited := make(map[string]bool)
+	var visit func(plugin *Plugin)
+	visit = func(plugin *Plugin) {
+		if visited[plugin.Name] {
+			return
+		}
+		visited[plugin.Name] = true
+
+		for _, name := range plugin.Dependencies {
+			if dependency, ok := byName[name]; ok {
+				visit(dependency)
+			}
+		}
+
+		order = append(order, plugin)
+	}
+
+	for _, plugin := range sorted {
+		visit(plugin)
+	}
+
+	return
+}
+
+func (c *Cy) findPlugin(name string) (*Plugin, bool) {
+	c.RLock()
+	defer c.RUnlock()
+
+	for _, plugin := range c.plugins {
+		if plugin.Name == name {
+			return plugin, true
+		}
+	}
+
+	return nil, false
+}
+
+func (c *Cy) evaluatePlugin(plugin *Plugin) error {
+	for _, name := range plugin.Dependencies {
+		dependency, ok := c.findPlugin(name)
+		if !ok {
+			return fmt.Errorf("missing dependency %s", name)
+		}
+
+		c.RLock()
+		loaded := dependency.Loaded
+		c.RUnlock()
+		if !loaded {
+			return fmt.Errorf("dependency %s is not loaded", name)
+		}
+	}
+
+	// The plugin's code runs in a new environment so that its private
+	// definitions do not leak into the user's environment. Its public
+	// definitions are then added directly to the VM's environment (the
+	// parent of the one the evaluation runs in), which keeps them even if
+	// the plugin is reloaded while other Janet code is running.
+	//
+	// go/evaluate is used rather than dofile because dofile traps the
+	// signals that API functions use to call into Go, which would stop
+	// the plugin at the first API call.
+	code := fmt.Sprintf(
+		`(let [target (table/getproto (curenv))
+		       env (go/evaluate (slurp %q) target %q)]
+			(when (string? env) (error env))
+			(merge-module target env %q true))`,
+		plugin.main,
+		plugin.main,
+		plugin.Name+"/",
+	)
+
+	return c.ExecuteCall(c.Ctx(), nil, janet.Call{
+		Code:       []byte(code),
+		SourcePath: plugin.main,
+	})
+}
+
+// loadPlugin evaluates `plugin`. Errors are reported to the user, but they
+// do not prevent other plugins or the configuration from loading.
+func (c *Cy) loadPlugin(plugin *Plugin) error {
+	err := c.evaluatePlugin(plugin)
+
+	c.Lock()
+	plugin.Loaded = err == nil
+	plugin.Error = ""
+	if err != nil {
+		plugin.Error = err.Error()
+	}
+	c.Unlock()
+
+	if err != nil {
+		c.log.Error().Err(err).Msgf("failed to load plugin %s", plugin.Name)
+		c.toast.Error(fmt.Sprintf(
+			"an error occurred while loading plugin %s: %s",
+			plugin.Name,
+			err.Error(),
+		))
+	}
+
+	return err
+}
+
+// loadPlugins loads all of the plugins in the plugin directory.
+func (c *Cy) loadPlugins() {
+	plugins, errs := readPlugins(c.pluginDir)
+	for _, err := range errs {
+		c.log.Error().Err(err).Msg("failed to read plugin")
+		c.toast.Error(err.Error())
+	}
+
+	c.Lock()
+	c.plugins = plugins
+	c.Unlock()
+
+	for _, plugin := range sortPlugins(plugins) {
+		c.loadPlugin(plugin)
+	}
+}
+
+type PluginModule struct {
+	cy *Cy
+}
+
+var _ janet.Documented = (*PluginModule)(nil)
+
+//go:embed docs-plugin.md
+var DOCS_PLUGIN string
+
+func (p *PluginModule) Documentation() string {
+	return DOCS_PLUGIN
+}
+
+func (p *PluginModule) List() []Plugin {
+	p.cy.RLock()
+	defer p.cy.RUnlock()
+
+	plugins := make([]Plugin, 0, len(p.cy.plugins))
+	for _, plugin := range p.cy.plugins {
+		plugins = append(plugins, *plugin)
+	}
+
+	sort.Slice(plugins, func(i, j int) bool {
+		return plugins[i].Name < plugins[j].Name
+	})
+	return plugins
+}
+
+func (p *PluginModule) Reload(name string) error {
+	plugin, ok := p.cy.findPlugin(name)
+	if !ok {
+		return fmt.Errorf("plugin %s not found", name)
+	}
+
+	// Pick up any changes to the manifest
+	updated, err := readPlugin(plugin.Path)
+	if err != nil {
+		return err
+	}
+
+	if updated.Name != plugin.Name {
+		return fmt.Errorf(
+			"plugin %s cannot be renamed while cy is running",
+			plugin.Name,
+		)
+	}
+
+	p.cy.Lock()
+	plugin.Version = updated.Version
+	plugin.Description = updated.Description
+	plugin.Dependencies = updated.Dependencies
+	plugin.main = updated.main
+	p.cy.Unlock()
+
+	return p.cy.loadPlugin(plugin)
+}
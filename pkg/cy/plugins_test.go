@@ -0,0 +1,120 @@
+package cy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writePlugin(t *testing.T, dir, name, manifest, code string) {
+	pluginDir := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(pluginDir, 0755))
+	require.NoError(t, os.WriteFile(
+		filepath.Join(pluginDir, PLUGIN_MANIFEST),
+		[]byte(manifest),
+		0644,
+	))
+	require.NoError(t, os.WriteFile(
+		filepath.Join(pluginDir, "init.janet"),
+		[]byte(code),
+		0644,
+	))
+}
+
+func TestPlugins(t *testing.T) {
+	dir := t.TempDir()
+	writePlugin(t, dir, "base", `{"version": "1.0.0"}`, `
+(defn greet [name] (string "hello " name))
+(def- secret 1)
+(key/bind :root ["ctrl+b" "g"] (fn []))
+(def after-bind true)
+`)
+	writePlugin(t, dir, "dependent", `{"dependencies": ["base"]}`, `
+(defn greet-world [] (base/greet "world"))
+`)
+	writePlugin(t, dir, "broken", `{}`, `(error "oops")`)
+	writePlugin(t, dir, "orphan", `{"dependencies": ["missing"]}`, `
+(def value 1)
+`)
+	// Not a valid manifest
+	writePlugin(t, dir, "invalid", `{`, ``)
+
+	config := filepath.Join(t.TempDir(), "cyrc.janet")
+	require.NoError(t, os.WriteFile(
+		config,
+		[]byte(`(def from-config (dependent/greet-world))`),
+		0644,
+	))
+
+	server, err := Start(context.Background(), Options{
+		Shell:     "/bin/bash",
+		PluginDir: dir,
+		Config:    config,
+	})
+	require.NoError(t, err)
+
+	plugins := (&PluginModule{cy: server}).List()
+	require.Len(t, plugins, 4)
+
+	loaded := make(map[string]bool)
+	for _, plugin := range plugins {
+		loaded[plugin.Name] = plugin.Loaded
+		if plugin.Loaded {
+			require.Empty(t, plugin.Error)
+		} else {
+			require.NotEmpty(t, plugin.Error)
+		}
+	}
+	require.Equal(t, map[string]bool{
+		"base":      true,
+		"broken":    false,
+		"dependent": true,
+		"orphan":    false,
+	}, loaded)
+	require.Equal(t, "1.0.0", plugins[0].Version)
+
+	// Failing plugins do not prevent the config from loading
+	require.NoError(t, server.Execute(server.Ctx(), `
+(assert (= from-config "hello world"))
+(assert (= (length (plugin/list)) 4))
+`))
+
+	// Plugins can call API functions
+	_, _, ok := server.tree.Root().Binds().Get([]string{"ctrl+b", "g"})
+	require.True(t, ok)
+	require.NoError(t, server.Execute(server.Ctx(), `(assert base/after-bind)`))
+
+	// Private definitions are not exported
+	require.Error(t, server.Execute(server.Ctx(), `base/secret`))
+
+	// Fix the broken plugin and reload it
+	writePlugin(t, dir, "broken", `{}`, `(def fixed true)`)
+	require.NoError(t, server.Execute(server.Ctx(), `(plugin/reload "broken")`))
+	require.NoError(t, server.Execute(server.Ctx(), `(assert broken/fixed)`))
+	plugin, ok := server.findPlugin("broken")
+	require.True(t, ok)
+	require.True(t, plugin.Loaded)
+	require.Empty(t, plugin.Error)
+
+	require.Error(t, server.Execute(server.Ctx(), `(plugin/reload "orphan")`))
+	require.Error(t, server.Execute(server.Ctx(), `(plugin/reload "nonexistent")`))
+}
+
+func TestSortPlugins(t *testing.T) {
+	order := sortPlugins([]*Plugin{
+		{Name: "c", Dependencies: []string{"b"}},
+		{Name: "b", Dependencies: []string{"a"}},
+		{Name: "a"},
+		{Name: "d", Dependencies: []string{"e"}},
+		{Name: "e", Dependencies: []string{"d"}},
+	})
+
+	var names []string
+	for _, plugin := range order {
+		names = append(names, plugin.Name)
+	}
+	require.Equal(t, []string{"a", "b", "c", "e", "d"}, names)
+}
@@ -0,0 +1,182 @@
+package cy
+
+import (
+	_ "embed"
+	"fmt"
+	"sort"
+
+	"github.com/cfoust/cy/pkg/janet"
+
+	"github.com/sasha-s/go-deadlock"
+)
+
+// registers stores text that users have copied. Named registers and the
+// copy history are shared by all clients.
+type registers struct {
+	deadlock.RWMutex
+	named map[string]string
+	// The most recently copied text comes first
+	history []string
+}
+
+func newRegisters() *registers {
+	return &registers{
+		named: make(map[string]string),
+	}
+}
+
+func (r *registers) Set(name, text string) {
+	r.Lock()
+	defer r.Unlock()
+	r.named[name] = text
+}
+
+func (r *registers) Get(name string) (text string, ok bool) {
+	r.RLock()
+	defer r.RUnlock()
+	text, ok = r.named[name]
+	return
+}
+
+// Push adds `text` to the front of the copy history, which contains at
+// most `limit` entries. Text that is already in the history is moved to
+// the front rather than being added again. If `limit` is not positive, the
+// history is cleared instead.
+func (r *registers) Push(text string, limit int) {
+	r.Lock()
+	defer r.Unlock()
+
+	if limit <= 0 {
+		r.history = nil
+		return
+	}
+
+	history := []string{text}
+	for _, existing := range r.history {
+		if existing == text {
+			continue
+		}
+		history = append(history, existing)
+	}
+
+	if len(history) > limit {
+		history = history[:limit]
+	}
+
+	r.history = history
+}
+
+// Trim removes the oldest entries in the copy history so that it contains
+// at most `limit` entries.
+func (r *registers) Trim(limit int) {
+	r.Lock()
+	defer r.Unlock()
+
+	if limit <= 0 {
+		r.history = nil
+		return
+	}
+
+	if len(r.history) > limit {
+		r.history = r.history[:limit]
+	}
+}
+
+func (r *registers) History() []string {
+	r.RLock()
+	defer r.RUnlock()
+
+	history := make([]string, len(r.history))
+	copy(history, r.history)
+	return history
+}
+
+// copyText stores text copied by `client`, optionally into the register
+// named `register`. `client` may be nil if the text was not copied by a
+// client.
+func (c *Cy) copyText(client *Client, register, text string) {
+	// Just like in vim, copying into a named register still replaces
+	// what (cy/paste) pastes by default
+	if client != nil {
+		client.buffer = text
+	}
+
+	if len(register) > 0 {
+		c.registers.Set(register, text)
+	}
+
+	c.registers.Push(text, c.tree.Root().Params().CopyHistory())
+}
+
+type RegisterModule struct {
+	cy *Cy
+}
+
+var _ janet.Documented = (*RegisterModule)(nil)
+
+//go:embed docs-register.md
+var DOCS_REGISTER string
+
+func (r *RegisterModule) Documentation() string {
+	return DOCS_REGISTER
+}
+
+type Register struct {
+	Name string
+	Text string
+}
+
+func (r *RegisterModule) Set(name, text string) error {
+	if len(name) == 0 {
+		return fmt.Errorf("register name must not be empty")
+	}
+
+	r.cy.registers.Set(name, text)
+	return nil
+}
+
+func (r *RegisterModule) Get(name string) *string {
+	text, ok := r.cy.registers.Get(name)
+	if !ok {
+		return nil
+	}
+
+	return &text
+}
+
+func (r *RegisterModule) List() []Register {
+	r.cy.registers.RLock()
+	defer r.cy.registers.RUnlock()
+
+	registers := make([]Register, 0, len(r.cy.registers.named))
+	for name, text := range r.cy.registers.named {
+		registers = append(registers, Register{
+			Name: name,
+			Text: text,
+		})
+	}
+
+	sort.Slice(registers, func(i, j int) bool {
+		return registers[i].Name < registers[j].Name
+	})
+	return registers
+}
+
+type CopyParams struct {
+	Register string
+}
+
+func (r *RegisterModule) Copy(
+	user interface{},
+	text string,
+	named *janet.Named[CopyParams],
+) {
+	client, _ := user.(*Client)
+	r.cy.copyText(client, named.Values().Register, text)
+}
+
+func (r *RegisterModule) History() []string {
+	// The limit may have been lowered since the text was copied
+	r.cy.registers.Trim(r.cy.tree.Root().Params().CopyHistory())
+	return r.cy.registers.History()
+}
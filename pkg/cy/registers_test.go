@@ -0,0 +1,48 @@
+package cy
+
+import (
+	"testing"
+
+	"github.com/cfoust/cy/pkg/geom"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPush(t *testing.T) {
+	r := newRegisters()
+	r.Push("foo", 2)
+	r.Push("bar", 2)
+	require.Equal(t, []string{"bar", "foo"}, r.History())
+
+	// Duplicates move to the front
+	r.Push("foo", 2)
+	require.Equal(t, []string{"foo", "bar"}, r.History())
+
+	// Old entries are dropped
+	r.Push("baz", 2)
+	require.Equal(t, []string{"baz", "foo"}, r.History())
+}
+
+func TestCopyText(t *testing.T) {
+	server, create := setup(t)
+	client := create(geom.DEFAULT_SIZE)
+
+	server.copyText(client, "", "foo")
+	server.copyText(client, "a", "bar")
+	require.Equal(t, "bar", client.buffer)
+	require.Equal(t, []string{"bar", "foo"}, server.registers.History())
+
+	text, ok := server.registers.Get("a")
+	require.True(t, ok)
+	require.Equal(t, "bar", text)
+
+	// Lowering the limit trims the history
+	server.tree.Root().Params().SetCopyHistory(1)
+	require.Equal(t, []string{"bar"}, (&RegisterModule{cy: server}).History())
+
+	// The history can be disabled, which clears it
+	server.tree.Root().Params().SetCopyHistory(0)
+	server.copyText(client, "", "baz")
+	require.Equal(t, "baz", client.buffer)
+	require.Empty(t, server.registers.History())
+}
@@ -0,0 +1,423 @@
+// Package repl provides an interactive Janet REPL that runs on cy's Janet VM.
+// The REPL is a mux.Stream that behaves like a very simple line editor, which
+// lets it be wrapped in a Replayable and used like any other pane.
+package repl
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/cfoust/cy/pkg/bind"
+	"github.com/cfoust/cy/pkg/emu"
+	"github.com/cfoust/cy/pkg/janet"
+	"github.com/cfoust/cy/pkg/mux"
+	"github.com/cfoust/cy/pkg/replay"
+	"github.com/cfoust/cy/pkg/taro"
+	"github.com/cfoust/cy/pkg/util"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/sasha-s/go-deadlock"
+)
+
+const (
+	styleError = "\x1b[31m"
+	styleReset = "\x1b[0m"
+)
+
+// Stream evaluates the Janet code typed into it and writes back the results.
+type Stream struct {
+	util.Lifetime
+	deadlock.Mutex
+
+	vm *janet.VM
+	// The context passed to API functions, typically the client that
+	// created the REPL.
+	user interface{}
+
+	r *io.PipeReader
+	w *io.PipeWriter
+
+	// Output that has not yet been written to the pipe. Writing to the
+	// pipe blocks until the terminal reads from it, so it happens in a
+	// separate goroutine.
+	outputLock deadlock.Mutex
+	output     bytes.Buffer
+	hasOutput  chan struct{}
+
+	// The lines of the current expression that have already been
+	// submitted, but which did not form a complete expression.
+	pending []string
+	// The line being edited and the location of the cursor within it.
+	line   []rune
+	cursor int
+
+	// Expressions that were previously evaluated, oldest first.
+	history []string
+	// The index of the history entry being shown, or len(history) if the
+	// user is not browsing history.
+	historyIndex int
+
+	// The number of expressions that have been evaluated.
+	count int
+
+	// Cancels the evaluation in progress, if there is one.
+	cancel context.CancelFunc
+}
+
+var _ mux.Stream = (*Stream)(nil)
+
+func (s *Stream) Kill() {
+	s.Cancel()
+}
+
+// Resizing does nothing to the REPL.
+func (s *Stream) Resize(size mux.Size) error {
+	return nil
+}
+
+func (s *Stream) Read(p []byte) (n int, err error) {
+	return s.r.Read(p)
+}
+
+// Write handles input from the user.
+func (s *Stream) Write(data []byte) (n int, err error) {
+	s.Lock()
+	defer s.Unlock()
+
+	for i, w := 0, 0; i < len(data); i += w {
+		var msg taro.Msg
+		w, msg = taro.DetectOneMsg(data[i:])
+		if key, ok := msg.(taro.KeyMsg); ok {
+			s.handleKey(key)
+		}
+	}
+
+	return len(data), nil
+}
+
+func (s *Stream) write(text string) {
+	s.outputLock.Lock()
+	s.output.WriteString(text)
+	s.outputLock.Unlock()
+
+	select {
+	case s.hasOutput <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Stream) pollOutput() {
+	for {
+		select {
+		case <-s.Ctx().Done():
+			s.w.Close()
+			return
+		case <-s.hasOutput:
+		}
+
+		s.outputLock.Lock()
+		data := bytes.Clone(s.output.Bytes())
+		s.output.Reset()
+		s.outputLock.Unlock()
+
+		s.w.Write(data)
+	}
+}
+
+// prompt returns the prompt that should precede the line being edited. Like
+// Janet's own REPL, it contains the number of the expression and any
+// delimiters that are still open.
+func (s *Stream) prompt() string {
+	open := ""
+	if len(s.pending) > 0 {
+		open = delimiters(strings.Join(s.pending, "\n"))
+	}
+
+	return fmt.Sprintf("repl:%d:%s> ", s.count+1, open)
+}
+
+// redraw rewrites the line being edited and puts the cursor in the right
+// place.
+func (s *Stream) redraw() {
+	text := "\r\x1b[K" + s.prompt() + string(s.line)
+
+	if after := runewidth.StringWidth(string(s.line[s.cursor:])); after > 0 {
+		text += fmt.Sprintf("\x1b[%dD", after)
+	}
+
+	s.write(text)
+}
+
+func (s *Stream) insert(runes []rune) {
+	line := make([]rune, 0, len(s.line)+len(runes))
+	line = append(line, s.line[:s.cursor]...)
+	line = append(line, runes...)
+	line = append(line, s.line[s.cursor:]...)
+	s.line = line
+	s.cursor += len(runes)
+}
+
+func (s *Stream) setLine(text string) {
+	s.line = []rune(text)
+	s.cursor = len(s.line)
+}
+
+func (s *Stream) handleKey(key taro.KeyMsg) {
+	// Everything other than interrupting the evaluation is ignored
+	// until it finishes
+	if s.cancel != nil {
+		if key.Type == taro.KeyCtrlC {
+			s.cancel()
+		}
+		return
+	}
+
+	switch key.Type {
+	case taro.KeyRunes, taro.KeySpace:
+		if key.Type == taro.KeySpace {
+			key.Runes = []rune{' '}
+		}
+
+		// Pasted text can contain any number of lines, but it is
+		// only evaluated once all of them have been submitted
+		text := strings.NewReplacer(
+			"\r\n", "\n",
+			"\r", "\n",
+		).Replace(string(key.Runes))
+		lines := strings.Split(text, "\n")
+		for _, line := range lines[:len(lines)-1] {
+			s.insert([]rune(line))
+			s.redraw()
+			s.advance()
+		}
+
+		s.insert([]rune(lines[len(lines)-1]))
+		if len(lines) > 1 && len(s.line) == 0 {
+			s.evaluatePending()
+			return
+		}
+	case taro.KeyEnter:
+		s.advance()
+		s.evaluatePending()
+		return
+	case taro.KeyBackspace, taro.KeyCtrlH:
+		if s.cursor == 0 {
+			return
+		}
+
+		s.line = append(s.line[:s.cursor-1], s.line[s.cursor:]...)
+		s.cursor--
+	case taro.KeyDelete, taro.KeyCtrlD:
+		if s.cursor == len(s.line) {
+			return
+		}
+
+		s.line = append(s.line[:s.cursor], s.line[s.cursor+1:]...)
+	case taro.KeyLeft, taro.KeyCtrlB:
+		s.cursor = max(s.cursor-1, 0)
+	case taro.KeyRight, taro.KeyCtrlF:
+		s.cursor = min(s.cursor+1, len(s.line))
+	case taro.KeyHome, taro.KeyCtrlA:
+		s.cursor = 0
+	case taro.KeyEnd, taro.KeyCtrlE:
+		s.cursor = len(s.line)
+	case taro.KeyCtrlU:
+		s.line = s.line[s.cursor:]
+		s.cursor = 0
+	case taro.KeyCtrlK:
+		s.line = s.line[:s.cursor]
+	case taro.KeyUp, taro.KeyCtrlP:
+		if s.historyIndex == 0 {
+			return
+		}
+
+		s.historyIndex--
+		s.setLine(s.history[s.historyIndex])
+	case taro.KeyDown, taro.KeyCtrlN:
+		if s.historyIndex == len(s.history) {
+			return
+		}
+
+		s.historyIndex++
+		if s.historyIndex == len(s.history) {
+			s.setLine("")
+		} else {
+			s.setLine(s.history[s.historyIndex])
+		}
+	case taro.KeyCtrlC:
+		s.write("^C\n")
+		s.pending = nil
+		s.setLine("")
+		s.historyIndex = len(s.history)
+	case taro.KeyCtrlL:
+		s.write("\x1b[H\x1b[2J")
+	case taro.KeyTab:
+		s.complete()
+	default:
+		return
+	}
+
+	s.redraw()
+}
+
+// complete completes the API symbol before the cursor. If there are several
+// candidates and none of them can be chosen, they are printed along with
+// their signatures.
+func (s *Stream) complete() {
+	start := s.cursor
+	for start > 0 && isSymbolRune(s.line[start-1]) {
+		start--
+	}
+
+	prefix := string(s.line[start:s.cursor])
+	if len(prefix) == 0 {
+		return
+	}
+
+	var matches []string
+	for _, symbol := range s.vm.Symbols() {
+		if strings.HasPrefix(symbol, prefix) {
+			matches = append(matches, symbol)
+		}
+	}
+
+	if len(matches) == 0 {
+		return
+	}
+
+	if common := commonPrefix(matches); len(common) > len(prefix) {
+		s.insert([]rune(common[len(prefix):]))
+		return
+	}
+
+	if len(matches) == 1 {
+		return
+	}
+
+	s.write("\n")
+	for _, match := range matches {
+		signature := match
+		if callback, ok := s.vm.Lookup(match); ok {
+			docstring := callback.Docstring()
+			if strings.HasPrefix(docstring, "("+match) {
+				signature, _, _ = strings.Cut(docstring, "\n")
+			}
+		}
+
+		s.write(signature + "\n")
+	}
+}
+
+// advance moves the line being edited to the lines of the current
+// expression.
+func (s *Stream) advance() {
+	s.write("\n")
+	s.pending = append(s.pending, string(s.line))
+	s.setLine("")
+}
+
+// evaluatePending evaluates the lines of the current expression if they are
+// complete. Otherwise it prompts for more input.
+func (s *Stream) evaluatePending() {
+	source := strings.Join(s.pending, "\n")
+	if len(strings.TrimSpace(source)) == 0 {
+		s.pending = nil
+		s.redraw()
+		return
+	}
+
+	if len(delimiters(source)) > 0 {
+		s.redraw()
+		return
+	}
+
+	s.pending = nil
+	if len(s.history) == 0 || s.history[len(s.history)-1] != source {
+		s.history = append(s.history, source)
+	}
+	s.historyIndex = len(s.history)
+	s.count++
+
+	ctx, cancel := context.WithCancel(s.Ctx())
+	s.cancel = cancel
+	go s.evaluate(ctx, source)
+}
+
+func (s *Stream) evaluate(ctx context.Context, source string) {
+	evaluation, err := s.vm.Evaluate(ctx, s.user, janet.Call{
+		Code:       []byte(source),
+		SourcePath: "repl",
+		Options:    janet.DEFAULT_CALL_OPTIONS,
+	})
+
+	s.Lock()
+	defer s.Unlock()
+	s.cancel()
+	s.cancel = nil
+
+	if evaluation != nil && len(evaluation.Output) > 0 {
+		output := evaluation.Output
+		if !strings.HasSuffix(output, "\n") {
+			output += "\n"
+		}
+		s.write(output)
+	}
+
+	if err != nil {
+		s.write(styleError + "error: " + err.Error() + styleReset + "\n")
+	} else {
+		s.write(evaluation.Result + "\n")
+	}
+
+	s.redraw()
+}
+
+// NewStream creates a Stream that evaluates code on `vm`. `user` is provided
+// as the context for API functions called from the REPL.
+func NewStream(
+	ctx context.Context,
+	vm *janet.VM,
+	user interface{},
+) *Stream {
+	r, w := io.Pipe()
+	s := &Stream{
+		Lifetime:  util.NewLifetime(ctx),
+		vm:        vm,
+		user:      user,
+		r:         r,
+		w:         w,
+		hasOutput: make(chan struct{}, 1),
+	}
+
+	go s.pollOutput()
+
+	// Set the terminal to CRLF mode so that Janet's output, which only
+	// contains line feeds, returns to the first column
+	s.write(emu.LineFeedMode)
+	s.write("Janet REPL connected to cy. Use (doc) for help.\n")
+	s.redraw()
+
+	return s
+}
+
+// New creates a Replayable containing a Janet REPL.
+func New(
+	ctx context.Context,
+	vm *janet.VM,
+	user interface{},
+	timeBinds, copyBinds *bind.BindScope,
+	termOptions ...emu.TerminalOption,
+) *replay.Replayable {
+	s := NewStream(ctx, vm, user)
+	return replay.NewReplayable(
+		s.Ctx(),
+		s,
+		s,
+		timeBinds,
+		copyBinds,
+		termOptions...,
+	)
+}
@@ -0,0 +1,180 @@
+package repl
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cfoust/cy/pkg/janet"
+
+	"github.com/sasha-s/go-deadlock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDelimiters(t *testing.T) {
+	for _, test := range []struct {
+		source string
+		open   string
+	}{
+		{"(+ 1 2)", ""},
+		{"(+ 1", "("},
+		{"(def a [1 {:a", "([{"},
+		{`(print "(")`, ""},
+		{`(print "a`, `("`},
+		{`(print "\"`, `("`},
+		{"(print ``a)`` ", "("},
+		{"(print `a", "(`"},
+		{"(+ 1 # )\n", "("},
+		{"(+ 1))", ""},
+	} {
+		require.Equal(
+			t,
+			test.open,
+			delimiters(test.source),
+			"source: %s", test.source,
+		)
+	}
+}
+
+func TestCommonPrefix(t *testing.T) {
+	require.Equal(t, "pane/", commonPrefix([]string{
+		"pane/attach",
+		"pane/current",
+	}))
+	require.Equal(t, "pane/attach", commonPrefix([]string{
+		"pane/attach",
+	}))
+}
+
+type testModule struct{}
+
+func (t *testModule) Documentation() string {
+	return `
+# doc: Attach
+
+(test/attach id)
+
+Attach to a node.
+
+# doc: Attribute
+
+(test/attribute name)
+
+Get an attribute.
+`
+}
+
+func (t *testModule) Attach(id int) {}
+
+func (t *testModule) Attribute(name string) {}
+
+// setup creates a REPL and returns a function that waits for its output to
+// contain the given text.
+func setup(t *testing.T) (*Stream, func(string)) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	vm, err := janet.New(ctx)
+	require.NoError(t, err)
+	require.NoError(t, vm.Module("test", &testModule{}))
+
+	s := NewStream(ctx, vm, nil)
+
+	var (
+		lock   deadlock.Mutex
+		output bytes.Buffer
+	)
+	go func() {
+		buffer := make([]byte, 1024)
+		for {
+			n, err := s.Read(buffer)
+			if err != nil {
+				return
+			}
+
+			lock.Lock()
+			output.Write(buffer[:n])
+			lock.Unlock()
+		}
+	}()
+
+	return s, func(text string) {
+		require.Eventually(t, func() bool {
+			lock.Lock()
+			defer lock.Unlock()
+			return bytes.Contains(output.Bytes(), []byte(text))
+		}, 2*time.Second, 10*time.Millisecond, "waiting for %q", text)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	s, waitFor := setup(t)
+	waitFor("repl:1:> ")
+
+	// Results are pretty-printed
+	s.Write([]byte("(+ 1 2)\r"))
+	waitFor("\x1b[32m3\x1b[0m\n")
+	waitFor("repl:2:> ")
+
+	// Definitions persist
+	s.Write([]byte("(def value 42)\r"))
+	waitFor("repl:3:> ")
+	s.Write([]byte("(print value)\r"))
+	waitFor("42\n")
+
+	// Errors are shown
+	s.Write([]byte("(error \"oops\")\r"))
+	waitFor("error: oops")
+}
+
+func TestMultiline(t *testing.T) {
+	s, waitFor := setup(t)
+
+	s.Write([]byte("(+ 1\r"))
+	waitFor("repl:1:(> ")
+	s.Write([]byte("2)\r"))
+	waitFor("\x1b[32m3\x1b[0m\n")
+	waitFor("repl:2:> ")
+
+	// Pasted text is evaluated all at once
+	s.Write([]byte("\x1b[200~(def a 1)\n(+ a\n1)\n\x1b[201~"))
+	waitFor("\x1b[32m2\x1b[0m\n")
+	waitFor("repl:3:> ")
+}
+
+func TestComplete(t *testing.T) {
+	s, waitFor := setup(t)
+
+	// Prefixes are expanded as far as possible
+	s.Write([]byte("(test/a\t"))
+	s.Lock()
+	require.Equal(t, "(test/att", string(s.line))
+	s.Unlock()
+
+	// Ambiguous candidates are listed with their signatures
+	s.Write([]byte("\t"))
+	waitFor("(test/attach id)\n(test/attribute name)\n")
+
+	s.Write([]byte("a\t"))
+	s.Lock()
+	require.Equal(t, "(test/attach", string(s.line))
+	s.Unlock()
+}
+
+func TestHistory(t *testing.T) {
+	s, waitFor := setup(t)
+
+	s.Write([]byte("(+ 1 2)\r"))
+	waitFor("repl:2:> ")
+
+	s.Write([]byte("\x1b[A"))
+	s.Lock()
+	require.Equal(t, "(+ 1 2)", string(s.line))
+	s.Unlock()
+
+	s.Write([]byte("\x1b[B"))
+	s.Lock()
+	require.Equal(t, "", string(s.line))
+	s.Unlock()
+}
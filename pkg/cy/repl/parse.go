@@ -0,0 +1,82 @@
+package repl
+
+import (
+	"strings"
+	"unicode"
+)
+
+// delimiters returns the delimiters in `source` that have not been closed,
+// outermost first. An unterminated string is reported as the delimiter that
+// opened it. Closing delimiters that do not match anything are ignored,
+// since Janet will report them when the code is evaluated.
+func delimiters(source string) string {
+	// All of Janet's delimiters are ASCII, so it is safe to operate on
+	// bytes
+	var stack []byte
+	for i := 0; i < len(source); i++ {
+		switch c := source[i]; c {
+		case '#':
+			for i < len(source) && source[i] != '\n' {
+				i++
+			}
+		case '"':
+			i++
+			for ; i < len(source) && source[i] != '"'; i++ {
+				if source[i] == '\\' {
+					i++
+				}
+			}
+
+			if i >= len(source) {
+				return string(append(stack, '"'))
+			}
+		case '`':
+			// Long strings are delimited by any number of backticks
+			width := 0
+			for i < len(source) && source[i] == '`' {
+				width++
+				i++
+			}
+
+			end := strings.Index(source[i:], strings.Repeat("`", width))
+			if end == -1 {
+				return string(append(stack, '`'))
+			}
+
+			i += end + width - 1
+		case '(', '[', '{':
+			stack = append(stack, c)
+		case ')', ']', '}':
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+
+	return string(stack)
+}
+
+// isSymbolRune reports whether `r` can appear in a Janet symbol.
+func isSymbolRune(r rune) bool {
+	if unicode.IsLetter(r) || unicode.IsDigit(r) || r > unicode.MaxASCII {
+		return true
+	}
+
+	return strings.ContainsRune("!$%&*+-./:<=>?@^_", r)
+}
+
+// commonPrefix returns the longest prefix shared by all of `words`.
+func commonPrefix(words []string) string {
+	if len(words) == 0 {
+		return ""
+	}
+
+	prefix := words[0]
+	for _, word := range words[1:] {
+		for !strings.HasPrefix(word, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+
+	return prefix
+}
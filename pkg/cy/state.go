@@ -0,0 +1,607 @@
+package cy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cfoust/cy/pkg/bind"
+	"github.com/cfoust/cy/pkg/bind/trie"
+	"github.com/cfoust/cy/pkg/cy/cmd"
+	"github.com/cfoust/cy/pkg/emu"
+	P "github.com/cfoust/cy/pkg/io/protocol"
+	"github.com/cfoust/cy/pkg/janet"
+	"github.com/cfoust/cy/pkg/mux/screen/tree"
+	"github.com/cfoust/cy/pkg/mux/stream"
+	"github.com/cfoust/cy/pkg/params"
+	"github.com/cfoust/cy/pkg/replay"
+	"github.com/cfoust/cy/pkg/sessions"
+)
+
+// STATE_VERSION is incremented whenever the format of saved state changes in
+// a way that older versions of cy cannot read.
+const STATE_VERSION = 1
+
+// savedState is the representation of the node tree written by
+// (cy/save-state).
+type savedState struct {
+	Version int
+	Saved   time.Time
+	Root    savedNode
+}
+
+type savedParam struct {
+	Key string
+	// Parameters that are stored as Go values, such as those that have
+	// defaults, are saved as JSON.
+	Value interface{} `json:",omitempty"`
+	// Parameters that are Janet values are saved using
+	// janet.VM.Serialize.
+	Janet []byte `json:",omitempty"`
+}
+
+type savedBind struct {
+	// The key sequence, in the form returned by trie.Trie.Leaves.
+	Sequence []string
+	Tag      string `json:",omitempty"`
+	// The binding's Janet function, saved using janet.VM.Serialize.
+	Callback []byte
+}
+
+type savedPane struct {
+	Command string
+	Args    []string `json:",omitempty"`
+	// The working directory of the pane's process at the time the state
+	// was saved.
+	Directory string
+	// The .borg file to which the pane's output was being recorded, if
+	// any.
+	Recording string `json:",omitempty"`
+}
+
+type savedNode struct {
+	Name      string
+	Protected bool         `json:",omitempty"`
+	Params    []savedParam `json:",omitempty"`
+	Binds     []savedBind  `json:",omitempty"`
+	// Only set for groups.
+	Children []savedNode `json:",omitempty"`
+	// Only set for panes.
+	Pane *savedPane `json:",omitempty"`
+}
+
+// saveParams returns the parameters set on `node`. An error is returned if
+// any parameter cannot be saved, since saving state without it would
+// silently lose data.
+func (c *Cy) saveParams(
+	ctx context.Context,
+	node tree.Node,
+) (saved []savedParam, err error) {
+	for key, value := range node.Params().Local() {
+		switch value := value.(type) {
+		case bool, int, string, []string:
+			saved = append(saved, savedParam{
+				Key:   key,
+				Value: value,
+			})
+		case *janet.Value:
+			data, err := c.Serialize(ctx, value)
+			if err != nil {
+				return nil, fmt.Errorf(
+					"could not save parameter :%s of node %d: %s",
+					key,
+					node.Id(),
+					err,
+				)
+			}
+
+			saved = append(saved, savedParam{
+				Key:   key,
+				Janet: data,
+			})
+		}
+	}
+
+	sort.Slice(saved, func(i, j int) bool {
+		return saved[i].Key < saved[j].Key
+	})
+	return saved, nil
+}
+
+// saveBinds returns the bindings in `node`'s bind scope. Like saveParams,
+// it fails if any binding cannot be saved.
+func (c *Cy) saveBinds(
+	ctx context.Context,
+	node tree.Node,
+) (saved []savedBind, err error) {
+	for _, leaf := range node.Binds().Leaves() {
+		data, err := c.Serialize(ctx, leaf.Value.Callback.Value)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"could not save binding %s of node %d: %s",
+				strings.Join(leaf.Path, " "),
+				node.Id(),
+				err,
+			)
+		}
+
+		saved = append(saved, savedBind{
+			Sequence: leaf.Path,
+			Tag:      leaf.Value.Tag,
+			Callback: data,
+		})
+	}
+
+	sort.Slice(saved, func(i, j int) bool {
+		return strings.Join(saved[i].Sequence, " ") <
+			strings.Join(saved[j].Sequence, " ")
+	})
+	return saved, nil
+}
+
+// savePane describes how to recreate `pane`. Only panes that run a command
+// can be recreated.
+func savePane(pane *tree.Pane) (*savedPane, bool) {
+	r, ok := pane.Screen().(*replay.Replayable)
+	if !ok {
+		return nil, false
+	}
+
+	command, ok := r.Cmd().(*stream.Cmd)
+	if !ok {
+		return nil, false
+	}
+
+	options := command.Options()
+	saved := &savedPane{
+		Command:   options.Command,
+		Args:      options.Args,
+		Directory: options.Directory,
+	}
+
+	if path, err := command.Path(); err == nil {
+		saved.Directory = path
+	}
+
+	if path, ok := cmd.Recording(r); ok {
+		saved.Recording = path
+	}
+
+	return saved, true
+}
+
+// saveNode describes `node` and its descendants. `ok` is false if `node`
+// is a pane that cannot be recreated, in which case it is left out of the
+// saved state.
+func (c *Cy) saveNode(
+	ctx context.Context,
+	node tree.Node,
+) (saved savedNode, ok bool, err error) {
+	saved = savedNode{
+		Name:      node.Name(),
+		Protected: node.Protected(),
+	}
+
+	saved.Params, err = c.saveParams(ctx, node)
+	if err != nil {
+		return
+	}
+
+	saved.Binds, err = c.saveBinds(ctx, node)
+	if err != nil {
+		return
+	}
+
+	switch node := node.(type) {
+	case *tree.Group:
+		for _, child := range node.Children() {
+			savedChild, ok, err := c.saveNode(ctx, child)
+			if err != nil {
+				return saved, false, err
+			}
+			if !ok {
+				continue
+			}
+			saved.Children = append(saved.Children, savedChild)
+		}
+	case *tree.Pane:
+		saved.Pane, ok = savePane(node)
+		if !ok {
+			return
+		}
+	}
+
+	return saved, true, nil
+}
+
+// saveState writes the state of the node tree to the file at `path`. If any
+// part of the tree cannot be saved, the existing file is left untouched.
+func (c *Cy) saveState(ctx context.Context, path string) error {
+	root, _, err := c.saveNode(ctx, c.tree.Root())
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(savedState{
+		Version: STATE_VERSION,
+		Saved:   time.Now(),
+		Root:    root,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so that a crash never leaves a
+	// partially written state file behind
+	temp := path + ".tmp"
+	err = os.WriteFile(temp, data, 0600)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(temp, path)
+}
+
+// restoreParam converts a parameter value decoded from JSON back into the
+// type it had when it was saved.
+func restoreParam(value interface{}) interface{} {
+	switch value := value.(type) {
+	case float64:
+		return int(value)
+	case []interface{}:
+		strs := make([]string, 0, len(value))
+		for _, item := range value {
+			str, ok := item.(string)
+			if !ok {
+				return value
+			}
+			strs = append(strs, str)
+		}
+		return strs
+	}
+
+	return value
+}
+
+func (c *Cy) restoreParams(
+	ctx context.Context,
+	target *params.Parameters,
+	saved []savedParam,
+) error {
+	for _, param := range saved {
+		var value interface{} = restoreParam(param.Value)
+		if param.Janet != nil {
+			janetValue, err := c.Deserialize(ctx, param.Janet)
+			if err != nil {
+				return fmt.Errorf(
+					"could not restore parameter :%s: %s",
+					param.Key,
+					err,
+				)
+			}
+			value = janetValue
+		}
+
+		err := target.Set(param.Key, value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseSequence converts a key sequence in the form returned by
+// trie.Trie.Leaves into one that can be passed to trie.Trie.Set.
+func parseSequence(path []string) ([]interface{}, error) {
+	sequence := make([]interface{}, 0, len(path))
+	for _, key := range path {
+		pattern, ok := strings.CutPrefix(key, "re:")
+		if !ok {
+			sequence = append(sequence, key)
+			continue
+		}
+
+		re, err := trie.NewRegex(pattern)
+		if err != nil {
+			return nil, err
+		}
+		sequence = append(sequence, re)
+	}
+
+	return sequence, nil
+}
+
+func (c *Cy) restoreBinds(
+	ctx context.Context,
+	scope *bind.BindScope,
+	saved []savedBind,
+) error {
+	for _, savedBind := range saved {
+		sequence, err := parseSequence(savedBind.Sequence)
+		if err != nil {
+			return err
+		}
+
+		value, err := c.Deserialize(ctx, savedBind.Callback)
+		if err != nil {
+			return fmt.Errorf(
+				"could not restore binding %s: %s",
+				strings.Join(savedBind.Sequence, " "),
+				err,
+			)
+		}
+
+		var callback *janet.Function
+		err = value.Unmarshal(&callback)
+		value.Free()
+		if err != nil {
+			return err
+		}
+
+		scope.Set(sequence, bind.Action{
+			Tag:      savedBind.Tag,
+			Callback: callback,
+		})
+	}
+
+	return nil
+}
+
+// readScreen reconstructs the screen at the end of the recording at `path`
+// and returns its text. Recordings made by servers that did not exit cleanly
+// may be truncated, so any error while reading events (including io.EOF)
+// just ends the recording early.
+func readScreen(path string) ([]byte, error) {
+	reader, err := sessions.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	term := emu.New()
+	for {
+		event, err := reader.Read()
+		if err != nil {
+			break
+		}
+
+		switch e := event.Message.(type) {
+		case P.OutputMessage:
+			term.Parse(e.Data)
+		case P.SizeMessage:
+			term.Resize(e.Vec())
+		}
+	}
+
+	var lines []string
+	for _, line := range term.Screen() {
+		lines = append(lines, strings.TrimRight(line.String(), " "))
+	}
+
+	for len(lines) > 0 && len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+
+	if len(lines) == 0 {
+		return nil, nil
+	}
+
+	return []byte(strings.Join(lines, "\r\n") + "\r\n"), nil
+}
+
+func (c *Cy) restorePane(
+	group *tree.Group,
+	saved *savedPane,
+	screens bool,
+) (*tree.Pane, error) {
+	var prefix []byte
+	if screens && len(saved.Recording) > 0 {
+		screen, err := readScreen(saved.Recording)
+		if err != nil {
+			c.log.Warn().Msgf(
+				"could not read screen from %s: %s",
+				saved.Recording,
+				err,
+			)
+		}
+		prefix = screen
+	}
+
+	// The directory may have been removed since the state was saved
+	directory := saved.Directory
+	if info, err := os.Stat(directory); err != nil || !info.IsDir() {
+		directory = ""
+	}
+
+	replayable, err := cmd.NewWithPrefix(
+		c.Ctx(),
+		stream.CmdOptions{
+			Command:   saved.Command,
+			Args:      saved.Args,
+			Directory: directory,
+		},
+		group.Params().DataDirectory(),
+		prefix,
+		c.timeBinds,
+		c.copyBinds,
+		emu.WithScrollback(group.Params().ScrollbackLines()),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return group.NewPane(c.Ctx(), replayable), nil
+}
+
+// restoreChildren recreates the children of `group` described by `saved`.
+// Groups and panes that already existed with the same name are reused, each
+// at most once, so that restoring state more than once (for example, in a
+// configuration file that is reloaded) does not duplicate them. Errors are logged
+// rather than returned so that one pane that cannot be restored does not
+// prevent the rest of the tree from being restored.
+func (c *Cy) restoreChildren(
+	ctx context.Context,
+	group *tree.Group,
+	saved []savedNode,
+	screens bool,
+) {
+	// Each existing child can be matched to at most one saved child, and
+	// only children that existed before this restore began are
+	// considered, since siblings often share a name
+	existing := group.Children()
+	claimed := make(map[tree.Node]struct{})
+	claim := func(saved savedNode) tree.Node {
+		for _, node := range existing {
+			if _, ok := claimed[node]; ok {
+				continue
+			}
+
+			if node.Name() != saved.Name {
+				continue
+			}
+
+			_, isPane := node.(*tree.Pane)
+			if isPane != (saved.Pane != nil) {
+				continue
+			}
+
+			claimed[node] = struct{}{}
+			return node
+		}
+		return nil
+	}
+
+	for _, child := range saved {
+		match := claim(child)
+
+		var node tree.Node
+		if child.Pane != nil {
+			// A pane with the same name was probably restored by
+			// an earlier call, so only its parameters and bindings
+			// are restored
+			pane, ok := match.(*tree.Pane)
+			if !ok {
+				var err error
+				pane, err = c.restorePane(group, child.Pane, screens)
+				if err != nil {
+					c.log.Error().Msgf(
+						"could not restore pane %s: %s",
+						child.Name,
+						err,
+					)
+					continue
+				}
+			}
+			pane.SetProtected(child.Protected)
+			node = pane
+		} else {
+			childGroup, ok := match.(*tree.Group)
+			if !ok {
+				childGroup = group.NewGroup()
+			}
+
+			childGroup.SetProtected(child.Protected)
+			c.restoreChildren(ctx, childGroup, child.Children, screens)
+			node = childGroup
+		}
+
+		node.SetName(child.Name)
+		c.restoreNode(ctx, node, child)
+	}
+}
+
+// restoreNode restores the parameters and bindings of `node`.
+func (c *Cy) restoreNode(ctx context.Context, node tree.Node, saved savedNode) {
+	err := c.restoreParams(ctx, node.Params(), saved.Params)
+	if err != nil {
+		c.log.Error().Msgf(
+			"could not restore parameters for %s: %s",
+			saved.Name,
+			err,
+		)
+	}
+
+	err = c.restoreBinds(ctx, node.Binds(), saved.Binds)
+	if err != nil {
+		c.log.Error().Msgf(
+			"could not restore bindings for %s: %s",
+			saved.Name,
+			err,
+		)
+	}
+}
+
+// restoreState recreates the node tree saved in the file at `path` inside of
+// the current tree. If `screens` is true, each pane initially shows the last
+// screen of its previous recording.
+func (c *Cy) restoreState(
+	ctx context.Context,
+	path string,
+	screens bool,
+) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var state savedState
+	err = json.Unmarshal(data, &state)
+	if err != nil {
+		return err
+	}
+
+	if state.Version != STATE_VERSION {
+		return fmt.Errorf(
+			"unsupported state version %d",
+			state.Version,
+		)
+	}
+
+	root := c.tree.Root()
+	c.restoreNode(ctx, root, state.Root)
+	c.restoreChildren(ctx, root, state.Root.Children, screens)
+	return nil
+}
+
+// setAutosave starts saving the state of the node tree to `path` every
+// `interval`, replacing any previous autosave. An empty `path` disables
+// autosaving.
+func (c *Cy) setAutosave(path string, interval time.Duration) {
+	c.Lock()
+	if c.stopAutosave != nil {
+		c.stopAutosave()
+		c.stopAutosave = nil
+	}
+	c.autosavePath = path
+	if len(path) == 0 {
+		c.Unlock()
+		return
+	}
+
+	ctx, cancel := context.WithCancel(c.Ctx())
+	c.stopAutosave = cancel
+	c.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			err := c.saveState(ctx, path)
+			if err != nil {
+				c.log.Error().Err(err).Msg("failed to save state")
+			}
+		}
+	}()
+}
@@ -0,0 +1,187 @@
+package cy
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cfoust/cy/pkg/cy/cmd"
+	"github.com/cfoust/cy/pkg/geom"
+	"github.com/cfoust/cy/pkg/mux/screen/tree"
+	"github.com/cfoust/cy/pkg/replay"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestState(t *testing.T) {
+	dir := t.TempDir()
+	statePath := filepath.Join(dir, "state.json")
+	dataDir := filepath.Join(dir, "data")
+
+	server, create := setup(t)
+	client := create(geom.DEFAULT_SIZE)
+	require.NoError(t, server.Callback("test/record", "", func() {}))
+
+	require.NoError(t, client.execute(`
+(param/set :root :data-directory "`+dataDir+`")
+(def group (group/new :root :name "work"))
+(param/set group :scrollback-lines 50)
+(def pane (cmd/new group :command "/bin/sh" :path "`+dir+`" :name "shell"))
+(param/set pane :custom @{:a [1 2 3]})
+(key/bind pane ["ctrl+a" "x"] (fn [] (test/record)) :tag "record")
+(pane/send-text pane "echo hello\n")
+(pane/wait-for pane "(?m)^hello$")
+`))
+
+	// Wait for the recording to be flushed to disk
+	node, ok := server.tree.Root().ChildByName("work")
+	require.True(t, ok)
+	node, ok = node.(*tree.Group).ChildByName("shell")
+	require.True(t, ok)
+	pane := node.(*tree.Pane)
+	recording, ok := cmd.Recording(pane.Screen().(*replay.Replayable))
+	require.True(t, ok)
+	require.Eventually(t, func() bool {
+		screen, _ := readScreen(recording)
+		return bytes.Contains(screen, []byte("hello"))
+	}, 5*time.Second, 50*time.Millisecond)
+
+	require.NoError(t, client.execute(`(cy/save-state "`+statePath+`")`))
+
+	// Restore the state in a new server
+	restored, _ := setup(t)
+	var calls atomic.Int32
+	require.NoError(t, restored.Callback("test/record", "", func() {
+		calls.Add(1)
+	}))
+	require.NoError(t, restored.Execute(
+		restored.Ctx(),
+		`(cy/restore-state "`+statePath+`" :screens true)`,
+	))
+
+	node, ok = restored.tree.Root().ChildByName("work")
+	require.True(t, ok)
+	group, ok := node.(*tree.Group)
+	require.True(t, ok)
+	require.Equal(t, 50, group.Params().ScrollbackLines())
+
+	node, ok = group.ChildByName("shell")
+	require.True(t, ok)
+	restoredPane, ok := node.(*tree.Pane)
+	require.True(t, ok)
+
+	_, ok = restoredPane.Params().Get("custom")
+	require.True(t, ok)
+
+	// The binding still works
+	action, _, ok := restoredPane.Binds().Get([]string{"ctrl+a", "x"})
+	require.True(t, ok)
+	require.Equal(t, "record", action.Tag)
+	require.NoError(t, action.Callback.Call(restored.Ctx()))
+	require.Equal(t, int32(1), calls.Load())
+
+	// The pane shows its previous screen and runs in the same directory
+	require.NoError(t, restored.Execute(restored.Ctx(), fmt.Sprintf(`
+(pane/wait-for %d "(?m)^hello$")
+(pane/send-text %d "pwd\n")
+(pane/wait-for %d %q)
+`,
+		restoredPane.Id(),
+		restoredPane.Id(),
+		restoredPane.Id(),
+		"(?m)^"+regexp.QuoteMeta(dir)+"$",
+	)))
+
+	// Restoring again reuses the existing group and pane
+	numChildren := len(restored.tree.Root().Children())
+	require.NoError(t, restored.restoreState(restored.Ctx(), statePath, false))
+	require.Len(t, group.Children(), 1)
+	require.Len(t, restored.tree.Root().Children(), numChildren)
+	node, ok = group.ChildByName("shell")
+	require.True(t, ok)
+	require.Equal(t, restoredPane.Id(), node.Id())
+
+	// State that cannot be saved does not replace the existing file
+	before, err := os.ReadFile(statePath)
+	require.NoError(t, err)
+	require.ErrorContains(t, client.execute(`
+(param/set :root :unsaveable (file/temp))
+(cy/save-state "`+statePath+`")
+`), "could not save parameter :unsaveable")
+	after, err := os.ReadFile(statePath)
+	require.NoError(t, err)
+	require.Equal(t, before, after)
+}
+
+func TestAutosave(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	server, create := setup(t)
+	client := create(geom.DEFAULT_SIZE)
+
+	require.NoError(t, client.execute(`
+(group/new :root :name "autosaved")
+(cy/autosave-state "`+statePath+`" :interval 10)
+`))
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(statePath)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+
+	server.setAutosave("", 0)
+}
+
+func TestStateSameNames(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+
+	server, create := setup(t)
+	client := create(geom.DEFAULT_SIZE)
+	require.NoError(t, client.execute(`
+(def named (group/new :root :name "named"))
+(cmd/new named :command "/bin/sh" :name "bash")
+(cmd/new named :command "/bin/sh" :name "bash")
+(def unnamed (group/new :root :name "unnamed"))
+(tree/set-name (cmd/new unnamed :command "/bin/sh") "")
+(tree/set-name (cmd/new unnamed :command "/bin/sh") "")
+(cy/save-state "`+statePath+`")
+`))
+
+	getGroup := func(server *Cy, name string) *tree.Group {
+		node, ok := server.tree.Root().ChildByName(name)
+		require.True(t, ok)
+		group, ok := node.(*tree.Group)
+		require.True(t, ok)
+		return group
+	}
+
+	requirePanes := func(group *tree.Group, name string) {
+		children := group.Children()
+		require.Len(t, children, 2)
+		require.NotEqual(t, children[0].Id(), children[1].Id())
+		for _, child := range children {
+			pane, ok := child.(*tree.Pane)
+			require.True(t, ok)
+			require.Equal(t, name, pane.Name())
+			_, ok = pane.Screen().(*replay.Replayable)
+			require.True(t, ok)
+		}
+	}
+	requirePanes(getGroup(server, "named"), "bash")
+	requirePanes(getGroup(server, "unnamed"), "")
+
+	// Every saved pane is recreated, even when its siblings share its
+	// name...
+	restored, _ := setup(t)
+	require.NoError(t, restored.restoreState(restored.Ctx(), statePath, false))
+	requirePanes(getGroup(restored, "named"), "bash")
+	requirePanes(getGroup(restored, "unnamed"), "")
+
+	// ...and restoring again still reuses them
+	require.NoError(t, restored.restoreState(restored.Ctx(), statePath, false))
+	requirePanes(getGroup(restored, "named"), "bash")
+	requirePanes(getGroup(restored, "unnamed"), "")
+}
@@ -334,6 +334,18 @@ func init() {
 		Size: geom.Size{R: 15, C: 60},
 	})
 
+	stories.Register("hints", func(ctx context.Context) (
+		mux.Screen,
+		error,
+	) {
+		_, client, screen, err := createStory(ctx)
+		client.execute(`(param/set :client :hint-delay 0)`)
+		client.binds.Input([]byte{0x01})
+		return screen, err
+	}, stories.Config{
+		Size: geom.Size{R: 20, C: 80},
+	})
+
 	stories.Register("logs", func(ctx context.Context) (
 		mux.Screen,
 		error,
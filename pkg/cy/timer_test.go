@@ -0,0 +1,272 @@
+package cy
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cfoust/cy/pkg/cy/api"
+	"github.com/cfoust/cy/pkg/geom"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a Clock whose time only passes when the test says so.
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Duration
+	timers map[*fakeTimer]struct{}
+}
+
+type fakeTimer struct {
+	deadline time.Duration
+	c        chan time.Time
+}
+
+var _ api.Clock = (*fakeClock)(nil)
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{
+		timers: make(map[*fakeTimer]struct{}),
+	}
+}
+
+func (f *fakeClock) NewTimer(d time.Duration) (<-chan time.Time, func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	timer := &fakeTimer{
+		deadline: f.now + d,
+		c:        make(chan time.Time, 1),
+	}
+	f.timers[timer] = struct{}{}
+
+	return timer.c, func() {
+		f.mu.Lock()
+		delete(f.timers, timer)
+		f.mu.Unlock()
+	}
+}
+
+func (f *fakeClock) numTimers() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.timers)
+}
+
+// Advance moves time forward by `d` and fires any timers that are due.
+func (f *fakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.now += d
+	for timer := range f.timers {
+		if timer.deadline > f.now {
+			continue
+		}
+
+		timer.c <- time.Time{}
+		delete(f.timers, timer)
+	}
+}
+
+// waitTimers waits until `n` timers are waiting to fire.
+func (f *fakeClock) waitTimers(t *testing.T, n int) {
+	require.Eventually(t, func() bool {
+		return f.numTimers() == n
+	}, 5*time.Second, time.Millisecond)
+}
+
+func TestTimers(t *testing.T) {
+	clock := newFakeClock()
+	ctx := context.Background()
+	server, err := Start(ctx, Options{
+		Shell: "/bin/bash",
+		Clock: clock,
+	})
+	require.NoError(t, err)
+
+	create := func() *Client {
+		client, err := server.NewClient(ctx, ClientOptions{
+			Env: map[string]string{
+				"TERM": "xterm-256color",
+			},
+			Size: geom.DEFAULT_SIZE,
+		})
+		require.NoError(t, err)
+		return client
+	}
+	client := create()
+
+	// Receives the client passed to each callback
+	calls := make(chan interface{}, 10)
+	require.NoError(t, server.Callback("test/record", "", func(user interface{}) {
+		calls <- user
+	}))
+
+	waitCall := func() interface{} {
+		select {
+		case user := <-calls:
+			return user
+		case <-time.After(5 * time.Second):
+			t.Fatal("callback was not called")
+		}
+		return nil
+	}
+
+	requireNoCalls := func() {
+		select {
+		case <-calls:
+			t.Fatal("callback was called")
+		default:
+		}
+	}
+
+	t.Run("after", func(t *testing.T) {
+		require.NoError(t, client.execute(`
+(timer/after 10 (fn [] (test/record)))
+`))
+		clock.waitTimers(t, 1)
+
+		clock.Advance(9 * time.Millisecond)
+		requireNoCalls()
+
+		clock.Advance(time.Millisecond)
+		// The callback receives the client that created the timer
+		require.Equal(t, client, waitCall())
+
+		// It only fires once
+		clock.waitTimers(t, 0)
+		clock.Advance(time.Second)
+		requireNoCalls()
+	})
+
+	t.Run("every", func(t *testing.T) {
+		require.NoError(t, client.execute(`
+(def timer (timer/every 5 (fn [] (test/record))))
+`))
+
+		for i := 0; i < 3; i++ {
+			clock.waitTimers(t, 1)
+			clock.Advance(5 * time.Millisecond)
+			waitCall()
+		}
+
+		clock.waitTimers(t, 1)
+		require.NoError(t, client.execute(`(timer/cancel timer)`))
+		clock.waitTimers(t, 0)
+		clock.Advance(time.Second)
+		requireNoCalls()
+	})
+
+	t.Run("cancel before firing", func(t *testing.T) {
+		require.NoError(t, client.execute(`
+(timer/cancel (timer/after 500 (fn [] (test/record))))
+`))
+		clock.waitTimers(t, 0)
+		clock.Advance(time.Second)
+		requireNoCalls()
+	})
+
+	t.Run("disconnected client", func(t *testing.T) {
+		other := create()
+		require.NoError(t, other.execute(`
+(timer/after 10 (fn [] (test/record)))
+`))
+		clock.waitTimers(t, 1)
+
+		// Callbacks do not receive clients that have gone away
+		other.Cancel()
+		clock.Advance(10 * time.Millisecond)
+		require.Nil(t, waitCall())
+	})
+
+	t.Run("config reload", func(t *testing.T) {
+		config := filepath.Join(t.TempDir(), "cyrc.janet")
+		writeConfig(t, config, `
+(timer/every 5 (fn [] (test/record)))
+`)
+		require.NoError(t, server.executeConfig(config))
+		clock.waitTimers(t, 1)
+
+		// Reloading replaces the old timer rather than adding another
+		require.NoError(t, server.executeConfig(config))
+		clock.waitTimers(t, 1)
+		clock.Advance(5 * time.Millisecond)
+		waitCall()
+		clock.waitTimers(t, 1)
+		requireNoCalls()
+
+		// A configuration that fails cancels the timers it created and
+		// leaves the previous configuration's running
+		writeConfig(t, config, `
+(timer/every 5 (fn [] (test/record)))
+(error "oops")
+`)
+		require.Error(t, server.executeConfig(config))
+		clock.waitTimers(t, 1)
+		clock.Advance(5 * time.Millisecond)
+		waitCall()
+		clock.waitTimers(t, 1)
+		requireNoCalls()
+
+		writeConfig(t, config, `(def a 1)`)
+		require.NoError(t, server.executeConfig(config))
+		clock.waitTimers(t, 0)
+	})
+
+	t.Run("invalid interval", func(t *testing.T) {
+		require.Error(t, client.execute(`(timer/every 0 (fn []))`))
+		require.Error(t, client.execute(`(timer/after -1 (fn []))`))
+	})
+}
+
+func TestExecAsync(t *testing.T) {
+	server, create := setup(t)
+	client := create(geom.DEFAULT_SIZE)
+
+	type result struct {
+		Stdout   string
+		Stderr   string
+		ExitCode int
+	}
+	results := make(chan result, 1)
+	require.NoError(t, server.Callback("test/result", "", func(
+		stdout, stderr string,
+		exitCode int,
+	) {
+		results <- result{stdout, stderr, exitCode}
+	}))
+
+	require.NoError(t, client.execute(`
+(exec/async
+  "echo hello; echo oops >&2; exit 3"
+  (fn [{:stdout stdout :stderr stderr :exit-code code}]
+    (test/result stdout stderr code)))
+`))
+
+	select {
+	case r := <-results:
+		require.Equal(t, result{"hello\n", "oops\n", 3}, r)
+	case <-time.After(5 * time.Second):
+		t.Fatal("callback was not called")
+	}
+
+	dir := t.TempDir()
+	require.NoError(t, client.execute(`
+(exec/async
+  "pwd"
+  (fn [{:stdout stdout :stderr stderr :exit-code code}]
+    (test/result stdout stderr code))
+  :path "`+dir+`")
+`))
+
+	select {
+	case r := <-results:
+		require.Equal(t, result{dir + "\n", "", 0}, r)
+	case <-time.After(5 * time.Second):
+		t.Fatal("callback was not called")
+	}
+}
@@ -0,0 +1,407 @@
+package cy
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cfoust/cy/pkg/bind"
+	"github.com/cfoust/cy/pkg/bind/trie"
+	"github.com/cfoust/cy/pkg/janet"
+	"github.com/cfoust/cy/pkg/mux/screen/tree"
+	"github.com/cfoust/cy/pkg/params"
+)
+
+// The number of lines shown before the line on which an error occurred.
+const CONFIG_EXCERPT_CONTEXT = 2
+
+// A ConfigError is an error that occurred while executing a configuration
+// file.
+type ConfigError struct {
+	Path string
+	// The location of the error in the file. Both are zero if the error
+	// did not refer to the file.
+	Line, Column int
+	// The lines of the file surrounding the error.
+	Excerpt string
+	Err     error
+}
+
+var _ error = (*ConfigError)(nil)
+
+func (e *ConfigError) Error() string {
+	if len(e.Excerpt) == 0 {
+		return e.Err.Error()
+	}
+
+	return fmt.Sprintf("%s\n\n%s", e.Err.Error(), e.Excerpt)
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// getExcerpt formats the lines in `source` that precede and include `line`
+// with a marker pointing at `column`.
+func getExcerpt(source []byte, line, column int) string {
+	lines := strings.Split(string(source), "\n")
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+
+	first := max(line-CONFIG_EXCERPT_CONTEXT, 1)
+	width := len(strconv.Itoa(line))
+
+	var excerpt strings.Builder
+	for i := first; i <= line; i++ {
+		marker := " "
+		if i == line {
+			marker = ">"
+		}
+
+		fmt.Fprintf(
+			&excerpt,
+			"%s %*d | %s\n",
+			marker,
+			width,
+			i,
+			strings.TrimRight(lines[i-1], "\r"),
+		)
+	}
+
+	if column >= 1 {
+		fmt.Fprintf(
+			&excerpt,
+			"  %s | %s^",
+			strings.Repeat(" ", width),
+			strings.Repeat(" ", column-1),
+		)
+	}
+
+	return strings.TrimRight(excerpt.String(), "\n")
+}
+
+// newConfigError finds the location in the file at `path` to which `err`
+// refers, if any.
+func newConfigError(path string, err error) *ConfigError {
+	configErr := &ConfigError{
+		Path: path,
+		Err:  err,
+	}
+
+	// Janet reports locations as path:line:column in both compile errors
+	// and stack traces
+	pattern := regexp.MustCompile(
+		regexp.QuoteMeta(path) + `:(\d+):(\d+)`,
+	)
+	match := pattern.FindStringSubmatch(err.Error())
+	if match == nil {
+		return configErr
+	}
+
+	configErr.Line, _ = strconv.Atoi(match[1])
+	configErr.Column, _ = strconv.Atoi(match[2])
+
+	source, readErr := os.ReadFile(path)
+	if readErr != nil {
+		return configErr
+	}
+
+	// Janet's columns can point past the end of the line
+	lines := bytes.Split(source, []byte("\n"))
+	if configErr.Line >= 1 && configErr.Line <= len(lines) {
+		configErr.Column = min(
+			configErr.Column,
+			len(lines[configErr.Line-1])+1,
+		)
+	}
+
+	configErr.Excerpt = getExcerpt(
+		source,
+		configErr.Line,
+		configErr.Column,
+	)
+	return configErr
+}
+
+// allNodes gets every node in the tree.
+func (c *Cy) allNodes() (nodes []tree.Node) {
+	var visit func(node tree.Node)
+	visit = func(node tree.Node) {
+		nodes = append(nodes, node)
+
+		group, ok := node.(*tree.Group)
+		if !ok {
+			return
+		}
+
+		for _, child := range group.Children() {
+			visit(child)
+		}
+	}
+	visit(c.tree.Root())
+	return
+}
+
+// bindScopes gets all of the bind scopes that the configuration can modify.
+func (c *Cy) bindScopes() []*bind.BindScope {
+	scopes := []*bind.BindScope{c.timeBinds, c.copyBinds}
+	for _, node := range c.allNodes() {
+		scopes = append(scopes, node.Binds())
+	}
+	return scopes
+}
+
+// allParams gets all of the parameters that the configuration can modify.
+func (c *Cy) allParams() []*params.Parameters {
+	result := []*params.Parameters{c.defaults}
+	for _, node := range c.allNodes() {
+		result = append(result, node.Params())
+	}
+	return result
+}
+
+func (c *Cy) runBoot(code string) error {
+	return c.ExecuteCall(c.Ctx(), nil, janet.Call{
+		Code: []byte(code),
+	})
+}
+
+// A bindSnapshot is a copy of the contents of a set of bind scopes.
+type bindSnapshot map[*bind.BindScope]*bind.BindScope
+
+func snapshotBinds(scopes []*bind.BindScope) bindSnapshot {
+	snapshot := make(bindSnapshot, len(scopes))
+	for _, scope := range scopes {
+		snapshot[scope] = scope.Clone()
+	}
+	return snapshot
+}
+
+func (b bindSnapshot) restore() {
+	for scope, saved := range b {
+		scope.Restore(saved)
+	}
+}
+
+// A bindChange is a binding that the configuration added, replaced, or
+// removed. `before` and `after` are nil if the binding did not exist.
+type bindChange struct {
+	scope         *bind.BindScope
+	path          []string
+	before, after *bind.Action
+}
+
+// getLeaves gets the bindings in `scope` keyed by their path.
+func getLeaves(scope *bind.BindScope) map[string]trie.Leaf[bind.Action] {
+	leaves := make(map[string]trie.Leaf[bind.Action])
+	for _, leaf := range scope.Leaves() {
+		leaves[strings.Join(leaf.Path, " ")] = leaf
+	}
+	return leaves
+}
+
+func sameAction(a, b *bind.Action) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Tag == b.Tag && a.Callback == b.Callback
+}
+
+// diff gets the bindings that have changed since the snapshot was taken.
+func (b bindSnapshot) diff() (changes []bindChange) {
+	for scope, saved := range b {
+		before := getLeaves(saved)
+		after := getLeaves(scope)
+
+		for key, leaf := range after {
+			action := leaf.Value
+			change := bindChange{
+				scope: scope,
+				path:  leaf.Path,
+				after: &action,
+			}
+			if previous, ok := before[key]; ok {
+				change.before = &previous.Value
+			}
+
+			if sameAction(change.before, change.after) {
+				continue
+			}
+			changes = append(changes, change)
+		}
+
+		for key, leaf := range before {
+			if _, ok := after[key]; ok {
+				continue
+			}
+
+			action := leaf.Value
+			changes = append(changes, bindChange{
+				scope:  scope,
+				path:   leaf.Path,
+				before: &action,
+			})
+		}
+	}
+
+	return
+}
+
+// A configChanges is the set of changes made by the last configuration that
+// loaded successfully. They are undone before the configuration is reloaded
+// so that reloading does not accumulate bindings and actions from previous
+// versions of the configuration.
+type configChanges struct {
+	binds []bindChange
+	// The timers the configuration created that were still active
+	// when it finished
+	timers []int
+}
+
+// undo reverts the bindings changed by the configuration. Bindings that have
+// changed again since then, such as by a plugin or the REPL, are left alone.
+func (c *configChanges) undo() error {
+	for _, change := range c.binds {
+		var current *bind.Action
+		leaves := getLeaves(change.scope)
+		if leaf, ok := leaves[strings.Join(change.path, " ")]; ok {
+			current = &leaf.Value
+		}
+
+		if !sameAction(current, change.after) {
+			continue
+		}
+
+		sequence, err := parseSequence(change.path)
+		if err != nil {
+			return err
+		}
+
+		if change.before == nil {
+			change.scope.Clear(sequence)
+			continue
+		}
+
+		change.scope.Set(sequence, *change.before)
+	}
+
+	return nil
+}
+
+// A configTransaction records the state of the server before the
+// configuration is executed so that it can be rolled back if the
+// configuration fails.
+type configTransaction struct {
+	binds  bindSnapshot
+	params []*params.Snapshot
+	// The ID of the last timer created before the configuration ran
+	lastTimer int
+}
+
+func (c *Cy) beginConfig() (*configTransaction, error) {
+	err := c.runBoot(`(actions/save :transaction)`)
+	if err != nil {
+		return nil, err
+	}
+
+	transaction := &configTransaction{
+		binds:     snapshotBinds(c.bindScopes()),
+		lastTimer: c.timers.LastID(),
+	}
+	for _, nodeParams := range c.allParams() {
+		transaction.params = append(
+			transaction.params,
+			nodeParams.Snapshot(),
+		)
+	}
+
+	return transaction, nil
+}
+
+func (c *Cy) rollbackConfig(transaction *configTransaction) error {
+	c.timers.CancelSince(transaction.lastTimer)
+	transaction.binds.restore()
+	for _, snapshot := range transaction.params {
+		snapshot.Restore()
+	}
+	return c.runBoot(`(actions/restore :transaction)`)
+}
+
+func (c *Cy) commitConfig(transaction *configTransaction) {
+	for _, snapshot := range transaction.params {
+		snapshot.Release()
+	}
+}
+
+// executeConfig executes the configuration file at `path`. Any changes to
+// key bindings and actions made by the last configuration that loaded
+// successfully are undone first, and its timers are cancelled if this one
+// succeeds. If it fails, any changes it made to key bindings, parameters,
+// and actions are undone, any timers it created are cancelled, and a
+// *ConfigError is returned.
+func (c *Cy) executeConfig(path string) error {
+	transaction, err := c.beginConfig()
+	if err != nil {
+		return err
+	}
+
+	c.Lock()
+	previous := c.configChanges
+	c.Unlock()
+
+	if previous != nil {
+		err = previous.undo()
+		if err == nil {
+			err = c.runBoot(`(actions/remove-saved :config)`)
+		}
+	}
+
+	var before bindSnapshot
+	if err == nil {
+		before = snapshotBinds(c.bindScopes())
+		err = c.runBoot(`(actions/save :before-config)`)
+	}
+
+	if err == nil {
+		err = c.ExecuteFile(c.Ctx(), path)
+		if err != nil {
+			err = newConfigError(path, err)
+		}
+	}
+
+	if err == nil {
+		err = c.runBoot(`(actions/save-added :before-config :config)`)
+	}
+
+	if err == nil {
+		// The previous configuration's timers are only cancelled
+		// once the new one has loaded, since they should keep running
+		// if it fails
+		if previous != nil {
+			for _, id := range previous.timers {
+				c.timers.Cancel(id)
+			}
+		}
+
+		c.Lock()
+		c.configChanges = &configChanges{
+			binds:  before.diff(),
+			timers: c.timers.Active(transaction.lastTimer),
+		}
+		c.Unlock()
+
+		c.commitConfig(transaction)
+		return nil
+	}
+
+	rollbackErr := c.rollbackConfig(transaction)
+	if rollbackErr != nil {
+		c.log.Error().Err(rollbackErr).Msg("failed to roll back config")
+	}
+
+	return err
+}
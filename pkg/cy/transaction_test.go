@@ -0,0 +1,194 @@
+package cy
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cfoust/cy/pkg/janet"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, path, code string) {
+	require.NoError(t, os.WriteFile(path, []byte(code), 0644))
+}
+
+func TestConfigRollback(t *testing.T) {
+	server, _ := setup(t)
+	path := filepath.Join(t.TempDir(), "cyrc.janet")
+	root := server.tree.Root()
+
+	require.NoError(t, server.Execute(server.Ctx(), `
+(param/set :root :existing 1)
+(def num-actions (length actions))
+`))
+
+	writeConfig(t, path, `
+(key/bind :root ["ctrl+b" "x"] (fn []))
+(key/bind :time ["ctrl+b" "x"] (fn []))
+(param/set :root :existing 2)
+(param/set :root :custom "value")
+(key/action action/broken "A broken action." nil)
+(error "oops")
+`)
+
+	err := server.executeConfig(path)
+	require.Error(t, err)
+	configErr, ok := err.(*ConfigError)
+	require.True(t, ok)
+	require.Equal(t, 7, configErr.Line)
+	require.Contains(t, configErr.Excerpt, `> 7 | (error "oops")`)
+
+	// None of the config's changes remain
+	_, _, ok = root.Binds().Get([]string{"ctrl+b", "x"})
+	require.False(t, ok)
+	_, _, ok = server.timeBinds.Get([]string{"ctrl+b", "x"})
+	require.False(t, ok)
+	value, ok := root.Params().Get("existing")
+	require.True(t, ok)
+	janetValue, ok := value.(*janet.Value)
+	require.True(t, ok)
+	var existing int
+	require.NoError(t, janetValue.Unmarshal(&existing))
+	require.Equal(t, 1, existing)
+	_, ok = root.Params().Get("custom")
+	require.False(t, ok)
+	require.NoError(t, server.Execute(
+		server.Ctx(),
+		`(assert (= (length actions) num-actions))`,
+	))
+
+	// Reloading does not accumulate bindings or actions
+	writeConfig(t, path, `
+(key/bind :root ["ctrl+b" "x"] (fn []))
+(key/action action/first "The first action." nil)
+`)
+	require.NoError(t, server.executeConfig(path))
+	_, _, ok = root.Binds().Get([]string{"ctrl+b", "x"})
+	require.True(t, ok)
+
+	writeConfig(t, path, `
+(key/bind :root ["ctrl+b" "y"] (fn []))
+(key/action action/second "The second action." nil)
+`)
+	require.NoError(t, server.executeConfig(path))
+	_, _, ok = root.Binds().Get([]string{"ctrl+b", "x"})
+	require.False(t, ok)
+	_, _, ok = root.Binds().Get([]string{"ctrl+b", "y"})
+	require.True(t, ok)
+	require.NoError(t, server.Execute(
+		server.Ctx(),
+		`(assert (= (length actions) (+ num-actions 1)))`,
+	))
+
+	// A failed reload keeps the previous config
+	writeConfig(t, path, `
+(key/bind :root ["ctrl+b" "z"] (fn []))
+(error "oops")
+`)
+	require.Error(t, server.executeConfig(path))
+	_, _, ok = root.Binds().Get([]string{"ctrl+b", "y"})
+	require.True(t, ok)
+	_, _, ok = root.Binds().Get([]string{"ctrl+b", "z"})
+	require.False(t, ok)
+	require.NoError(t, server.Execute(
+		server.Ctx(),
+		`(assert (= (length actions) (+ num-actions 1)))`,
+	))
+}
+
+func TestCheckConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cyrc.janet")
+
+	writeConfig(t, path, `(key/bind :root ["ctrl+b" "x"] (fn []))`)
+	require.NoError(t, CheckConfig(context.Background(), Options{
+		Config: path,
+		Shell:  "/bin/bash",
+	}))
+
+	writeConfig(t, path, "(def a 1)\n(undefined-symbol a)\n")
+	err := CheckConfig(context.Background(), Options{
+		Config: path,
+		Shell:  "/bin/bash",
+	})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unknown symbol undefined-symbol")
+	require.Contains(t, err.Error(), "> 2 | (undefined-symbol a)\n    | ^")
+
+	// Checking the config has no side effects outside of the server
+	dir := t.TempDir()
+	spawned := filepath.Join(dir, "spawned")
+	executed := filepath.Join(dir, "executed")
+	saved := filepath.Join(dir, "saved.json")
+	writeConfig(t, path, fmt.Sprintf(`
+(def pane (cmd/new :root))
+(tree/set-name pane "shell")
+(cmd/new :root :command "touch" :args [%q])
+(exec/async "touch %s" (fn [&]))
+(timer/every 1 (fn [] (exec/async "touch %s" (fn [&]))))
+(cy/save-state %q)
+`, spawned, executed, executed, saved))
+	require.NoError(t, CheckConfig(context.Background(), Options{
+		Config: path,
+		Shell:  "/bin/bash",
+	}))
+	require.Never(t, func() bool {
+		entries, err := os.ReadDir(dir)
+		return err != nil || len(entries) > 0
+	}, 500*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestConfigReloadAfterPlugin(t *testing.T) {
+	dir := t.TempDir()
+	writePlugin(t, dir, "keys", `{}`, `
+(key/bind :root ["ctrl+b" "p"] (fn []))
+(key/action action/plugin "An action from a plugin." nil)
+`)
+
+	config := filepath.Join(t.TempDir(), "cyrc.janet")
+	writeConfig(t, config, `
+(key/bind :root ["ctrl+b" "c"] (fn []))
+(key/action action/config "An action from the config." nil)
+`)
+
+	server, err := Start(context.Background(), Options{
+		Shell:     "/bin/bash",
+		PluginDir: dir,
+		Config:    config,
+	})
+	require.NoError(t, err)
+	root := server.tree.Root()
+
+	require.NoError(t, server.Execute(server.Ctx(), `
+(plugin/reload "keys")
+(key/bind :root ["ctrl+b" "r"] (fn []))
+(key/action action/repl "An action from the REPL." nil)
+`))
+
+	// Reloading the config only undoes what it added before
+	writeConfig(t, config, `
+(key/bind :root ["ctrl+b" "d"] (fn []))
+`)
+	require.NoError(t, server.executeConfig(config))
+
+	for key, exists := range map[string]bool{
+		"p": true,
+		"r": true,
+		"c": false,
+		"d": true,
+	} {
+		_, _, ok := root.Binds().Get([]string{"ctrl+b", key})
+		require.Equal(t, exists, ok, key)
+	}
+
+	require.NoError(t, server.Execute(server.Ctx(), `
+(def docs (map first actions))
+(assert (has-value? docs "An action from a plugin."))
+(assert (has-value? docs "An action from the REPL."))
+(assert (not (has-value? docs "An action from the config.")))
+`))
+}
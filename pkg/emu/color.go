@@ -20,19 +20,115 @@ const (
 	White
 )
 
+// The upper eight bits of a Color indicate what kind of color it is.
+const (
+	colorPalette Color = 0
+	colorRGB     Color = 1 << 24
+	colorDefault Color = 2 << 24
+	colorKind    Color = 0xff << 24
+)
+
 // Default colors are potentially distinct to allow for special behavior.
 // For example, a transparent background. Otherwise, the simple case is to
 // map default colors to another color.
 const (
-	DefaultFG Color = 1<<24 + iota
+	DefaultFG Color = colorDefault + iota
 	DefaultBG
 	DefaultCursor
 )
 
-// Color maps to the ANSI colors [0, 16) and the xterm colors [16, 256).
+// Color is either an index into the 256-color palette, a 24-bit RGB color,
+// or one of the default colors. Palette colors are represented by their
+// index, so the ANSI colors [0, 16) and the xterm colors [16, 256) can be
+// used directly. RGB colors must be created with NewRGB.
 type Color uint32
 
+// NewRGB returns the true color Color with the given components.
+func NewRGB(r, g, b uint8) Color {
+	return colorRGB | Color(r)<<16 | Color(g)<<8 | Color(b)
+}
+
 // ANSI returns true if Color is within [0, 16).
 func (c Color) ANSI() bool {
 	return (c < 16)
 }
+
+// IsPalette returns true if Color refers to one of the 256 colors in the
+// palette.
+func (c Color) IsPalette() bool {
+	return c < 256
+}
+
+// IsRGB returns true if Color is a 24-bit RGB color.
+func (c Color) IsRGB() bool {
+	return c&colorKind == colorRGB
+}
+
+// IsDefault returns true if Color is one of the default colors.
+func (c Color) IsDefault() bool {
+	return c&colorKind == colorDefault
+}
+
+// The RGB values of the ANSI colors used by xterm.
+var ansiColors = [16]Color{
+	NewRGB(0x00, 0x00, 0x00),
+	NewRGB(0xcd, 0x00, 0x00),
+	NewRGB(0x00, 0xcd, 0x00),
+	NewRGB(0xcd, 0xcd, 0x00),
+	NewRGB(0x00, 0x00, 0xee),
+	NewRGB(0xcd, 0x00, 0xcd),
+	NewRGB(0x00, 0xcd, 0xcd),
+	NewRGB(0xe5, 0xe5, 0xe5),
+	NewRGB(0x7f, 0x7f, 0x7f),
+	NewRGB(0xff, 0x00, 0x00),
+	NewRGB(0x00, 0xff, 0x00),
+	NewRGB(0xff, 0xff, 0x00),
+	NewRGB(0x5c, 0x5c, 0xff),
+	NewRGB(0xff, 0x00, 0xff),
+	NewRGB(0x00, 0xff, 0xff),
+	NewRGB(0xff, 0xff, 0xff),
+}
+
+// RGB returns the components of the Color. Palette colors are converted
+// using the standard xterm palette and default colors are treated as
+// light grey text on a black background.
+func (c Color) RGB() (r, g, b uint8) {
+	switch {
+	case c.IsRGB():
+	case c.ANSI():
+		c = ansiColors[c]
+	case c.IsPalette() && c < 232:
+		// The 6x6x6 color cube
+		levels := [6]uint8{0x00, 0x5f, 0x87, 0xaf, 0xd7, 0xff}
+		index := c - 16
+		c = NewRGB(
+			levels[index/36],
+			levels[index/6%6],
+			levels[index%6],
+		)
+	case c.IsPalette():
+		// The grayscale ramp
+		level := uint8(8 + (c-232)*10)
+		c = NewRGB(level, level, level)
+	case c == DefaultBG:
+		c = ansiColors[Black]
+	default:
+		c = ansiColors[LightGrey]
+	}
+
+	return uint8(c >> 16), uint8(c >> 8), uint8(c)
+}
+
+// Theme changes the RGB values of the ANSI colors and the default colors,
+// mapping each to an RGB Color. Colors not in the Theme are displayed as
+// they normally would be.
+type Theme map[Color]Color
+
+// Resolve returns the RGB Color that `c` is displayed as.
+func (t Theme) Resolve(c Color) Color {
+	if themed, ok := t[c]; ok {
+		return themed
+	}
+
+	return NewRGB(c.RGB())
+}
@@ -0,0 +1,560 @@
+// Package conformance checks the behavior of emu.Terminal against fixtures
+// that describe the state a terminal should be in after receiving some
+// input.
+//
+// Fixtures come in two forms. Files ending in `.vt` contain both the input
+// and the expected result, separated into sections:
+//
+//	# Lines beginning with # are comments.
+//	-- size --
+//	3 10
+//	-- input --
+//	hello\r\n
+//	\e[1mworld
+//	-- screen --
+//	|hello     |
+//	|world     |
+//	|          |
+//	-- cursor --
+//	position 1 5
+//	style block
+//	visible true
+//	-- modes --
+//	+wrap -alt-screen
+//
+// Input is unescaped like a Go string literal (with the addition of `\e`
+// for ESC) and the lines are joined without newlines, so line breaks must
+// be written explicitly. Every section other than `input` is optional and
+// only the properties that appear in a fixture are checked.
+//
+// Files ending in `.borg` are recorded sessions. Their expected result is
+// stored in a file with the same name ending in `.golden`, which uses the
+// same format as a `.vt` file but has no `size` or `input` sections.
+package conformance
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cfoust/cy/pkg/emu"
+	"github.com/cfoust/cy/pkg/geom"
+	P "github.com/cfoust/cy/pkg/io/protocol"
+	"github.com/cfoust/cy/pkg/sessions"
+)
+
+// Modes maps the names used in fixtures to the modes they refer to.
+var Modes = map[string]emu.ModeFlag{
+	"wrap":            emu.ModeWrap,
+	"insert":          emu.ModeInsert,
+	"app-keypad":      emu.ModeAppKeypad,
+	"alt-screen":      emu.ModeAltScreen,
+	"crlf":            emu.ModeCRLF,
+	"mouse-button":    emu.ModeMouseButton,
+	"mouse-motion":    emu.ModeMouseMotion,
+	"reverse":         emu.ModeReverse,
+	"keyboard-lock":   emu.ModeKeyboardLock,
+	"hide":            emu.ModeHide,
+	"echo":            emu.ModeEcho,
+	"app-cursor":      emu.ModeAppCursor,
+	"mouse-sgr":       emu.ModeMouseSgr,
+	"8bit":            emu.Mode8bit,
+	"blink":           emu.ModeBlink,
+	"focus":           emu.ModeFocus,
+	"mouse-x10":       emu.ModeMouseX10,
+	"mouse-many":      emu.ModeMouseMany,
+	"bracketed-paste": emu.ModeBracketedPaste,
+	"sync-update":     emu.ModeSyncUpdate,
+}
+
+// CursorStyles maps the names used in fixtures to the cursor styles they
+// refer to.
+var CursorStyles = map[string]emu.CursorStyle{
+	"block":           emu.CursorStyleBlock,
+	"steady-block":    emu.CursorStyleSteadyBlock,
+	"underline":       emu.CursorStyleUnderline,
+	"blink-underline": emu.CursorStyleBlinkUnderline,
+	"bar":             emu.CursorStyleBar,
+	"blink-bar":       emu.CursorStyleBlinkBar,
+}
+
+// Expectation describes the state of a terminal. Fields that are nil are
+// not checked.
+type Expectation struct {
+	// Screen contains the text of each row of the screen.
+	Screen        []string
+	Cursor        *geom.Vec2
+	CursorStyle   *emu.CursorStyle
+	CursorVisible *bool
+	// Modes maps modes to whether they should be set.
+	Modes map[emu.ModeFlag]bool
+}
+
+// Fixture is input to a terminal and the state the terminal should be in
+// after processing it.
+type Fixture struct {
+	Name string
+	// The initial size of the terminal.
+	Size   geom.Vec2
+	Events []sessions.Event
+	Expect Expectation
+}
+
+// Run creates a new terminal and feeds it the fixture's events.
+func (f *Fixture) Run() emu.Terminal {
+	term := emu.New(emu.WithSize(f.Size))
+	for _, event := range f.Events {
+		switch e := event.Message.(type) {
+		case P.OutputMessage:
+			term.Write(e.Data)
+		case P.SizeMessage:
+			term.Resize(e.Vec())
+		}
+	}
+
+	return term
+}
+
+// Capture returns an Expectation that describes every property of `term`
+// that fixtures can check.
+func Capture(term emu.Terminal) Expectation {
+	var expect Expectation
+
+	size := term.Size()
+	for row := 0; row < size.R; row++ {
+		var line []rune
+		for col := 0; col < size.C; col++ {
+			line = append(line, term.Cell(col, row).Char)
+		}
+		expect.Screen = append(expect.Screen, string(line))
+	}
+
+	cursor := term.Cursor()
+	visible := term.CursorVisible()
+	expect.Cursor = &cursor.Vec2
+	expect.CursorStyle = &cursor.Style
+	expect.CursorVisible = &visible
+
+	mode := term.Mode()
+	expect.Modes = make(map[emu.ModeFlag]bool)
+	for _, flag := range Modes {
+		expect.Modes[flag] = mode&flag != 0
+	}
+
+	return expect
+}
+
+func modeName(flag emu.ModeFlag) string {
+	for name, other := range Modes {
+		if other == flag {
+			return name
+		}
+	}
+	return fmt.Sprintf("%d", flag)
+}
+
+func styleName(style emu.CursorStyle) string {
+	for name, other := range CursorStyles {
+		if other == style {
+			return name
+		}
+	}
+	return fmt.Sprintf("%d", style)
+}
+
+// Check compares the state of `term` to the Expectation and returns a
+// description of every difference.
+func (e Expectation) Check(term emu.Terminal) (problems []string) {
+	actual := Capture(term)
+
+	if e.Screen != nil {
+		if len(e.Screen) != len(actual.Screen) {
+			problems = append(problems, fmt.Sprintf(
+				"screen has %d rows, expected %d",
+				len(actual.Screen),
+				len(e.Screen),
+			))
+		}
+
+		for row := 0; row < len(e.Screen) && row < len(actual.Screen); row++ {
+			if e.Screen[row] == actual.Screen[row] {
+				continue
+			}
+
+			problems = append(problems, fmt.Sprintf(
+				"row %d is |%s|, expected |%s|",
+				row,
+				actual.Screen[row],
+				e.Screen[row],
+			))
+		}
+	}
+
+	if e.Cursor != nil && *e.Cursor != *actual.Cursor {
+		problems = append(problems, fmt.Sprintf(
+			"cursor is at %d %d, expected %d %d",
+			actual.Cursor.R,
+			actual.Cursor.C,
+			e.Cursor.R,
+			e.Cursor.C,
+		))
+	}
+
+	if e.CursorStyle != nil && *e.CursorStyle != *actual.CursorStyle {
+		problems = append(problems, fmt.Sprintf(
+			"cursor style is %s, expected %s",
+			styleName(*actual.CursorStyle),
+			styleName(*e.CursorStyle),
+		))
+	}
+
+	if e.CursorVisible != nil && *e.CursorVisible != *actual.CursorVisible {
+		problems = append(problems, fmt.Sprintf(
+			"cursor visible is %t, expected %t",
+			*actual.CursorVisible,
+			*e.CursorVisible,
+		))
+	}
+
+	for flag, set := range e.Modes {
+		if actual.Modes[flag] == set {
+			continue
+		}
+
+		problems = append(problems, fmt.Sprintf(
+			"mode %s is %t, expected %t",
+			modeName(flag),
+			actual.Modes[flag],
+			set,
+		))
+	}
+
+	sort.Strings(problems)
+	return
+}
+
+// Format writes the Expectation in the fixture format.
+func (e Expectation) Format(w io.Writer) {
+	if e.Screen != nil {
+		fmt.Fprintln(w, "-- screen --")
+		for _, line := range e.Screen {
+			fmt.Fprintf(w, "|%s|\n", line)
+		}
+	}
+
+	if e.Cursor != nil || e.CursorStyle != nil || e.CursorVisible != nil {
+		fmt.Fprintln(w, "-- cursor --")
+		if e.Cursor != nil {
+			fmt.Fprintf(w, "position %d %d\n", e.Cursor.R, e.Cursor.C)
+		}
+		if e.CursorStyle != nil {
+			fmt.Fprintf(w, "style %s\n", styleName(*e.CursorStyle))
+		}
+		if e.CursorVisible != nil {
+			fmt.Fprintf(w, "visible %t\n", *e.CursorVisible)
+		}
+	}
+
+	if len(e.Modes) > 0 {
+		var modes []string
+		for flag, set := range e.Modes {
+			prefix := "-"
+			if set {
+				prefix = "+"
+			}
+			modes = append(modes, prefix+modeName(flag))
+		}
+		sort.Slice(modes, func(i, j int) bool {
+			return modes[i][1:] < modes[j][1:]
+		})
+
+		fmt.Fprintln(w, "-- modes --")
+		fmt.Fprintln(w, strings.Join(modes, " "))
+	}
+}
+
+// parseSections splits a fixture into its sections, ignoring comments.
+func parseSections(data []byte) (sections map[string][]string, err error) {
+	sections = make(map[string][]string)
+
+	var current string
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+
+		if strings.HasPrefix(line, "-- ") && strings.HasSuffix(line, " --") {
+			current = strings.TrimSpace(line[3 : len(line)-3])
+			if _, ok := sections[current]; ok {
+				return nil, fmt.Errorf(
+					"line %d: duplicate section %s",
+					i+1,
+					current,
+				)
+			}
+			sections[current] = []string{}
+			continue
+		}
+
+		if strings.HasPrefix(line, "#") || (current != "screen" && len(strings.TrimSpace(line)) == 0) {
+			continue
+		}
+
+		if current == "" {
+			return nil, fmt.Errorf("line %d: text outside of a section", i+1)
+		}
+
+		sections[current] = append(sections[current], line)
+	}
+
+	// Trailing blank lines are not part of the screen
+	screen := sections["screen"]
+	for len(screen) > 0 && len(screen[len(screen)-1]) == 0 {
+		screen = screen[:len(screen)-1]
+	}
+	if screen != nil {
+		sections["screen"] = screen
+	}
+
+	return sections, nil
+}
+
+// Unescape interprets the escape sequences in a line of fixture input.
+func Unescape(line string) ([]byte, error) {
+	var quoted strings.Builder
+	quoted.WriteByte('"')
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '"':
+			quoted.WriteString(`\"`)
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == 'e':
+			quoted.WriteString(`\x1b`)
+			i++
+		case line[i] == '\\' && i+1 < len(line):
+			quoted.WriteByte(line[i])
+			quoted.WriteByte(line[i+1])
+			i++
+		default:
+			quoted.WriteByte(line[i])
+		}
+	}
+	quoted.WriteByte('"')
+
+	value, err := strconv.Unquote(quoted.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid input %s: %w", line, err)
+	}
+
+	return []byte(value), nil
+}
+
+func parseInts(line string, n int) ([]int, error) {
+	fields := strings.Fields(line)
+	if len(fields) != n {
+		return nil, fmt.Errorf("expected %d numbers, got %s", n, line)
+	}
+
+	values := make([]int, n)
+	for i, field := range fields {
+		value, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+
+	return values, nil
+}
+
+func parseExpectation(sections map[string][]string) (expect Expectation, err error) {
+	if screen, ok := sections["screen"]; ok {
+		expect.Screen = []string{}
+		for _, line := range screen {
+			if len(line) < 2 || line[0] != '|' || line[len(line)-1] != '|' {
+				return expect, fmt.Errorf(
+					"screen lines must be surrounded by |: %s",
+					line,
+				)
+			}
+			expect.Screen = append(expect.Screen, line[1:len(line)-1])
+		}
+	}
+
+	for _, line := range sections["cursor"] {
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "position":
+			values, err := parseInts(value, 2)
+			if err != nil {
+				return expect, err
+			}
+			expect.Cursor = &geom.Vec2{R: values[0], C: values[1]}
+		case "style":
+			style, ok := CursorStyles[value]
+			if !ok {
+				return expect, fmt.Errorf("unknown cursor style %s", value)
+			}
+			expect.CursorStyle = &style
+		case "visible":
+			visible, err := strconv.ParseBool(value)
+			if err != nil {
+				return expect, err
+			}
+			expect.CursorVisible = &visible
+		default:
+			return expect, fmt.Errorf("unknown cursor property %s", key)
+		}
+	}
+
+	for _, line := range sections["modes"] {
+		for _, field := range strings.Fields(line) {
+			if len(field) < 2 || (field[0] != '+' && field[0] != '-') {
+				return expect, fmt.Errorf(
+					"modes must begin with + or -: %s",
+					field,
+				)
+			}
+
+			flag, ok := Modes[field[1:]]
+			if !ok {
+				return expect, fmt.Errorf("unknown mode %s", field[1:])
+			}
+
+			if expect.Modes == nil {
+				expect.Modes = make(map[emu.ModeFlag]bool)
+			}
+			expect.Modes[flag] = field[0] == '+'
+		}
+	}
+
+	return expect, nil
+}
+
+// Parse reads a fixture in the `.vt` format.
+func Parse(name string, data []byte) (*Fixture, error) {
+	sections, err := parseSections(data)
+	if err != nil {
+		return nil, err
+	}
+
+	fixture := &Fixture{
+		Name: name,
+		Size: geom.DEFAULT_SIZE,
+	}
+
+	if size, ok := sections["size"]; ok {
+		if len(size) != 1 {
+			return nil, fmt.Errorf("size must be a single line")
+		}
+
+		values, err := parseInts(size[0], 2)
+		if err != nil {
+			return nil, err
+		}
+		fixture.Size = geom.Vec2{R: values[0], C: values[1]}
+	}
+
+	input, ok := sections["input"]
+	if !ok {
+		return nil, fmt.Errorf("fixture has no input")
+	}
+
+	var data_ []byte
+	for _, line := range input {
+		unescaped, err := Unescape(line)
+		if err != nil {
+			return nil, err
+		}
+		data_ = append(data_, unescaped...)
+	}
+	fixture.Events = []sessions.Event{{
+		Message: P.OutputMessage{Data: data_},
+	}}
+
+	fixture.Expect, err = parseExpectation(sections)
+	if err != nil {
+		return nil, err
+	}
+
+	return fixture, nil
+}
+
+// GoldenPath returns the path of the file containing the expected result
+// of the `.borg` file at `path`.
+func GoldenPath(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".golden"
+}
+
+// readEvents reads all of the events in a `.borg` file.
+func readEvents(path string) (events []sessions.Event, err error) {
+	reader, err := sessions.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	for {
+		event, err := reader.Read()
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return events, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+}
+
+// Load reads the fixture at `path`, which must be either a `.vt` or a
+// `.borg` file. The golden file for a `.borg` file does not need to exist.
+func Load(path string) (*Fixture, error) {
+	name := filepath.Base(path)
+
+	switch filepath.Ext(path) {
+	case ".vt":
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		fixture, err := Parse(name, data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		return fixture, nil
+	case ".borg":
+		events, err := readEvents(path)
+		if err != nil {
+			return nil, err
+		}
+
+		fixture := &Fixture{
+			Name:   name,
+			Size:   geom.DEFAULT_SIZE,
+			Events: events,
+		}
+
+		golden, err := os.ReadFile(GoldenPath(path))
+		if os.IsNotExist(err) {
+			return fixture, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		sections, err := parseSections(golden)
+		if err != nil {
+			return nil, err
+		}
+
+		fixture.Expect, err = parseExpectation(sections)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", GoldenPath(name), err)
+		}
+		return fixture, nil
+	}
+
+	return nil, fmt.Errorf("unknown fixture type: %s", path)
+}
@@ -0,0 +1,121 @@
+package conformance
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cfoust/cy/pkg/emu"
+	"github.com/cfoust/cy/pkg/geom"
+
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool(
+	"update",
+	false,
+	"rewrite the golden files for recorded sessions",
+)
+
+func TestParse(t *testing.T) {
+	fixture, err := Parse("test", []byte(`
+# comment
+-- size --
+2 3
+-- input --
+a\eb\r\n
+\x1b[1m
+-- screen --
+|a b|
+
+-- cursor --
+position 1 2
+style bar
+visible false
+-- modes --
++wrap -alt-screen
+`))
+	require.NoError(t, err)
+	require.Equal(t, geom.Vec2{R: 2, C: 3}, fixture.Size)
+	require.Len(t, fixture.Events, 1)
+
+	expect := fixture.Expect
+	require.Equal(t, []string{"a b"}, expect.Screen)
+	require.Equal(t, geom.Vec2{R: 1, C: 2}, *expect.Cursor)
+	require.Equal(t, emu.CursorStyleBar, *expect.CursorStyle)
+	require.False(t, *expect.CursorVisible)
+	require.Equal(t, map[emu.ModeFlag]bool{
+		emu.ModeWrap:      true,
+		emu.ModeAltScreen: false,
+	}, expect.Modes)
+
+	_, err = Parse("test", []byte("-- size --\n1 1\n"))
+	require.Error(t, err)
+
+	_, err = Parse("test", []byte("-- input --\na\n-- modes --\n+foo\n"))
+	require.Error(t, err)
+}
+
+func TestCheck(t *testing.T) {
+	fixture, err := Parse("test", []byte(`
+-- size --
+1 3
+-- input --
+ab
+-- screen --
+|ba |
+-- cursor --
+position 0 2
+`))
+	require.NoError(t, err)
+
+	problems := fixture.Expect.Check(fixture.Run())
+	require.Equal(t, []string{"row 0 is |ab |, expected |ba |"}, problems)
+
+	// Capture and Format should produce an Expectation that passes
+	term := fixture.Run()
+	var buf bytes.Buffer
+	Capture(term).Format(&buf)
+	sections, err := parseSections(buf.Bytes())
+	require.NoError(t, err)
+	expect, err := parseExpectation(sections)
+	require.NoError(t, err)
+	require.Empty(t, expect.Check(term))
+}
+
+func TestFixtures(t *testing.T) {
+	paths, err := filepath.Glob("testdata/*")
+	require.NoError(t, err)
+
+	for _, path := range paths {
+		ext := filepath.Ext(path)
+		if ext != ".vt" && ext != ".borg" {
+			continue
+		}
+
+		t.Run(strings.TrimSuffix(filepath.Base(path), ext), func(t *testing.T) {
+			fixture, err := Load(path)
+			require.NoError(t, err)
+
+			term := fixture.Run()
+
+			if ext == ".borg" && *update {
+				var buf bytes.Buffer
+				Capture(term).Format(&buf)
+				require.NoError(t, os.WriteFile(
+					GoldenPath(path),
+					buf.Bytes(),
+					0644,
+				))
+				return
+			}
+
+			for _, problem := range fixture.Expect.Check(term) {
+				t.Error(problem)
+			}
+		})
+	}
+}
@@ -9,4 +9,18 @@ const (
 	LineFeedMode   = "\033[20h"
 	EnterAltScreen = "\033[?1049h"
 	ExitAltScreen  = "\033[?1049l"
+	// Applications that enable bracketed paste expect pasted text to be
+	// surrounded by these sequences.
+	// See: https://invisible-island.net/xterm/xterm-paste64.html
+	PasteStart = "\033[200~"
+	PasteEnd   = "\033[201~"
+	// See:
+	// https://gist.github.com/christianparpart/d8a62cc1ab659194337d73e399004036
+	BeginSyncUpdate = "\033[?2026h"
+	EndSyncUpdate   = "\033[?2026l"
+	// The responses to requests for the primary (DA1) and secondary (DA2)
+	// device attributes. cy identifies itself as a VT220 that supports
+	// sixel graphics.
+	PrimaryAttributes   = "\033[?62;4c"
+	SecondaryAttributes = "\033[>1;0;0c"
 )
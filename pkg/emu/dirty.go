@@ -15,8 +15,11 @@ type Dirty struct {
 
 	// A mapping from the hook -> whether it has appeared since the last
 	// Reset()
-	hooks     map[string]bool
-	hookState []byte
+	hooks map[string]bool
+	// The parameters that followed the most recent occurrence of each
+	// hook
+	hookParams map[string]string
+	hookState  []byte
 	// The number of bytes used in hookState so we can avoid allocations
 	hookCount int
 
@@ -49,6 +52,7 @@ func (t *State) Changes() *Dirty {
 // input to the terminal since the last Reset(), Hook("cy") will return true.
 func (d *Dirty) SetHooks(hooks []string) {
 	d.hooks = make(map[string]bool)
+	d.hookParams = make(map[string]string)
 	for _, hook := range hooks {
 		d.hooks[hook] = false
 	}
@@ -60,6 +64,14 @@ func (d *Dirty) Hook(hook string) (value, ok bool) {
 	return
 }
 
+// HookParams returns the parameters that followed the hook the last time it
+// appeared since the last Reset(). Parameters are separated from the hook
+// by a semicolon, so for the device control string "\033Pcy;exit=0\033\\",
+// HookParams("cy") returns "exit=0".
+func (d *Dirty) HookParams(hook string) string {
+	return d.hookParams[hook]
+}
+
 func (d *Dirty) LastWrite() WriteID {
 	return d.writeId
 }
@@ -89,6 +101,7 @@ func (d *Dirty) Reset() {
 	d.hookCount = 0
 	for hook := range d.hooks {
 		d.hooks[hook] = false
+		delete(d.hookParams, hook)
 	}
 }
 
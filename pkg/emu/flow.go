@@ -35,8 +35,8 @@ func (s *State) accessPhysicalLines() (getLines func(int) (Line, bool), numLines
 	screen, history, _ := s.getFlowTarget()
 
 	var (
-		numHistory     = len(history)
-		isWrapped      = false
+		numHistory     = history.Len()
+		isWrapped      = history.IsWrapped()
 		screenPhysical = unwrapLines(screen)
 		screenLines    = resolveLines(screen, screenPhysical)
 		screenStart    = 0
@@ -44,10 +44,6 @@ func (s *State) accessPhysicalLines() (getLines func(int) (Line, bool), numLines
 
 	numLines = numHistory + len(screenLines)
 
-	if numHistory > 0 {
-		isWrapped = history[numHistory-1].IsWrapped()
-	}
-
 	// If the last line of history continues onto the screen, we have one
 	// less line
 	if isWrapped {
@@ -56,6 +52,14 @@ func (s *State) accessPhysicalLines() (getLines func(int) (Line, bool), numLines
 	}
 
 	lastHistory := numHistory - screenStart
+
+	// Lines in history must be decoded, so we keep the most recent one
+	// around since callers tend to request the same line repeatedly
+	var (
+		cachedIndex = -1
+		cachedLine  Line
+	)
+
 	getLines = func(index int) (line Line, ok bool) {
 		if index < 0 || index >= numLines {
 			return
@@ -64,13 +68,17 @@ func (s *State) accessPhysicalLines() (getLines func(int) (Line, bool), numLines
 		ok = true
 
 		if index < lastHistory {
-			line = history[index]
+			if index != cachedIndex {
+				cachedIndex = index
+				cachedLine = history.Line(index)
+			}
+			line = cachedLine
 			return
 		}
 
 		// special case: history line continues onto screen
 		if isWrapped && index == lastHistory {
-			line = history[len(history)-1].Clone()
+			line = history.Line(numHistory - 1)
 			line = append(line, screenLines[0]...)
 			return
 		}
@@ -81,7 +89,7 @@ func (s *State) accessPhysicalLines() (getLines func(int) (Line, bool), numLines
 	return
 }
 
-func (s *State) getFlowTarget() (screen, history []Line, cursor Cursor) {
+func (s *State) getFlowTarget() (screen []Line, history *scrollback, cursor Cursor) {
 	if IsAltMode(s.mode) {
 		return s.altScreen, s.altHistory, s.curSaved
 	}
@@ -97,17 +105,13 @@ func (s *State) Flow(
 	screen, history, cursor := s.getFlowTarget()
 
 	var (
-		numHistory        = len(history)
-		isWrapped         = false
+		numHistory        = history.Len()
+		isWrapped         = history.IsWrapped()
 		screenLines       = unwrapLines(screen)
 		cols              = viewport.C
 		getLine, numLines = s.accessPhysicalLines()
 	)
 
-	if numHistory > 0 {
-		isWrapped = history[numHistory-1].IsWrapped()
-	}
-
 	result.NumLines = numLines
 
 	if root.C < 0 || root.R < 0 || root.R >= numLines {
@@ -0,0 +1,239 @@
+package emu
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/cfoust/cy/pkg/geom"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fuzzSeeds are inputs that exercise the parts of the parser that are most
+// likely to index out of bounds.
+var fuzzSeeds = []string{
+	"hello world\r\nfoo\tbar",
+	"\x1b[2J\x1b[H\x1b[999;999H\x1b[Kx",
+	"\x1b[5;2r\x1b[?6h\x1b[99Bx\x1bM\x1bD\x1b[3L\x1b[3M",
+	"\x1b[999@\x1b[999P\x1b[999X\x1b[999I\x1b[999Z",
+	"\x1b[?1049h\x1b[?47l\x1b[?1047h\x1b[?1048l",
+	"\x1b[38;2;1;2;3m\x1b[48;5;300m\x1b[38;5m\x1b[38;2m",
+	"\x1b]4;300;rgb:ff/ff/ff\x07\x1b]10;?\x07\x1b]104;999\x1b\\",
+	"\x1b(0lqqk\x1b(B\x1b#8",
+	"日本語の文字列\x1b[1;79H日本",
+	"é\U0001F44D\U0001F3FD‍",
+	"\x1bPq#0;2;100;0;0#0!10~-~~\x1b\\",
+	"\x1b_Ga=T,f=24,s=1,v=1;AAAA\x1b\\",
+	"\x1b[>1u\x1b[<u\x1b[?u\x1b[=5;2u",
+}
+
+// checkInvariants verifies properties of the terminal that should hold
+// regardless of what it has been sent.
+func checkInvariants(t *testing.T, term Terminal) {
+	size := term.Size()
+
+	cursor := term.Cursor()
+	require.GreaterOrEqual(t, cursor.R, 0)
+	require.GreaterOrEqual(t, cursor.C, 0)
+	require.Less(t, cursor.R, size.R)
+	require.Less(t, cursor.C, size.C)
+
+	screen := term.Screen()
+	require.Len(t, screen, size.R)
+	for _, line := range screen {
+		require.Len(t, line, size.C)
+	}
+
+	require.Len(t, term.History(), term.HistoryLength())
+
+	// Flowing the lines at the root of the screen with a viewport the
+	// size of the screen should produce the screen
+	if term.IsAltMode() {
+		return
+	}
+
+	result := term.Flow(size, term.Root())
+	if !result.OK {
+		return
+	}
+
+	require.LessOrEqual(t, len(result.Lines), size.R)
+	for row, line := range result.Lines {
+		require.Equal(
+			t,
+			strings.TrimRight(screen[row].String(), " "),
+			strings.TrimRight(line.Chars.String(), " "),
+			"row %d differs", row,
+		)
+	}
+}
+
+func FuzzWrite(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		size := geom.Vec2{R: 5, C: 10}
+		term := New(WithSize(size))
+		term.Write(data)
+		checkInvariants(t, term)
+
+		// Whether history is kept should not affect the screen
+		other := New(WithSize(size), WithoutHistory)
+		other.Write(data)
+		require.Equal(t, term.String(), other.String())
+	})
+}
+
+// checkResize writes `data` to a terminal, resizes it to `other`, and
+// writes `data` again, checking that the terminal is consistent along the
+// way.
+func checkResize(t *testing.T, data []byte, other geom.Vec2) {
+	size := geom.Vec2{R: 5, C: 10}
+
+	term := New(WithSize(size))
+	term.Write(data)
+	checkInvariants(t, term)
+	term.Resize(other)
+	checkInvariants(t, term)
+	term.Write(data)
+	checkInvariants(t, term)
+
+	// Reflowing the terminal's lines should not change them
+	if term.IsAltMode() {
+		return
+	}
+
+	term.Resize(size)
+	checkInvariants(t, term)
+	before := physicalLines(term)
+	term.Resize(other)
+	term.Resize(size)
+	checkInvariants(t, term)
+	require.Equal(t, before, physicalLines(term))
+}
+
+func FuzzResize(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add([]byte(seed), uint8(3), uint8(4))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte, rows, cols uint8) {
+		// Keep the terminal small so that the fuzzer explores
+		// wrapping
+		checkResize(t, data, geom.Vec2{
+			R: int(rows%20) + 1,
+			C: int(cols%20) + 1,
+		})
+	})
+}
+
+// Inputs found by fuzzing that used to violate an invariant.
+func TestFuzzRegressions(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		data string
+		size geom.Vec2
+	}{
+		{
+			// DEL was treated as a zero-width character, which
+			// wrapped the line without printing anything
+			name: "zero-width wrap",
+			data: "000\b00000000\x7f\f0",
+			size: geom.Vec2{R: 3, C: 4},
+		},
+		{
+			// Blank cells at the end of wrapped rows were
+			// dropped when the line was unwrapped
+			name: "wrapped blanks",
+			data: "hello world\r\nfoo\tbar",
+			size: geom.Vec2{R: 4, C: 5},
+		},
+		{
+			// Deleting the continuation of a wrapped line left the
+			// line above it wrapped
+			name: "delete continuation",
+			data: "\x1b[M\x1bD000000",
+			size: geom.Vec2{R: 4, C: 5},
+		},
+		{
+			// Erasing the continuation of a wrapped line
+			name: "erase continuation",
+			data: "\x1b[X\x1b[7;10H0",
+			size: geom.Vec2{R: 16, C: 5},
+		},
+		{
+			// Resizing moved the cursor off of an empty line
+			name: "cursor on empty line",
+			data: "000000000000000000\f0",
+			size: geom.Vec2{R: 16, C: 5},
+		},
+		{
+			// The padding before a wide character that did not fit
+			// was included in the scrollback
+			name: "wide padding",
+			data: "日本0\xaa\x9eの文字\xe500000日本",
+			size: geom.Vec2{R: 18, C: 5},
+		},
+		{
+			// A narrow character replaced the wide character that
+			// padding was inserted for
+			name: "stale padding",
+			data: "本の文字列\x1b80000\xe6",
+			size: geom.Vec2{R: 12, C: 5},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			checkResize(t, []byte(test.data), test.size)
+		})
+	}
+}
+
+// The wrap state belongs to the last cell in a row, even if that cell is
+// part of a wide character.
+func TestWideWrap(t *testing.T) {
+	term := New(WithSize(geom.Vec2{R: 2, C: 2}))
+	term.Write([]byte("\U0001F3FD0"))
+	require.True(t, term.Screen()[0].IsWrapped())
+	checkInvariants(t, term)
+}
+
+// Sequences with large counts should not take time proportional to the
+// count.
+func TestLargeCounts(t *testing.T) {
+	term := New(WithSize(geom.Vec2{R: 5, C: 10}))
+	term.Write([]byte("\x1b[2147483647I\x1b[2147483647Z"))
+	require.Equal(t, geom.Vec2{}, term.Cursor().Vec2)
+	checkInvariants(t, term)
+}
+
+// physicalLines returns the text of all of the unwrapped lines in the
+// terminal, excluding any trailing empty lines.
+func physicalLines(term Terminal) (lines []string) {
+	result := term.Flow(geom.Vec2{C: math.MaxInt32}, geom.Vec2{})
+	for _, line := range result.Lines {
+		lines = append(lines, strings.TrimRight(line.Chars.String(), " "))
+	}
+
+	for len(lines) > 0 && len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	return
+}
+
+// A panic while handling a sequence should not propagate to the caller.
+func TestParseRecover(t *testing.T) {
+	term := New(WithSize(geom.Vec2{R: 2, C: 4}))
+
+	// Corrupt the cursor so that printing indexes out of bounds
+	st := term.(*terminal)
+	st.cur.R = 100
+
+	n, err := term.Write([]byte("ab"))
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	checkInvariants(t, term)
+	require.Equal(t, "b", strings.TrimRight(term.Screen()[1].String(), " "))
+}
@@ -0,0 +1,204 @@
+package emu
+
+import (
+	"image"
+	"image/color"
+	"sync"
+	"sync/atomic"
+
+	"github.com/cfoust/cy/pkg/geom"
+)
+
+// DefaultCellSize is the size in pixels of a single cell. cy cannot know the
+// dimensions of the cells on every client's screen, so images are laid out
+// as though all cells were this size.
+var DefaultCellSize = geom.Vec2{R: 20, C: 10}
+
+// The maximum width or height in pixels of an image the terminal will
+// decode. Anything larger is discarded.
+const maxImageDimension = 4096
+
+var imageCounter uint64
+
+// Image is a picture that a program drew on the screen using either sixel or
+// the kitty graphics protocol.
+type Image struct {
+	// ID uniquely identifies this image among all of the images that
+	// have been decoded by any Terminal.
+	ID uint64
+	// Pixels contains the image's decoded pixel data.
+	Pixels image.Image
+	// Size is the number of cells the image covers.
+	Size geom.Vec2
+
+	previewOnce sync.Once
+	preview     [][]Color
+}
+
+func newImage(pixels image.Image, size geom.Vec2) *Image {
+	return &Image{
+		ID:     atomic.AddUint64(&imageCounter, 1),
+		Pixels: pixels,
+		Size:   size,
+	}
+}
+
+// cellsFor returns the number of cells required to show an image with the
+// given size in pixels.
+func cellsFor(pixels geom.Vec2) geom.Vec2 {
+	return geom.Vec2{
+		R: geom.Max((pixels.R+DefaultCellSize.R-1)/DefaultCellSize.R, 1),
+		C: geom.Max((pixels.C+DefaultCellSize.C-1)/DefaultCellSize.C, 1),
+	}
+}
+
+// CellSize returns the size in pixels of the portion of the image that
+// appears in each cell.
+func (i *Image) CellSize() geom.Vec2 {
+	bounds := i.Pixels.Bounds()
+	return geom.Vec2{
+		R: geom.Max((bounds.Dy()+i.Size.R-1)/geom.Max(i.Size.R, 1), 1),
+		C: geom.Max((bounds.Dx()+i.Size.C-1)/geom.Max(i.Size.C, 1), 1),
+	}
+}
+
+// Region returns the rectangle of pixels shown in the cells beginning at
+// `offset` and extending for `size` cells.
+func (i *Image) Region(offset, size geom.Vec2) image.Rectangle {
+	bounds := i.Pixels.Bounds()
+	cell := i.CellSize()
+	return image.Rect(
+		bounds.Min.X+offset.C*cell.C,
+		bounds.Min.Y+offset.R*cell.R,
+		bounds.Min.X+(offset.C+size.C)*cell.C,
+		bounds.Min.Y+(offset.R+size.R)*cell.R,
+	).Intersect(bounds)
+}
+
+// toColor converts a color in the image into a true color Color.
+func toColor(c color.Color) Color {
+	r, g, b, _ := c.RGBA()
+	return NewRGB(uint8(r>>8), uint8(g>>8), uint8(b>>8))
+}
+
+// Preview returns the average color of the portion of the image in the cell
+// at `offset`, which is used to approximate the image on terminals that
+// cannot display it.
+func (i *Image) Preview(offset geom.Vec2) Color {
+	i.previewOnce.Do(func() {
+		const maxSamples = 8
+		i.preview = make([][]Color, i.Size.R)
+		for row := range i.preview {
+			i.preview[row] = make([]Color, i.Size.C)
+			for col := range i.preview[row] {
+				region := i.Region(
+					geom.Vec2{R: row, C: col},
+					geom.Vec2{R: 1, C: 1},
+				)
+				if region.Empty() {
+					i.preview[row][col] = DefaultBG
+					continue
+				}
+
+				stepX := geom.Max(region.Dx()/maxSamples, 1)
+				stepY := geom.Max(region.Dy()/maxSamples, 1)
+
+				var r, g, b, a, n uint64
+				for y := region.Min.Y; y < region.Max.Y; y += stepY {
+					for x := region.Min.X; x < region.Max.X; x += stepX {
+						cr, cg, cb, ca := i.Pixels.At(x, y).RGBA()
+						r += uint64(cr)
+						g += uint64(cg)
+						b += uint64(cb)
+						a += uint64(ca)
+						n++
+					}
+				}
+
+				// Mostly transparent cells show the background
+				if a/n < 0x8000 {
+					i.preview[row][col] = DefaultBG
+					continue
+				}
+
+				i.preview[row][col] = toColor(color.RGBA64{
+					R: uint16(r / n),
+					G: uint16(g / n),
+					B: uint16(b / n),
+					A: 0xffff,
+				})
+			}
+		}
+	})
+
+	if offset.R < 0 || offset.R >= len(i.preview) || offset.C < 0 || offset.C >= len(i.preview[offset.R]) {
+		return DefaultBG
+	}
+
+	return i.preview[offset.R][offset.C]
+}
+
+// ImageCell is a reference to the portion of an Image that is shown in a
+// single cell.
+type ImageCell struct {
+	Image *Image
+	// Offset is the location of this cell relative to the top-left cell of
+	// the image.
+	Offset geom.Vec2
+}
+
+// Equal reports whether two ImageCells refer to the same part of the same
+// image. Either may be nil.
+func (i *ImageCell) Equal(other *ImageCell) bool {
+	if i == nil || other == nil {
+		return i == other
+	}
+
+	return *i == *other
+}
+
+// placeImage draws `img` with its top-left corner at the cursor, scrolling
+// the screen if the image extends beyond the bottom. Afterwards the cursor
+// is left on the image's final row. Any portion of the image that does not
+// fit horizontally is clipped.
+func (t *State) placeImage(img *Image) {
+	t.clusterValid = false
+	col := t.cur.C
+
+	for row := 0; row < img.Size.R; row++ {
+		if row > 0 {
+			t.newline(false)
+		}
+
+		y := t.cur.R
+		t.dirty.markScreen()
+		t.markDirtyLine(y)
+
+		for i := 0; i < img.Size.C && col+i < t.cols; i++ {
+			glyph := EmptyGlyph()
+			glyph.Write = t.dirty.writeId
+			glyph.Image = &ImageCell{
+				Image:  img,
+				Offset: geom.Vec2{R: row, C: i},
+			}
+			t.screen[y][col+i] = glyph
+		}
+	}
+}
+
+// deleteImages removes the cells on the screen that display an image for
+// which `shouldDelete` returns true.
+func (t *State) deleteImages(shouldDelete func(img *Image) bool) {
+	for y, line := range t.screen {
+		for x, glyph := range line {
+			if glyph.Image == nil || !shouldDelete(glyph.Image.Image) {
+				continue
+			}
+
+			t.markDirtyLine(y)
+			t.screen[y][x] = EmptyGlyph()
+			t.screen[y][x].Write = t.dirty.writeId
+		}
+	}
+	t.dirty.markScreen()
+}
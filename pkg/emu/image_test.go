@@ -0,0 +1,91 @@
+package emu
+
+import (
+	"encoding/base64"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/cfoust/cy/pkg/geom"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSixel(t *testing.T) {
+	term := New(WithSize(geom.Vec2{R: 5, C: 10}))
+
+	// A 20x12 red rectangle
+	term.Write([]byte("\033Pq#1;2;100;0;0#1!20~-!20~\033\\"))
+
+	first := term.Cell(0, 0).Image
+	require.NotNil(t, first)
+	require.Equal(t, geom.Vec2{R: 1, C: 2}, first.Image.Size)
+	require.Equal(t, geom.Vec2{}, first.Offset)
+	require.Equal(
+		t,
+		color.RGBA{255, 0, 0, 255},
+		first.Image.Pixels.At(19, 11),
+	)
+	require.Equal(t, geom.Vec2{C: 1}, term.Cell(1, 0).Image.Offset)
+	require.Nil(t, term.Cell(2, 0).Image)
+	require.Equal(t, geom.Vec2{R: 1}, term.Cursor().Vec2)
+
+	// Sixel data longer than the hook buffer should not cause problems
+	term.Write([]byte("\033Pq#1;2;0;100;0#1" + strings.Repeat("~", 1000) + "\033\\"))
+	require.Equal(t, geom.Vec2{R: 1, C: 100}, term.Cell(0, 1).Image.Image.Size)
+}
+
+func TestKittyGraphics(t *testing.T) {
+	var out strings.Builder
+	term := New(
+		WithWriter(&out),
+		WithSize(geom.Vec2{R: 4, C: 10}),
+	)
+
+	// 20x40 pixels of opaque blue
+	pixels := make([]byte, 0, 20*40*4)
+	for i := 0; i < 20*40; i++ {
+		pixels = append(pixels, 0, 0, 255, 255)
+	}
+	payload := base64.StdEncoding.EncodeToString(pixels)
+
+	// Send the image in two chunks
+	half := len(payload) / 2
+	term.Write([]byte("\033_Ga=T,f=32,s=20,v=40,i=5,m=1;" + payload[:half] + "\033\\"))
+	require.Nil(t, term.Cell(0, 0).Image)
+	term.Write([]byte("\033_Gm=0;" + payload[half:] + "\033\\"))
+	require.Equal(t, "\033_Gi=5;OK\033\\", out.String())
+
+	cell := term.Cell(1, 1).Image
+	require.NotNil(t, cell)
+	require.Equal(t, geom.Vec2{R: 2, C: 2}, cell.Image.Size)
+	require.Equal(t, geom.Vec2{R: 1, C: 1}, cell.Offset)
+	require.Equal(t, geom.Vec2{R: 1, C: 2}, term.Cursor().Vec2)
+	require.False(t, term.Cell(0, 0).IsEmpty())
+
+	// Placing the stored image again, scaled to a single row
+	term.Write([]byte("\r\n\033_Ga=p,i=5,r=1,q=1\033\\"))
+	require.Equal(t, geom.Vec2{R: 1, C: 1}, term.Cell(0, 2).Image.Image.Size)
+
+	// Images survive scrolling into the history
+	term.Write([]byte("\r\n\n\n\n"))
+	history := term.History()
+	require.Len(t, history, 3)
+	require.NotNil(t, history[0][0].Image)
+	require.NotNil(t, history[2][0].Image)
+
+	term.Write([]byte("\033_Ga=p,i=5,r=1\033\\"))
+	require.NotNil(t, term.Cell(0, 3).Image)
+	term.Write([]byte("\033_Ga=d\033\\"))
+	require.Nil(t, term.Cell(0, 3).Image)
+
+	// Unknown images produce errors
+	out.Reset()
+	term.Write([]byte("\033_Ga=p,i=6\033\\"))
+	require.Equal(t, "\033_Gi=6;ENOENT:image not found\033\\", out.String())
+
+	// Escape sequences split across writes still work
+	term.Write([]byte("\033"))
+	term.Write([]byte("[2;1Hx"))
+	require.Equal(t, 'x', term.Cell(0, 1).Char)
+}
@@ -0,0 +1,463 @@
+package emu
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"strconv"
+
+	"github.com/cfoust/cy/pkg/geom"
+)
+
+// This file implements the subset of the kitty graphics protocol that is
+// needed to display images that are sent directly over the terminal.
+// See: https://sw.kovidgoyal.net/kitty/graphics-protocol/
+
+const (
+	// The maximum number of bytes in a single APC string.
+	maxAPCData = 8 * 1024 * 1024
+	// The maximum number of bytes of encoded image data the terminal will
+	// accept across all of the chunks of a single image.
+	maxKittyData = 64 * 1024 * 1024
+	// The maximum number of transmitted images a terminal retains.
+	maxKittyImages = 64
+)
+
+// apcState tracks the progress of an Application Program Command string
+// (`ESC _ ... ESC \`), which go-vte otherwise silently discards.
+type apcState struct {
+	// Whether the previous byte was an unhandled ESC.
+	escape bool
+	// Whether an APC string is being received.
+	active bool
+	data   []byte
+}
+
+// advance feeds a single byte to the parser, intercepting APC strings.
+func (t *State) advance(b byte) {
+	apc := &t.apc
+
+	if apc.active {
+		if apc.escape {
+			apc.escape = false
+			apc.active = false
+
+			if b == '\\' {
+				t.handleAPC(apc.data)
+				apc.data = nil
+				return
+			}
+
+			// Anything other than ST aborts the string
+			apc.data = nil
+			t.parser.Advance(0x1b)
+			t.parser.Advance(b)
+			return
+		}
+
+		if b == 0x1b {
+			apc.escape = true
+			return
+		}
+
+		if len(apc.data) < maxAPCData {
+			apc.data = append(apc.data, b)
+		}
+		return
+	}
+
+	if apc.escape {
+		apc.escape = false
+
+		if b == '_' {
+			apc.active = true
+			// Cancel whatever sequence the parser was in the
+			// middle of, which is what ESC would have done
+			t.parser.Advance(0x18)
+			return
+		}
+
+		t.parser.Advance(0x1b)
+	}
+
+	if b == 0x1b {
+		apc.escape = true
+		return
+	}
+
+	t.parser.Advance(b)
+}
+
+func (t *State) handleAPC(data []byte) {
+	t.clusterValid = false
+	if len(data) == 0 || data[0] != 'G' {
+		return
+	}
+
+	t.handleGraphics(data[1:])
+}
+
+// kittyCommand is a single graphics command, which consists of a set of
+// key-value pairs and an optional payload.
+type kittyCommand struct {
+	keys    map[byte]string
+	payload []byte
+}
+
+func parseKittyCommand(data []byte) kittyCommand {
+	cmd := kittyCommand{keys: make(map[byte]string)}
+
+	control := data
+	if index := bytes.IndexByte(data, ';'); index != -1 {
+		control = data[:index]
+		cmd.payload = data[index+1:]
+	}
+
+	for _, pair := range bytes.Split(control, []byte(",")) {
+		if len(pair) < 3 || pair[1] != '=' {
+			continue
+		}
+		cmd.keys[pair[0]] = string(pair[2:])
+	}
+
+	return cmd
+}
+
+func (c kittyCommand) str(key byte, def string) string {
+	if value, ok := c.keys[key]; ok {
+		return value
+	}
+	return def
+}
+
+func (c kittyCommand) num(key byte) int {
+	value, err := strconv.ParseInt(c.keys[key], 10, 64)
+	if err != nil || value < 0 || value > 1<<32-1 {
+		return 0
+	}
+	return int(value)
+}
+
+// kittyTransfer is an image that is being sent in multiple chunks.
+type kittyTransfer struct {
+	cmd  kittyCommand
+	data []byte
+}
+
+// respond replies to a graphics command, which the protocol only does when
+// the program provided an image ID.
+func (t *State) respond(cmd kittyCommand, err error) {
+	id := cmd.num('i')
+	quiet := cmd.num('q')
+	if id == 0 || (err == nil && quiet >= 1) || quiet >= 2 {
+		return
+	}
+
+	message := "OK"
+	if err != nil {
+		message = err.Error()
+	}
+
+	fmt.Fprintf(t.w, "\033_Gi=%d;%s\033\\", id, message)
+}
+
+func (t *State) handleGraphics(data []byte) {
+	cmd := parseKittyCommand(data)
+
+	// Subsequent chunks only include the `m` and `q` keys
+	if t.transfer != nil {
+		transfer := t.transfer
+		transfer.data = append(transfer.data, cmd.payload...)
+		if len(transfer.data) > maxKittyData {
+			t.transfer = nil
+			t.respond(transfer.cmd, fmt.Errorf("EFBIG:image is too large"))
+			return
+		}
+
+		if cmd.num('m') == 1 {
+			return
+		}
+
+		t.transfer = nil
+		cmd = transfer.cmd
+		cmd.payload = transfer.data
+	} else if cmd.num('m') == 1 {
+		t.transfer = &kittyTransfer{
+			cmd:  cmd,
+			data: append([]byte(nil), cmd.payload...),
+		}
+		return
+	}
+
+	switch cmd.str('a', "t") {
+	case "q":
+		_, err := decodeKittyImage(cmd)
+		t.respond(cmd, err)
+	case "t", "T":
+		pixels, err := decodeKittyImage(cmd)
+		if err != nil {
+			t.respond(cmd, err)
+			return
+		}
+
+		img := newImage(pixels, cellsFor(geom.Vec2{
+			R: pixels.Bounds().Dy(),
+			C: pixels.Bounds().Dx(),
+		}))
+
+		if id := uint32(cmd.num('i')); id != 0 {
+			t.storeImage(id, img)
+		}
+
+		if cmd.str('a', "t") == "T" {
+			t.displayImage(cmd, img)
+		}
+		t.respond(cmd, nil)
+	case "p":
+		img, ok := t.images[uint32(cmd.num('i'))]
+		if !ok {
+			t.respond(cmd, fmt.Errorf("ENOENT:image not found"))
+			return
+		}
+
+		t.displayImage(cmd, img)
+		t.respond(cmd, nil)
+	case "d":
+		t.deleteKittyImages(cmd)
+	}
+}
+
+// storeImage saves a transmitted image so that it can be displayed later,
+// evicting the oldest image if too many are stored.
+func (t *State) storeImage(id uint32, img *Image) {
+	if t.images == nil {
+		t.images = make(map[uint32]*Image)
+	}
+
+	if _, ok := t.images[id]; !ok {
+		t.imageOrder = append(t.imageOrder, id)
+	}
+	t.images[id] = img
+
+	if len(t.imageOrder) <= maxKittyImages {
+		return
+	}
+
+	oldest := t.imageOrder[0]
+	t.imageOrder = t.imageOrder[1:]
+	delete(t.images, oldest)
+}
+
+func (t *State) forgetImage(id uint32) {
+	delete(t.images, id)
+	for i, other := range t.imageOrder {
+		if other != id {
+			continue
+		}
+		t.imageOrder = append(t.imageOrder[:i], t.imageOrder[i+1:]...)
+		break
+	}
+}
+
+// displayImage places an image on the screen at the cursor, cropping and
+// scaling it according to the command's parameters.
+func (t *State) displayImage(cmd kittyCommand, img *Image) {
+	pixels := img.Pixels
+	bounds := pixels.Bounds()
+
+	// Source rectangle
+	if cmd.num('x')|cmd.num('y')|cmd.num('w')|cmd.num('h') != 0 {
+		x, y := cmd.num('x'), cmd.num('y')
+		w, h := cmd.num('w'), cmd.num('h')
+		if w == 0 {
+			w = bounds.Dx()
+		}
+		if h == 0 {
+			h = bounds.Dy()
+		}
+
+		region := image.Rect(
+			bounds.Min.X+x,
+			bounds.Min.Y+y,
+			bounds.Min.X+x+w,
+			bounds.Min.Y+y+h,
+		).Intersect(bounds)
+		if region.Empty() {
+			return
+		}
+
+		if sub, ok := pixels.(interface {
+			SubImage(image.Rectangle) image.Image
+		}); ok {
+			pixels = sub.SubImage(region)
+			bounds = region
+		}
+	}
+
+	size := cellsFor(geom.Vec2{R: bounds.Dy(), C: bounds.Dx()})
+	cols, rows := cmd.num('c'), cmd.num('r')
+	switch {
+	case cols > 0 && rows > 0:
+		size = geom.Vec2{R: rows, C: cols}
+	case cols > 0:
+		size = geom.Vec2{
+			R: geom.Max(
+				(bounds.Dy()*cols*DefaultCellSize.C+
+					bounds.Dx()*DefaultCellSize.R-1)/
+					(bounds.Dx()*DefaultCellSize.R),
+				1,
+			),
+			C: cols,
+		}
+	case rows > 0:
+		size = geom.Vec2{
+			R: rows,
+			C: geom.Max(
+				(bounds.Dx()*rows*DefaultCellSize.R+
+					bounds.Dy()*DefaultCellSize.C-1)/
+					(bounds.Dy()*DefaultCellSize.C),
+				1,
+			),
+		}
+	}
+	size.R = geom.Min(size.R, maxImageDimension)
+	size.C = geom.Min(size.C, maxImageDimension)
+
+	if pixels != img.Pixels || size != img.Size {
+		img = newImage(pixels, size)
+	}
+
+	cur := t.cur
+	t.placeImage(img)
+
+	if cmd.num('C') == 1 {
+		t.moveTo(cur.C, t.cur.R-img.Size.R+1)
+		return
+	}
+
+	// The cursor moves to the cell after the image's last column
+	destCol := cur.C + img.Size.C
+	if destCol < t.cols {
+		t.moveTo(destCol, t.cur.R)
+	} else {
+		t.moveTo(t.cols-1, t.cur.R)
+		t.cur.State |= cursorWrapNext
+	}
+}
+
+// deleteKittyImages removes images from the screen. Upper case values of
+// the `d` key also free the image data.
+func (t *State) deleteKittyImages(cmd kittyCommand) {
+	switch target := cmd.str('d', "a"); target {
+	case "a", "A":
+		t.deleteImages(func(img *Image) bool { return true })
+		if target == "A" {
+			t.images = nil
+			t.imageOrder = nil
+		}
+	case "i", "I":
+		id := uint32(cmd.num('i'))
+		stored, ok := t.images[id]
+		if !ok {
+			return
+		}
+
+		t.deleteImages(func(img *Image) bool {
+			return img == stored || img.Pixels == stored.Pixels
+		})
+		if target == "I" {
+			t.forgetImage(id)
+		}
+	}
+}
+
+// decodeKittyImage decodes the payload of a graphics command into an image.
+func decodeKittyImage(cmd kittyCommand) (image.Image, error) {
+	if medium := cmd.str('t', "d"); medium != "d" {
+		return nil, fmt.Errorf("EINVAL:unsupported transmission medium")
+	}
+
+	data, err := base64.StdEncoding.DecodeString(string(cmd.payload))
+	if err != nil {
+		data, err = base64.RawStdEncoding.DecodeString(string(cmd.payload))
+		if err != nil {
+			return nil, fmt.Errorf("EINVAL:invalid base64 data")
+		}
+	}
+
+	if cmd.str('o', "") == "z" {
+		reader, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("EINVAL:invalid compressed data")
+		}
+
+		data, err = io.ReadAll(io.LimitReader(reader, maxKittyData+1))
+		if err != nil || len(data) > maxKittyData {
+			return nil, fmt.Errorf("EINVAL:invalid compressed data")
+		}
+	}
+
+	format := cmd.num('f')
+	if format == 0 {
+		format = 32
+	}
+
+	switch format {
+	case 100:
+		config, err := png.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("EBADPNG:%s", err)
+		}
+		if config.Width > maxImageDimension || config.Height > maxImageDimension {
+			return nil, fmt.Errorf("EFBIG:image is too large")
+		}
+
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("EBADPNG:%s", err)
+		}
+
+		rgba := image.NewRGBA(img.Bounds())
+		draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+		return rgba, nil
+	case 24, 32:
+		width, height := cmd.num('s'), cmd.num('v')
+		if width == 0 || height == 0 {
+			return nil, fmt.Errorf("EINVAL:missing image dimensions")
+		}
+		if width > maxImageDimension || height > maxImageDimension {
+			return nil, fmt.Errorf("EFBIG:image is too large")
+		}
+
+		stride := format / 8
+		if len(data) < width*height*stride {
+			return nil, fmt.Errorf("ENODATA:insufficient image data")
+		}
+
+		img := image.NewRGBA(image.Rect(0, 0, width, height))
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				i := (y*width + x) * stride
+				c := color.NRGBA{
+					R: data[i],
+					G: data[i+1],
+					B: data[i+2],
+					A: 255,
+				}
+				if stride == 4 {
+					c.A = data[i+3]
+				}
+				img.Set(x, y, c)
+			}
+		}
+		return img, nil
+	}
+
+	return nil, fmt.Errorf("EINVAL:unsupported format")
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/cfoust/cy/pkg/geom"
 
 	"github.com/mattn/go-runewidth"
+	"github.com/rivo/uniseg"
 )
 
 // TODO(cfoust): 05/19/23 combine this with the other declaration
@@ -50,9 +51,28 @@ const (
 	ModeFocus
 	ModeMouseX10
 	ModeMouseMany
+	// The application expects pasted text to be surrounded by
+	// ESC[200~ and ESC[201~.
+	ModeBracketedPaste
+	// The application is in the middle of a synchronized update and
+	// would prefer that the screen not be drawn until it is done.
+	ModeSyncUpdate
 	ModeMouseMask = ModeMouseButton | ModeMouseMotion | ModeMouseX10 | ModeMouseMany
 )
 
+// KeyboardFlag represents the progressive enhancements to keyboard handling
+// that a program has requested using the kitty keyboard protocol.
+// See: https://sw.kovidgoyal.net/kitty/keyboard-protocol/
+type KeyboardFlag int
+
+const (
+	KeyboardDisambiguate KeyboardFlag = 1 << iota
+	KeyboardReportEvents
+	KeyboardReportAlternates
+	KeyboardReportAll
+	KeyboardReportText
+)
+
 // ChangeFlag represents possible state changes of the terminal.
 type ChangeFlag uint32
 
@@ -67,29 +87,53 @@ const (
 )
 
 type Glyph struct {
-	Char        rune
+	Char rune
+	// Combining contains any runes that follow Char in the same grapheme
+	// cluster, such as combining marks, variation selectors, or the rest
+	// of a ZWJ emoji sequence.
+	Combining   string
 	Mode        int16
 	FG, BG      Color
 	Transparent bool
 	Write       WriteID
+	// Image is set when this cell displays part of an image rather than
+	// text.
+	Image *ImageCell
 }
 
 func (g Glyph) IsEmpty() bool {
-	return g.Char == ' '
+	return g.Char == ' ' && len(g.Combining) == 0 && g.Image == nil
 }
 
 func (g Glyph) IsDefault() bool {
 	return g.Mode&attrBlank != 0
 }
 
+// String returns the full grapheme cluster contained in this Glyph.
+func (g Glyph) String() string {
+	if len(g.Combining) == 0 {
+		return string(g.Char)
+	}
+
+	return string(g.Char) + g.Combining
+}
+
 func (g Glyph) Width() int {
 	// runewidth can be 0, but we strictly want visible glyphs to be at
 	// least one cell wide.
-	return geom.Max(runewidth.RuneWidth(g.Char), 1)
+	width := runewidth.RuneWidth(g.Char)
+
+	// Some clusters (such as flags or emoji with a presentation selector)
+	// are wider than their first rune, but never narrower.
+	if len(g.Combining) > 0 {
+		width = geom.Max(width, uniseg.StringWidth(g.String()))
+	}
+
+	return geom.Max(width, 1)
 }
 
 func (g Glyph) Equal(other Glyph) bool {
-	return g.Char == other.Char && g.Mode == other.Mode && g.FG == other.FG && g.BG == other.BG
+	return g.Char == other.Char && g.Combining == other.Combining && g.Mode == other.Mode && g.FG == other.FG && g.BG == other.BG && g.Image.Equal(other.Image)
 }
 
 func EmptyGlyph() Glyph {
@@ -104,7 +148,7 @@ type Line []Glyph
 
 func (l Line) String() (str string) {
 	for i := 0; i < len(l); i++ {
-		str += string(l[i].Char)
+		str += l[i].String()
 		i += l[i].Width() - 1
 	}
 
@@ -189,13 +233,16 @@ func (l Line) Whitespace() (first, last int) {
 func LineFromString(text string) Line {
 	line := make(Line, 0)
 
-	for _, r := range text {
+	graphemes := uniseg.NewGraphemes(text)
+	for graphemes.Next() {
+		runes := graphemes.Runes()
 		glyph := EmptyGlyph()
-		glyph.Char = r
+		glyph.Char = runes[0]
+		glyph.Combining = string(runes[1:])
 		line = append(line, glyph)
 
 		// Handle wider characters
-		w := runewidth.RuneWidth(r)
+		w := glyph.Width()
 		if w > 1 {
 			for i := 0; i < w-1; i++ {
 				line = append(line, EmptyGlyph())
@@ -224,6 +271,12 @@ type Cursor struct {
 	Style CursorStyle
 }
 
+// Origin returns true if origin mode (DECOM) was enabled when the cursor was
+// set, which makes cursor positioning relative to the scrolling region.
+func (c Cursor) Origin() bool {
+	return c.State&cursorOrigin != 0
+}
+
 type Cell struct {
 	geom.Vec2
 	Glyph
@@ -256,9 +309,17 @@ type View interface {
 	// Mode returns the current terminal mode.
 	Mode() ModeFlag
 
+	// KeyboardFlags returns the kitty keyboard protocol enhancements
+	// currently requested by the program.
+	KeyboardFlags() KeyboardFlag
+
 	// Title represents the title of the console window.
 	Title() string
 
+	// SetTheme changes the colors the terminal reports when programs
+	// query its palette or default colors.
+	SetTheme(theme Theme)
+
 	// Cell returns the glyph containing the character code, foreground color, and
 	// background color at position (x, y) relative to the top left of the terminal.
 	Cell(x, y int) Glyph
@@ -269,11 +330,27 @@ type View interface {
 	// CursorVisible returns the visible state of the cursor.
 	CursorVisible() bool
 
+	// SavedCursor returns the cursor saved by DECSC.
+	SavedCursor() Cursor
+
+	// ScrollRegion returns the first and last rows of the scrolling
+	// region set by DECSTBM.
+	ScrollRegion() (top, bottom int)
+
 	// Screen gets all of the lines on the screen.
 	Screen() []Line
 
 	// History returns the scrollback buffer.
 	History() []Line
+	// HistoryLength returns the number of lines in the scrollback buffer.
+	// This is much cheaper than calling History.
+	HistoryLength() int
+	// TrimmedLines returns the total number of lines that have been
+	// discarded from the beginning of the scrollback buffer because of
+	// its length limit. Rows obtained before lines were discarded, such
+	// as those passed to GetLines, must be moved up by the number of
+	// lines discarded since.
+	TrimmedLines() int
 
 	IsAltMode() bool
 
@@ -319,6 +396,7 @@ type TerminalInfo struct {
 	w              io.Writer
 	cols, rows     int
 	disableHistory bool
+	scrollback     int
 }
 
 func WithWriter(w io.Writer) TerminalOption {
@@ -340,6 +418,15 @@ var WithoutHistory TerminalOption = func(info *TerminalInfo) {
 	info.disableHistory = true
 }
 
+// WithScrollback limits the scrollback buffer to the given number of lines.
+// Once the limit is reached, the oldest lines are discarded. A limit of zero
+// (the default) means the scrollback buffer can grow without bound.
+func WithScrollback(lines int) TerminalOption {
+	return func(info *TerminalInfo) {
+		info.scrollback = lines
+	}
+}
+
 // New returns a new virtual terminal emulator.
 func New(opts ...TerminalOption) Terminal {
 	info := TerminalInfo{
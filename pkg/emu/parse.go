@@ -2,17 +2,95 @@ package emu
 
 import (
 	"fmt"
+	"strings"
+
+	"github.com/cfoust/cy/pkg/geom"
+	"github.com/cfoust/cy/pkg/version"
 
 	"github.com/mattn/go-runewidth"
+	"github.com/rivo/uniseg"
 )
 
+// joinCluster attempts to append `c` to the grapheme cluster in the most
+// recently printed cell, returning true if it did so.
+func (t *State) joinCluster(c rune) bool {
+	if !t.clusterValid {
+		return false
+	}
+
+	pos := t.cluster
+	if pos.R >= len(t.screen) || pos.C >= len(t.screen[pos.R]) {
+		return false
+	}
+
+	glyph := t.screen[pos.R][pos.C]
+	cluster := glyph.String() + string(c)
+	first, _, _, _ := uniseg.FirstGraphemeClusterInString(cluster, -1)
+	if len(first) != len(cluster) {
+		return false
+	}
+
+	oldWidth := glyph.Width()
+	glyph.Combining += string(c)
+	newWidth := glyph.Width()
+
+	// The cluster became wider (for example, because of an emoji
+	// presentation selector) but there is no room for it on this line, so
+	// we drop the modifier.
+	if pos.C+newWidth > t.cols {
+		return true
+	}
+
+	t.dirty.markScreen()
+	t.markDirtyLine(pos.R)
+	t.screen[pos.R][pos.C] = glyph
+
+	for i := pos.C + oldWidth; i < pos.C+newWidth; i++ {
+		t.screen[pos.R][i] = glyph
+		t.screen[pos.R][i].Char = ' '
+		t.screen[pos.R][i].Combining = ""
+	}
+
+	t.dirty.Printed = true
+	t.dirty.Print.Vec2 = pos
+	t.dirty.Print.Glyph = glyph
+
+	if newWidth == oldWidth {
+		return true
+	}
+
+	// Move the cursor past the newly occupied cells
+	destCol := pos.C + newWidth
+	if destCol < t.cols {
+		t.moveTo(destCol, pos.R)
+	} else {
+		t.cur.State |= cursorWrapNext
+	}
+
+	t.cluster = pos
+	t.clusterValid = true
+	return true
+}
+
 func (t *State) Print(c rune) {
+	if t.joinCluster(c) {
+		return
+	}
+
+	// Zero-width runes that did not join a cluster are not printed, nor do
+	// they move the cursor
+	w := runewidth.RuneWidth(c)
+	if w == 0 {
+		return
+	}
+
 	if t.mode&ModeWrap != 0 && t.cur.State&cursorWrapNext != 0 {
-		t.screen[t.cur.R][t.cur.C].Mode |= attrWrap
+		// The cursor is on the first cell of the last glyph, which
+		// may be wide, but the wrap state belongs to the last cell
+		t.screen[t.cur.R][t.cols-1].Mode |= attrWrap
 		t.newline(true)
 	}
 
-	w := runewidth.RuneWidth(c)
 	destCol := t.cur.C + w
 
 	// TODO(cfoust): 04/03/24 this is a nasty problem, what is the expected
@@ -24,21 +102,33 @@ func (t *State) Print(c rune) {
 	// Specifically can only happen if a double-width character is printed
 	// to the final cell in a row
 	if destCol > t.cols {
-		t.screen[t.cur.R][t.cur.C].Mode |= attrWrap
+		// The final cell becomes padding, so anything in it is erased
+		t.clear(t.cur.C, t.cur.R, t.cur.C, t.cur.R)
+		t.screen[t.cur.R][t.cur.C].Mode |= attrWrap | attrPad
 		t.newline(true)
 		t.Print(c)
 		return
 	}
 
+	// IRM - shift the rest of the line to make room for the character
+	if t.mode&ModeInsert != 0 && destCol < t.cols {
+		t.insertBlanks(w)
+	}
+
+	pos := geom.Vec2{R: t.cur.R, C: t.cur.C}
 	t.setChar(c, &t.cur.Attr, t.cur.C, t.cur.R)
 	if destCol < t.cols {
 		t.moveTo(destCol, t.cur.R)
 	} else {
 		t.cur.State |= cursorWrapNext
 	}
+
+	t.cluster = pos
+	t.clusterValid = true
 }
 
 func (t *State) Execute(b byte) {
+	t.clusterValid = false
 	switch b {
 	// HT
 	case '\t':
@@ -61,14 +151,55 @@ func (t *State) Execute(b byte) {
 }
 
 func (t *State) Put(b byte) {
-	t.dirty.hookState[t.dirty.hookCount] = b
+	if t.sixel != nil {
+		if len(t.sixel.data) >= maxSixelData {
+			t.sixel = nil
+		} else {
+			t.sixel.data = append(t.sixel.data, b)
+		}
+	}
+
+	// Hooks are short, so we stop recording once the buffer is full
+	if t.dirty.hookCount < len(t.dirty.hookState) {
+		t.dirty.hookState[t.dirty.hookCount] = b
+	}
 	t.dirty.hookCount++
 	// TODO(cfoust): 08/10/23
 	//fmt.Printf("[Put] %02x\n", b)
 }
 
 func (t *State) Unhook() {
-	hook := string(t.dirty.hookState[0:t.dirty.hookCount])
+	if t.sixel != nil {
+		sixel := t.sixel
+		t.sixel = nil
+		t.handleSixel(sixel)
+	}
+
+	count := t.dirty.hookCount
+	truncated := count > len(t.dirty.hookState)
+	if truncated {
+		count = len(t.dirty.hookState)
+	}
+
+	// Hooks may be followed by parameters, as in "cy;exit=0"
+	hook, params, hasParams := strings.Cut(
+		string(t.dirty.hookState[0:count]),
+		";",
+	)
+
+	// If the buffer filled up, the hook is kept along with the
+	// parameters that fit in full
+	if truncated {
+		if !hasParams {
+			return
+		}
+
+		if last := strings.LastIndex(params, ";"); last != -1 {
+			params = params[:last]
+		} else {
+			params = ""
+		}
+	}
 
 	_, ok := t.dirty.hooks[hook]
 	if !ok {
@@ -76,25 +207,41 @@ func (t *State) Unhook() {
 	}
 
 	t.dirty.hooks[hook] = true
+	t.dirty.hookParams[hook] = params
 
 	// TODO(cfoust): 08/10/23
 	//fmt.Printf("[Unhook]\n")
 }
 
 func (t *State) Hook(params []int64, intermediates []byte, ignore bool, r rune) {
+	t.clusterValid = false
 	t.dirty.hookCount = 1
 	t.dirty.hookState[0] = byte(r)
+
+	t.sixel = nil
+	if r == 'q' && len(intermediates) == 0 && !ignore {
+		t.sixel = &sixelState{}
+	}
 	// TODO(cfoust): 08/10/23
 	//fmt.Printf("[Hook] params=%v, intermediates=%v, ignore=%v, r=%v\n", params, intermediates, ignore, r)
 }
 
 func (t *State) OscDispatch(params [][]byte, bellTerminated bool) {
-	// TODO(cfoust): 08/10/23 do we care about operating system commands?
-	// I can only find one reference to them here: https://vt100.net/docs/vt510-rm/chapter4.html
-	//fmt.Printf("[OscDispatch] params=%v, bellTerminated=%v\n", params, bellTerminated)
+	t.clusterValid = false
+
+	t.str.reset()
+	t.str.typ = ']'
+	for i, param := range params {
+		if i > 0 {
+			t.str.buf = append(t.str.buf, ';')
+		}
+		t.str.buf = append(t.str.buf, []rune(string(param))...)
+	}
+	t.handleSTR()
 }
 
 func (t *State) CsiDispatch(params []int64, intermediates []byte, ignore bool, r rune) {
+	t.clusterValid = false
 	args := make([]int, 0)
 	for _, arg := range params {
 		args = append(args, int(arg))
@@ -123,8 +270,17 @@ func (t *State) CsiDispatch(params []int64, intermediates []byte, ignore bool, r
 	case 'B', 'e': // CUD, VPR - cursor <n> down
 		t.moveTo(t.cur.C, t.cur.R+c.maxarg(0, 1))
 	case 'c': // DA - device attributes
-		if c.arg(0, 0) == 0 {
-			// TODO: write vt102 id
+		if c.arg(0, 0) != 0 {
+			break
+		}
+
+		// Every pane reports the same attributes regardless of the
+		// terminals of the clients that are attached to it
+		switch c.intermediate(0, 0) {
+		case 0: // primary: VT220 with sixel graphics
+			t.w.Write([]byte(PrimaryAttributes))
+		case '>': // secondary
+			t.w.Write([]byte(SecondaryAttributes))
 		}
 	case 'C', 'a': // CUF, HPR - cursor <n> forward
 		t.moveTo(t.cur.C+c.maxarg(0, 1), t.cur.R)
@@ -152,7 +308,8 @@ func (t *State) CsiDispatch(params []int64, intermediates []byte, ignore bool, r
 	case 'H', 'f': // CUP, HVP - move to <row> <col>
 		t.moveAbsTo(c.arg(1, 1)-1, c.arg(0, 1)-1)
 	case 'I': // CHT - cursor forward tabulation <n> tab stops
-		n := c.arg(0, 1)
+		// There can be no more tab stops than columns
+		n := min(c.arg(0, 1), t.cols)
 		for i := 0; i < n; i++ {
 			t.putTab(true)
 		}
@@ -160,28 +317,28 @@ func (t *State) CsiDispatch(params []int64, intermediates []byte, ignore bool, r
 		// TODO: sel.ob.x = -1
 		switch c.arg(0, 0) {
 		case 0: // below
-			t.clear(t.cur.C, t.cur.R, t.cols-1, t.cur.R)
+			t.erase(t.cur.C, t.cur.R, t.cols-1, t.cur.R)
 			if t.cur.R < t.rows-1 {
-				t.clear(0, t.cur.R+1, t.cols-1, t.rows-1)
+				t.erase(0, t.cur.R+1, t.cols-1, t.rows-1)
 			}
 		case 1: // above
-			if t.cur.R > 1 {
-				t.clear(0, 0, t.cols-1, t.cur.R-1)
+			if t.cur.R > 0 {
+				t.erase(0, 0, t.cols-1, t.cur.R-1)
 			}
-			t.clear(0, t.cur.R, t.cur.C, t.cur.R)
+			t.erase(0, t.cur.R, t.cur.C, t.cur.R)
 		case 2: // all
-			t.clear(0, 0, t.cols-1, t.rows-1)
+			t.erase(0, 0, t.cols-1, t.rows-1)
 		default:
 			goto unknown
 		}
 	case 'K': // EL - clear line
 		switch c.arg(0, 0) {
 		case 0: // right
-			t.clear(t.cur.C, t.cur.R, t.cols-1, t.cur.R)
+			t.erase(t.cur.C, t.cur.R, t.cols-1, t.cur.R)
 		case 1: // left
-			t.clear(0, t.cur.R, t.cur.C, t.cur.R)
+			t.erase(0, t.cur.R, t.cur.C, t.cur.R)
 		case 2: // all
-			t.clear(0, t.cur.R, t.cols-1, t.cur.R)
+			t.erase(0, t.cur.R, t.cols-1, t.cur.R)
 		}
 	case 'S': // SU - scroll <n> lines up
 		t.scrollUp(t.top, c.arg(0, 1))
@@ -194,11 +351,11 @@ func (t *State) CsiDispatch(params []int64, intermediates []byte, ignore bool, r
 	case 'M': // DL - delete <n> lines
 		t.deleteLines(c.arg(0, 1))
 	case 'X': // ECH - erase <n> chars
-		t.clear(t.cur.C, t.cur.R, t.cur.C+c.arg(0, 1)-1, t.cur.R)
+		t.erase(t.cur.C, t.cur.R, t.cur.C+c.arg(0, 1)-1, t.cur.R)
 	case 'P': // DCH - delete <n> chars
 		t.deleteChars(c.arg(0, 1))
 	case 'Z': // CBT - cursor backward tabulation <n> tab stops
-		n := c.arg(0, 1)
+		n := min(c.arg(0, 1), t.cols)
 		for i := 0; i < n; i++ {
 			t.putTab(false)
 		}
@@ -220,6 +377,13 @@ func (t *State) CsiDispatch(params []int64, intermediates []byte, ignore bool, r
 		case 6: // CPR - cursor position report
 			t.w.Write([]byte(fmt.Sprintf("\033[%d;%dR", t.cur.R+1, t.cur.C+1)))
 		}
+	case 'p':
+		// DECRQM - request mode
+		if c.priv && c.intermediate(1, 0) == '$' {
+			t.reportMode(c.arg(0, 0))
+			break
+		}
+		goto unknown
 	case 'r': // DECSTBM - set scrolling region
 		if c.priv {
 			goto unknown
@@ -229,9 +393,24 @@ func (t *State) CsiDispatch(params []int64, intermediates []byte, ignore bool, r
 		}
 	case 's': // DECSC - save cursor position (ANSI.SYS)
 		t.saveCursor()
-	case 'u': // DECRC - restore cursor position (ANSI.SYS)
-		t.restoreCursor(false)
-	case 'q': // DECSCUSR - set cursor style
+	case 'u':
+		switch c.intermediate(0, 0) {
+		case '?', '>', '<', '=': // kitty keyboard protocol
+			t.handleKeyboard(c)
+		default: // DECRC - restore cursor position (ANSI.SYS)
+			t.restoreCursor(false)
+		}
+	case 'q':
+		// XTVERSION - report terminal name and version
+		if c.intermediate(0, 0) == '>' {
+			t.w.Write([]byte(fmt.Sprintf(
+				"\033P>|cy(%s)\033\\",
+				version.Version,
+			)))
+			break
+		}
+
+		// DECSCUSR - set cursor style
 		style := CursorStyleBlock
 		switch c.arg(0, 0) {
 		case 2:
@@ -246,19 +425,54 @@ func (t *State) CsiDispatch(params []int64, intermediates []byte, ignore bool, r
 			style = CursorStyleBlinkBar
 		}
 		t.cur.Style = style
-	case 't': // XTWINOPS - window manipulation (ignored)
+	case 't': // XTWINOPS - window manipulation
+		// Only the size reports are supported, which programs use to
+		// lay out images
+		switch c.arg(0, 0) {
+		case 14: // size of the text area in pixels
+			t.w.Write([]byte(fmt.Sprintf(
+				"\033[4;%d;%dt",
+				t.rows*DefaultCellSize.R,
+				t.cols*DefaultCellSize.C,
+			)))
+		case 16: // size of a cell in pixels
+			t.w.Write([]byte(fmt.Sprintf(
+				"\033[6;%d;%dt",
+				DefaultCellSize.R,
+				DefaultCellSize.C,
+			)))
+		case 18: // size of the text area in characters
+			t.w.Write([]byte(fmt.Sprintf(
+				"\033[8;%d;%dt",
+				t.rows,
+				t.cols,
+			)))
+		}
 	case '0', '1', '2', '3', '4', '5', '6':
 	}
 	return
 
 unknown: // TODO: get rid of this goto
-	fmt.Printf("[CsiDispatch] params=%v, intermediates=%v, ignore=%v, r=%v\n", params, intermediates, ignore, r)
+	t.logf(
+		"unknown CSI sequence params=%v, intermediates=%v, ignore=%v, r=%c\n",
+		params,
+		intermediates,
+		ignore,
+		r,
+	)
 }
 
 func (t *State) EscDispatch(intermediates []byte, ignore bool, b byte) {
+	t.clusterValid = false
+
+	if len(intermediates) > 0 {
+		t.escIntermediate(intermediates[0], b)
+		return
+	}
+
 	switch b {
 	default:
-		fmt.Printf("[EscDispatch] %c intermediates=%v, ignore=%v, byte=%02x\n", b, intermediates, ignore, b)
+		t.logf("unknown ESC sequence %c (%02x)\n", b, b)
 	case 'D': // IND - linefeed
 		if t.cur.R == t.bottom {
 			t.scrollUp(t.top, 1)
@@ -288,16 +502,38 @@ func (t *State) EscDispatch(intermediates []byte, ignore bool, b byte) {
 	case '8': // DECRC - restore cursor
 		t.restoreCursor(false)
 	case '\\': // ST - stop
+	}
+}
 
-	// Character Sets (G0 and G1 Designators)
-	case '0': // line drawing set
-		t.cur.Attr.Mode |= attrGfx
-	case 'B': // USASCII
-		t.cur.Attr.Mode &^= attrGfx
-	case 'A', // UK (ignored)
-		'<', // multinational (ignored)
-		'5', // Finnish (ignored)
-		'C', // Finnish (ignored)
-		'K': // German (ignored)
+// escIntermediate handles escape sequences with an intermediate byte, such
+// as the character set designators.
+func (t *State) escIntermediate(intermediate byte, b byte) {
+	switch intermediate {
+	case '(': // G0 character set designation
+		switch b {
+		case '0': // line drawing set
+			t.cur.Attr.Mode |= attrGfx
+		case 'B': // USASCII
+			t.cur.Attr.Mode &^= attrGfx
+		case 'A', // UK (ignored)
+			'<', // multinational (ignored)
+			'5', // Finnish (ignored)
+			'C', // Finnish (ignored)
+			'K': // German (ignored)
+		default:
+			t.logf("unknown character set %c\n", b)
+		}
+	case ')', '*', '+': // G1-G3 character set designation (ignored)
+	case '#':
+		switch b {
+		case '8': // DECALN - screen alignment test
+			t.alignmentTest()
+		case '3', '4', '5', '6': // line size (ignored)
+		default:
+			t.logf("unknown ESC # sequence %c\n", b)
+		}
+	case ' ': // S7C1T, S8C1T, ANSI conformance levels (ignored)
+	default:
+		t.logf("unknown ESC sequence %c %c\n", intermediate, b)
 	}
 }
@@ -0,0 +1,308 @@
+package emu
+
+import (
+	"unicode/utf8"
+)
+
+// cellStyle contains the attributes of a Glyph that tend to be shared by
+// many cells.
+type cellStyle struct {
+	Mode        int16
+	FG, BG      Color
+	Transparent bool
+}
+
+func styleOf(glyph Glyph) cellStyle {
+	return cellStyle{
+		Mode:        glyph.Mode,
+		FG:          glyph.FG,
+		BG:          glyph.BG,
+		Transparent: glyph.Transparent,
+	}
+}
+
+// styleTable assigns an index to every distinct cellStyle so that lines in
+// the scrollback buffer can refer to styles by index rather than storing
+// them for every cell. Styles are reference counted by the runs that use
+// them so that the styles of discarded lines can be reused.
+type styleTable struct {
+	styles  []cellStyle
+	refs    []int
+	indices map[cellStyle]uint32
+	// Indices of styles that are no longer referenced by any run
+	free []uint32
+}
+
+func newStyleTable() *styleTable {
+	return &styleTable{
+		indices: make(map[cellStyle]uint32),
+	}
+}
+
+// intern gets the index of `style`, adding it to the table if necessary.
+// The style is not referenced until retain is called.
+func (s *styleTable) intern(style cellStyle) uint32 {
+	if index, ok := s.indices[style]; ok {
+		return index
+	}
+
+	var index uint32
+	if numFree := len(s.free); numFree > 0 {
+		index = s.free[numFree-1]
+		s.free = s.free[:numFree-1]
+		s.styles[index] = style
+	} else {
+		index = uint32(len(s.styles))
+		s.styles = append(s.styles, style)
+		s.refs = append(s.refs, 0)
+	}
+
+	s.indices[style] = index
+	return index
+}
+
+func (s *styleTable) retain(index uint32) {
+	s.refs[index]++
+}
+
+// release removes a reference to the style at `index`, freeing it if
+// nothing refers to it anymore.
+func (s *styleTable) release(index uint32) {
+	s.refs[index]--
+	if s.refs[index] > 0 {
+		return
+	}
+
+	delete(s.indices, s.styles[index])
+	s.free = append(s.free, index)
+}
+
+// Len returns the number of styles in use.
+func (s *styleTable) Len() int {
+	return len(s.indices)
+}
+
+// styleRun is a sequence of adjacent cells that have the same style and
+// were written by the same call to Write().
+type styleRun struct {
+	Count uint32
+	Style uint32
+	Write WriteID
+}
+
+// cellExtra holds the contents of a cell that most cells do not have.
+type cellExtra struct {
+	Col       int
+	Combining string
+	Image     *ImageCell
+}
+
+// compactLine is the representation of a Line in the scrollback buffer. The
+// characters of the line are stored as UTF-8 and its attributes are
+// run-length encoded, which for typical output takes up a small fraction of
+// the space of the equivalent []Glyph.
+type compactLine struct {
+	text   string
+	length int
+	runs   []styleRun
+	extras []cellExtra
+	// Whether the line continues onto the next line.
+	wrapped bool
+}
+
+// append adds the cells in `line` to the end of the compactLine.
+func (c *compactLine) append(styles *styleTable, line Line) {
+	// Padding is not part of the line's contents, but it carries the
+	// line's wrap state, which we store separately
+	c.wrapped = line.IsWrapped()
+	if c.wrapped && line[len(line)-1].Mode&attrPad != 0 {
+		line = line[:len(line)-1]
+	}
+
+	text := make([]byte, 0, len(c.text)+len(line))
+	text = append(text, c.text...)
+
+	for i, glyph := range line {
+		text = utf8.AppendRune(text, glyph.Char)
+
+		if len(glyph.Combining) > 0 || glyph.Image != nil {
+			c.extras = append(c.extras, cellExtra{
+				Col:       c.length + i,
+				Combining: glyph.Combining,
+				Image:     glyph.Image,
+			})
+		}
+
+		style := styles.intern(styleOf(glyph))
+		if numRuns := len(c.runs); numRuns > 0 {
+			last := &c.runs[numRuns-1]
+			if last.Style == style && last.Write == glyph.Write {
+				last.Count++
+				continue
+			}
+		}
+
+		styles.retain(style)
+		c.runs = append(c.runs, styleRun{
+			Count: 1,
+			Style: style,
+			Write: glyph.Write,
+		})
+	}
+
+	c.text = string(text)
+	c.length += len(line)
+}
+
+// release releases the styles used by the compactLine.
+func (c *compactLine) release(styles *styleTable) {
+	for _, run := range c.runs {
+		styles.release(run.Style)
+	}
+}
+
+// decode converts the compactLine back into a Line.
+func (c *compactLine) decode(styles *styleTable) Line {
+	line := make(Line, 0, c.length)
+	text := c.text
+	for _, run := range c.runs {
+		style := styles.styles[run.Style]
+		for i := uint32(0); i < run.Count; i++ {
+			char, size := utf8.DecodeRuneInString(text)
+			text = text[size:]
+			line = append(line, Glyph{
+				Char:        char,
+				Mode:        style.Mode,
+				FG:          style.FG,
+				BG:          style.BG,
+				Transparent: style.Transparent,
+				Write:       run.Write,
+			})
+		}
+	}
+
+	for _, extra := range c.extras {
+		line[extra.Col].Combining = extra.Combining
+		line[extra.Col].Image = extra.Image
+	}
+
+	if c.wrapped && len(line) > 0 {
+		line[len(line)-1].Mode |= attrWrap
+	}
+
+	return line
+}
+
+// scrollback stores the lines that have scrolled off of the top of the
+// screen. Lines are kept in a compact form and only converted back into
+// Lines when they are requested.
+type scrollback struct {
+	styles *styleTable
+	lines  []compactLine
+	// The maximum number of lines to keep. When this is exceeded, the
+	// oldest lines are discarded. Zero means there is no limit.
+	limit int
+	// The total number of lines that have been discarded.
+	trimmed int
+}
+
+func newScrollback(styles *styleTable) *scrollback {
+	return &scrollback{styles: styles}
+}
+
+// Len returns the number of lines in the scrollback buffer.
+func (s *scrollback) Len() int {
+	return len(s.lines)
+}
+
+// Line decodes the line at `index`.
+func (s *scrollback) Line(index int) Line {
+	return s.lines[index].decode(s.styles)
+}
+
+// Lines decodes every line in the scrollback buffer.
+func (s *scrollback) Lines() []Line {
+	lines := make([]Line, len(s.lines))
+	for i := range s.lines {
+		lines[i] = s.Line(i)
+	}
+	return lines
+}
+
+// IsWrapped returns true if the last line in the buffer is wrapped.
+func (s *scrollback) IsWrapped() bool {
+	if len(s.lines) == 0 {
+		return false
+	}
+
+	return s.lines[len(s.lines)-1].wrapped
+}
+
+// LastLength returns the number of cells in the last line of the buffer.
+func (s *scrollback) LastLength() int {
+	if len(s.lines) == 0 {
+		return 0
+	}
+
+	return s.lines[len(s.lines)-1].length
+}
+
+// Append adds `line` to the scrollback buffer. If the last line in the
+// buffer is wrapped, `line` is joined to it instead.
+func (s *scrollback) Append(line Line) {
+	if len(s.lines) == 0 {
+		s.push(line)
+		return
+	}
+
+	occupied := getOccupiedLine(line)
+
+	if !s.IsWrapped() {
+		s.push(occupied)
+		return
+	}
+
+	s.lines[len(s.lines)-1].append(s.styles, occupied)
+}
+
+func (s *scrollback) push(line Line) {
+	var compact compactLine
+	compact.append(s.styles, line)
+	s.lines = append(s.lines, compact)
+
+	if s.limit <= 0 || len(s.lines) <= s.limit {
+		return
+	}
+
+	numExtra := len(s.lines) - s.limit
+	// Clear out the discarded lines so that they can be garbage collected
+	// before the slice is next reallocated
+	for i := range s.lines[:numExtra] {
+		s.lines[i].release(s.styles)
+		s.lines[i] = compactLine{}
+	}
+	s.lines = s.lines[numExtra:]
+	s.trimmed += numExtra
+}
+
+// SetLimit changes the maximum number of lines in the scrollback buffer.
+func (s *scrollback) SetLimit(limit int) {
+	s.limit = limit
+
+	if limit <= 0 || len(s.lines) <= limit {
+		return
+	}
+
+	numExtra := len(s.lines) - limit
+	for i := range s.lines[:numExtra] {
+		s.lines[i].release(s.styles)
+	}
+	s.lines = append([]compactLine(nil), s.lines[numExtra:]...)
+	s.trimmed += numExtra
+}
+
+// Trimmed returns the total number of lines that have been discarded from
+// the beginning of the scrollback buffer.
+func (s *scrollback) Trimmed() int {
+	return s.trimmed
+}
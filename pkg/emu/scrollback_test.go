@@ -0,0 +1,95 @@
+package emu
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/cfoust/cy/pkg/geom"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompactLine(t *testing.T) {
+	styles := newStyleTable()
+
+	line := LineFromString("héllo wörld")
+	line[0].FG = Color(3)
+	line[1].Combining = "́"
+	line[2].Mode = attrBold
+	line[2].Write = 2
+	line[3].Image = &ImageCell{Offset: geom.Vec2{C: 1}}
+
+	var compact compactLine
+	compact.append(styles, line)
+	require.Equal(t, line, compact.decode(styles))
+	require.Len(t, compact.runs, 4)
+
+	compact.append(styles, line)
+	require.Equal(t, append(line.Clone(), line...), compact.decode(styles))
+}
+
+func TestScrollbackLimit(t *testing.T) {
+	term := New(
+		WithSize(geom.Vec2{R: 3, C: 10}),
+		WithScrollback(5),
+	)
+	term.Write([]byte(LineFeedMode))
+
+	for i := 0; i < 20; i++ {
+		term.Write([]byte(fmt.Sprintf("line %d\n", i)))
+	}
+
+	history := term.History()
+	require.Len(t, history, 5)
+	require.Equal(t, 5, term.HistoryLength())
+	require.Equal(t, "line 13", history[0].String())
+	require.Equal(t, "line 17", history[4].String())
+
+	lines := term.GetLines(0, 1)
+	require.Len(t, lines, 2)
+	require.Equal(t, "line 13", lines[0].String())
+
+	result := term.Flow(geom.Vec2{R: 2, C: 4}, geom.Vec2{R: 1})
+	require.True(t, result.OK)
+	require.Equal(t, "line", result.Lines[0].Chars.String())
+	require.Equal(t, " 14", result.Lines[1].Chars.String())
+}
+
+func TestScrollbackStyles(t *testing.T) {
+	term := New(
+		WithSize(geom.Vec2{R: 2, C: 10}),
+		WithScrollback(2),
+	)
+	term.Write([]byte(LineFeedMode))
+
+	// Every line has a different color, but styles used only by discarded
+	// lines are released
+	for i := 0; i < 200; i++ {
+		term.Write([]byte(fmt.Sprintf("\033[38;5;%dmline\n", i)))
+	}
+
+	state := term.(*terminal)
+	require.Equal(t, 197, state.TrimmedLines())
+	require.LessOrEqual(t, state.history.styles.Len(), 4)
+	require.LessOrEqual(t, len(state.history.styles.styles), 4)
+
+	history := term.History()
+	require.Len(t, history, 2)
+	require.Equal(t, Color(197), history[0][0].FG)
+	require.Equal(t, Color(198), history[1][0].FG)
+}
+
+func TestScrollbackWrapped(t *testing.T) {
+	term := New(
+		WithSize(geom.Vec2{R: 2, C: 4}),
+		WithScrollback(2),
+	)
+	term.Write([]byte(LineFeedMode))
+	term.Write([]byte("foobarbaz\nqux\n"))
+
+	// Wrapped lines count as a single line in the scrollback buffer
+	history := term.History()
+	require.Len(t, history, 1)
+	require.Equal(t, "foobarbaz", history[0].String())
+	require.Equal(t, geom.Vec2{R: 1}, term.Root())
+}
@@ -0,0 +1,260 @@
+package emu
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/cfoust/cy/pkg/geom"
+)
+
+// This file implements a decoder for sixel graphics, which programs send
+// inside of a Device Control String beginning with `ESC P ... q`.
+// See: https://vt100.net/docs/vt3xx-gp/chapter14.html
+
+// The maximum number of bytes of sixel data the terminal will buffer.
+const maxSixelData = 32 * 1024 * 1024
+
+// The number of color registers available to sixel images.
+const sixelRegisters = 256
+
+// The default VT340 color palette.
+var sixelPalette = [16]color.RGBA{
+	{0, 0, 0, 255},
+	{51, 51, 204, 255},
+	{204, 36, 36, 255},
+	{51, 204, 51, 255},
+	{204, 51, 204, 255},
+	{51, 204, 204, 255},
+	{204, 204, 51, 255},
+	{120, 120, 120, 255},
+	{69, 69, 69, 255},
+	{87, 87, 153, 255},
+	{153, 69, 69, 255},
+	{87, 153, 87, 255},
+	{153, 87, 153, 255},
+	{87, 153, 153, 255},
+	{153, 153, 87, 255},
+	{204, 204, 204, 255},
+}
+
+type sixelState struct {
+	data []byte
+}
+
+// readSixelNumber parses a decimal number at `data[i:]`, returning the number
+// and the index of the first byte after it.
+func readSixelNumber(data []byte, i int) (value, next int) {
+	for i < len(data) && data[i] >= '0' && data[i] <= '9' {
+		if value < 1<<20 {
+			value = value*10 + int(data[i]-'0')
+		}
+		i++
+	}
+	return value, i
+}
+
+// readSixelParams parses a list of semicolon-separated numbers.
+func readSixelParams(data []byte, i int) (params []int, next int) {
+	for {
+		var value int
+		value, i = readSixelNumber(data, i)
+		params = append(params, value)
+		if i >= len(data) || data[i] != ';' {
+			return params, i
+		}
+		i++
+	}
+}
+
+func hueToRGB(m1, m2, h float64) float64 {
+	for h < 0 {
+		h += 360
+	}
+	for h >= 360 {
+		h -= 360
+	}
+
+	switch {
+	case h < 60:
+		return m1 + (m2-m1)*h/60
+	case h < 180:
+		return m2
+	case h < 240:
+		return m1 + (m2-m1)*(240-h)/60
+	}
+	return m1
+}
+
+// sixelHLS converts a color in the sixel HLS color space, where blue has a
+// hue of 0, into RGB.
+func sixelHLS(h, l, s int) color.RGBA {
+	lf := float64(l) / 100
+	sf := float64(s) / 100
+
+	if sf == 0 {
+		v := uint8(lf * 255)
+		return color.RGBA{v, v, v, 255}
+	}
+
+	var m2 float64
+	if lf <= 0.5 {
+		m2 = lf * (1 + sf)
+	} else {
+		m2 = lf + sf - lf*sf
+	}
+	m1 := 2*lf - m2
+
+	// sixel hues are rotated 120 degrees from the standard model
+	hue := float64(h) + 240
+	return color.RGBA{
+		R: uint8(hueToRGB(m1, m2, hue+120) * 255),
+		G: uint8(hueToRGB(m1, m2, hue) * 255),
+		B: uint8(hueToRGB(m1, m2, hue-120) * 255),
+		A: 255,
+	}
+}
+
+func percentToByte(value int) uint8 {
+	if value > 100 {
+		value = 100
+	}
+	return uint8((value*255 + 50) / 100)
+}
+
+// walkSixel interprets sixel data, calling `paint` for every pixel that is
+// set.
+func walkSixel(data []byte, paint func(x, y int, c color.RGBA)) (width, height int) {
+	var palette [sixelRegisters]color.RGBA
+	for i := range palette {
+		palette[i] = sixelPalette[i%len(sixelPalette)]
+	}
+
+	var (
+		x, y    int
+		current = palette[0]
+	)
+
+	for i := 0; i < len(data); {
+		b := data[i]
+		switch {
+		case b == '"':
+			// Raster attributes: Pan;Pad;Ph;Pv
+			var params []int
+			params, i = readSixelParams(data, i+1)
+			if len(params) >= 4 {
+				width = max(width, min(params[2], maxImageDimension))
+				height = max(height, min(params[3], maxImageDimension))
+			}
+			continue
+		case b == '#':
+			var params []int
+			params, i = readSixelParams(data, i+1)
+			register := params[0] % sixelRegisters
+			if len(params) >= 5 {
+				switch params[1] {
+				case 1:
+					palette[register] = sixelHLS(
+						params[2],
+						params[3],
+						params[4],
+					)
+				case 2:
+					palette[register] = color.RGBA{
+						percentToByte(params[2]),
+						percentToByte(params[3]),
+						percentToByte(params[4]),
+						255,
+					}
+				}
+			}
+			current = palette[register]
+			continue
+		case b == '!':
+			var count int
+			count, i = readSixelNumber(data, i+1)
+			if i >= len(data) {
+				continue
+			}
+
+			b = data[i]
+			i++
+			if b < '?' || b > '~' {
+				continue
+			}
+
+			count = min(count, maxImageDimension)
+			for j := 0; j < count; j++ {
+				paintSixel(x, y, b-'?', current, paint)
+				x++
+			}
+			width = max(width, min(x, maxImageDimension))
+			height = max(height, min(y+6, maxImageDimension))
+			continue
+		case b == '$':
+			x = 0
+		case b == '-':
+			x = 0
+			y += 6
+		case b >= '?' && b <= '~':
+			paintSixel(x, y, b-'?', current, paint)
+			x++
+			width = max(width, min(x, maxImageDimension))
+			height = max(height, min(y+6, maxImageDimension))
+		}
+		i++
+	}
+
+	return
+}
+
+func paintSixel(x, y int, bits byte, c color.RGBA, paint func(x, y int, c color.RGBA)) {
+	if x >= maxImageDimension {
+		return
+	}
+
+	for i := 0; i < 6; i++ {
+		if bits&(1<<i) == 0 || y+i >= maxImageDimension {
+			continue
+		}
+		paint(x, y+i, c)
+	}
+}
+
+// decodeSixel decodes sixel data into an image. Pixels that are not painted
+// are left transparent.
+func decodeSixel(data []byte) image.Image {
+	width, height := walkSixel(
+		data,
+		func(x, y int, c color.RGBA) {},
+	)
+	if width == 0 || height == 0 {
+		return nil
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	walkSixel(data, func(x, y int, c color.RGBA) {
+		img.SetRGBA(x, y, c)
+	})
+	return img
+}
+
+// handleSixel decodes the sixel image received in a DCS sequence and draws
+// it on the screen. Afterwards the cursor is placed on the line below the
+// image.
+func (t *State) handleSixel(sixel *sixelState) {
+	pixels := decodeSixel(sixel.data)
+	if pixels == nil {
+		return
+	}
+
+	bounds := pixels.Bounds()
+	img := newImage(pixels, cellsFor(geom.Vec2{
+		R: bounds.Dy(),
+		C: bounds.Dx(),
+	}))
+
+	col := t.cur.C
+	t.placeImage(img)
+	t.newline(false)
+	t.moveTo(col, t.cur.R)
+}
@@ -1,6 +1,7 @@
 package emu
 
 import (
+	"fmt"
 	"io"
 	"log"
 
@@ -24,6 +25,9 @@ const (
 	attrBlink
 	attrWrap
 	attrBlank
+	// attrPad marks the cell at the end of a row that was left empty
+	// because the wide character after it did not fit
+	attrPad
 )
 
 // State represents the terminal emulation state. Use Lock/Unlock
@@ -36,7 +40,7 @@ type State struct {
 	cols, rows int
 
 	screen, altScreen   []Line
-	history, altHistory []Line
+	history, altHistory *scrollback
 	// Whether the last cell of history _continues to wrap onto the screen_.
 	// This is only used when `disableHistory` is true, since we still need
 	// the wrapping behavior to be correct.
@@ -50,6 +54,29 @@ type State struct {
 	tabs          []bool
 	title         string
 	colorOverride map[Color]Color
+	// The colors reported to programs that query the terminal's palette.
+	theme Theme
+
+	// The stacks of kitty keyboard protocol flags for the main and
+	// alternate screens.
+	keyFlags, altKeyFlags []KeyboardFlag
+
+	// The location of the most recently printed cell, which is where
+	// subsequent combining characters are appended. Only valid until
+	// anything other than a printable character is received.
+	cluster      geom.Vec2
+	clusterValid bool
+
+	// The sixel image currently being received, if any.
+	sixel *sixelState
+	// Images transmitted with the kitty graphics protocol, indexed by
+	// their IDs.
+	images     map[uint32]*Image
+	imageOrder []uint32
+	// The kitty graphics command that is still receiving data.
+	transfer *kittyTransfer
+	// The APC string currently being received.
+	apc apcState
 
 	dirty *Dirty
 
@@ -60,12 +87,16 @@ type State struct {
 }
 
 func newState(w io.Writer) *State {
+	styles := newStyleTable()
 	t := &State{
 		w:             w,
+		history:       newScrollback(styles),
+		altHistory:    newScrollback(styles),
 		colorOverride: make(map[Color]Color),
 		dirty: &Dirty{
-			hooks:     make(map[string]bool),
-			hookState: make([]byte, 256),
+			hooks:      make(map[string]bool),
+			hookParams: make(map[string]string),
+			hookState:  make([]byte, 1024),
 		},
 	}
 
@@ -125,6 +156,20 @@ func (t *State) CursorVisible() bool {
 	return t.mode&ModeHide == 0
 }
 
+// SavedCursor returns the cursor saved by DECSC.
+func (t *State) SavedCursor() Cursor {
+	t.RLock()
+	defer t.RUnlock()
+	return t.curSaved
+}
+
+// ScrollRegion returns the first and last rows of the scrolling region.
+func (t *State) ScrollRegion() (top, bottom int) {
+	t.RLock()
+	defer t.RUnlock()
+	return t.top, t.bottom
+}
+
 // Mode returns the current terminal mode.
 func (t *State) Mode() ModeFlag {
 	t.RLock()
@@ -132,6 +177,16 @@ func (t *State) Mode() ModeFlag {
 	return t.mode
 }
 
+// KeyboardFlags returns the kitty keyboard protocol flags in effect.
+func (t *State) KeyboardFlags() KeyboardFlag {
+	t.RLock()
+	defer t.RUnlock()
+	if len(t.keyFlags) == 0 {
+		return 0
+	}
+	return t.keyFlags[len(t.keyFlags)-1]
+}
+
 // Title returns the current title set via the tty.
 func (t *State) Title() string {
 	t.RLock()
@@ -139,6 +194,12 @@ func (t *State) Title() string {
 	return t.title
 }
 
+func (t *State) SetTheme(theme Theme) {
+	t.Lock()
+	defer t.Unlock()
+	t.theme = theme
+}
+
 /*
 // ChangeMask returns a bitfield of changes that have occured by VT.
 func (t *State) ChangeMask() ChangeFlag {
@@ -220,12 +281,19 @@ func (t *State) setChar(c rune, attr *Glyph, x, y int) {
 	t.dirty.markScreen()
 	t.markDirtyLine(y)
 
+	// The padding at the end of the previous row only makes sense if the
+	// line continues with a wide character
+	if x == 0 && y > 0 && w < 2 {
+		t.screen[y-1][t.cols-1].Mode &^= attrPad
+	}
+
 	t.dirty.Printed = false
 	t.dirty.Print.R = y
 	t.dirty.Print.C = x
 
 	for i := x; i < len(t.screen[y]) && i < x+w; i++ {
 		t.screen[y][i] = *attr
+		t.screen[y][i].Combining = ""
 		// Every explicit character change means cell is no longer
 		// blank (important for wrapping)
 		t.screen[y][i].Mode &= ^attrBlank
@@ -270,6 +338,8 @@ func (t *State) reset() {
 	t.top = 0
 	t.bottom = t.rows - 1
 	t.mode = ModeWrap
+	t.keyFlags = nil
+	t.altKeyFlags = nil
 	t.clear(0, 0, t.cols-1, t.rows-1)
 	t.moveTo(0, 0)
 }
@@ -286,15 +356,18 @@ func (t *State) resize(size geom.Vec2) {
 		return
 	}
 
+	t.clusterValid = false
+
 	// Get rid of any wrapped lines (kitty does this too)
 	// TODO(cfoust): 02/28/24 what about in the alt screen?
-	for isWrappedLines(t.history) || (t.disableHistory && t.wrapped) {
+	for t.history.IsWrapped() || (t.disableHistory && t.wrapped) {
 		t.scrollUp(0, 1)
+		// The cursor moves with the line it was on
+		t.cur.R = max(t.cur.R-1, 0)
 	}
 
 	tabs := t.tabs
 	screen, altScreen := t.screen, t.altScreen
-	history, altHistory := t.history, t.altHistory
 	t.screen = make([]Line, rows)
 	t.altScreen = make([]Line, rows)
 	t.dirty.Lines = make(map[int]bool, rows)
@@ -319,14 +392,14 @@ func (t *State) resize(size geom.Vec2) {
 			oldScreen  = screen
 			newScreen  = t.screen
 			oldCursor  = t.cur
-			newHistory = history
+			newHistory = t.history
 		)
 		wrapped := false
 		if IsAltMode(t.mode) {
 			oldScreen = altScreen
 			newScreen = t.altScreen
 			oldCursor = t.curSaved
-			newHistory = altHistory
+			newHistory = t.altHistory
 		}
 
 		newLines, newCursor, cursorValid := reflow(oldScreen, oldCursor, cols)
@@ -338,19 +411,10 @@ func (t *State) resize(size geom.Vec2) {
 				if t.disableHistory {
 					continue
 				}
-				newHistory = appendWrapped(
-					newHistory,
-					newLines[i],
-				)
+				newHistory.Append(newLines[i])
 			}
 		}
 
-		if IsAltMode(t.mode) {
-			t.altHistory = newHistory
-		} else {
-			t.history = newHistory
-		}
-
 		if t.disableHistory {
 			if IsAltMode(t.mode) {
 				t.altWrapped = wrapped
@@ -428,10 +492,57 @@ func (t *State) clear(x0, y0, x1, y1 int) {
 	}
 }
 
+// erase clears the given region in response to a request from the
+// program. Rows that become completely empty are no longer treated as the
+// continuation of the rows that wrapped onto them, since the program has
+// removed whatever was there.
+func (t *State) erase(x0, y0, x1, y1 int) {
+	t.clear(x0, y0, x1, y1)
+
+	top := clamp(min(y0, y1), 0, t.rows-1)
+	bottom := clamp(max(y0, y1), 0, t.rows-1)
+	for y := bottom; y >= top; y-- {
+		for row := y; row > 0; row-- {
+			line := t.screen[row]
+			if line.Length() != 0 || line.IsWrapped() || !t.screen[row-1].IsWrapped() {
+				break
+			}
+
+			t.unwrapRow(row - 1)
+		}
+	}
+}
+
 func (t *State) clearAll() {
 	t.clear(0, 0, t.cols-1, t.rows-1)
 }
 
+// repair ensures that the scrolling region and the cursor are within the
+// bounds of the screen.
+func (t *State) repair() {
+	t.clusterValid = false
+	t.setScroll(t.top, t.bottom)
+	t.moveTo(t.cur.C, t.cur.R)
+	t.curSaved.C = clamp(t.curSaved.C, 0, t.cols-1)
+	t.curSaved.R = clamp(t.curSaved.R, 0, t.rows-1)
+}
+
+// alignmentTest fills the screen with Es, resets the scrolling region, and
+// moves the cursor to the top left corner (DECALN).
+func (t *State) alignmentTest() {
+	t.setScroll(0, t.rows-1)
+	t.cur.State &^= cursorOrigin
+
+	attr := t.defaultCursor().Attr
+	for y := 0; y < t.rows; y++ {
+		for x := 0; x < t.cols; x++ {
+			t.setChar('E', &attr, x, y)
+		}
+	}
+
+	t.moveTo(0, 0)
+}
+
 func (t *State) moveAbsTo(x, y int) {
 	if t.cur.State&cursorOrigin != 0 {
 		y += t.top
@@ -459,6 +570,7 @@ func (t *State) moveTo(x, y int) {
 func (t *State) swapScreen() {
 	t.screen, t.altScreen = t.altScreen, t.screen
 	t.history, t.altHistory = t.altHistory, t.history
+	t.keyFlags, t.altKeyFlags = t.altKeyFlags, t.keyFlags
 	t.mode ^= ModeAltScreen
 	t.dirtyAll()
 }
@@ -514,6 +626,15 @@ func IsAltMode(mode ModeFlag) bool {
 	return (mode & ModeAltScreen) != 0
 }
 
+// unwrapRow marks the line at `row` as not continuing onto the next row.
+func (t *State) unwrapRow(row int) {
+	if row < 0 || row >= t.rows || t.cols == 0 {
+		return
+	}
+
+	t.screen[row][t.cols-1].Mode &^= attrWrap | attrPad
+}
+
 func (t *State) scrollDown(orig, n int) {
 	n = clamp(n, 0, t.bottom-orig+1)
 
@@ -525,6 +646,13 @@ func (t *State) scrollDown(orig, n int) {
 		t.markDirtyLine(i - n)
 	}
 
+	// The lines that used to follow these are no longer continuations of
+	// them
+	if n > 0 {
+		t.unwrapRow(orig - 1)
+		t.unwrapRow(t.bottom)
+	}
+
 	t.dirty.Scrolled = true
 	t.dirty.Scroll = Scroll{
 		Up:     false,
@@ -542,7 +670,7 @@ func (t *State) scrollUp(orig, n int) {
 			if t.disableHistory {
 				continue
 			}
-			t.history = appendWrapped(t.history, t.screen[i])
+			t.history.Append(t.screen[i])
 		}
 	}
 
@@ -554,6 +682,12 @@ func (t *State) scrollUp(orig, n int) {
 		t.markDirtyLine(i + n)
 	}
 
+	// Scrolling a region that does not start at the top of the screen
+	// separates the line above it from the line it wrapped onto
+	if n > 0 && orig > 0 {
+		t.unwrapRow(orig - 1)
+	}
+
 	t.dirty.Scrolled = true
 	t.dirty.Scroll = Scroll{
 		Up:     true,
@@ -624,6 +758,10 @@ func (t *State) setMode(priv bool, set bool, args []int) {
 				t.modMode(set, ModeMouseSgr)
 			case 1034:
 				t.modMode(set, Mode8bit)
+			case 2004: // bracketed paste
+				t.modMode(set, ModeBracketedPaste)
+			case 2026: // synchronized update
+				t.modMode(set, ModeSyncUpdate)
 			case 1049, // = 1047 and 1048
 				47, 1047:
 				alt := t.mode&ModeAltScreen != 0
@@ -684,6 +822,83 @@ func (t *State) setMode(priv bool, set bool, args []int) {
 	}
 }
 
+// privateModes maps DEC private mode numbers to the ModeFlag that
+// represents them, which is used to respond to DECRQM queries.
+var privateModes = map[int]ModeFlag{
+	1:    ModeAppCursor,
+	5:    ModeReverse,
+	7:    ModeWrap,
+	9:    ModeMouseX10,
+	1000: ModeMouseButton,
+	1002: ModeMouseMotion,
+	1003: ModeMouseMany,
+	1004: ModeFocus,
+	1006: ModeMouseSgr,
+	1049: ModeAltScreen,
+	2004: ModeBracketedPaste,
+	2026: ModeSyncUpdate,
+}
+
+// reportMode responds to a DECRQM request for the state of the private
+// mode `a`.
+func (t *State) reportMode(a int) {
+	// 0: not recognized, 1: set, 2: reset
+	state := 0
+	if a == 25 {
+		state = 2
+		if t.mode&ModeHide == 0 {
+			state = 1
+		}
+	} else if flag, ok := privateModes[a]; ok {
+		state = 2
+		if t.mode&flag != 0 {
+			state = 1
+		}
+	}
+
+	t.w.Write([]byte(fmt.Sprintf("\033[?%d;%d$y", a, state)))
+}
+
+// The maximum number of entries in the keyboard flag stack. Once this is
+// exceeded, the oldest entries are discarded.
+const maxKeyFlags = 16
+
+// handleKeyboard handles the `CSI u` sequences that make up the kitty
+// keyboard protocol.
+func (t *State) handleKeyboard(c csiEscape) {
+	current := KeyboardFlag(0)
+	if len(t.keyFlags) > 0 {
+		current = t.keyFlags[len(t.keyFlags)-1]
+	}
+
+	switch c.intermediate(0, 0) {
+	case '?': // query
+		t.w.Write([]byte(fmt.Sprintf("\033[?%du", current)))
+	case '>': // push
+		t.keyFlags = append(t.keyFlags, KeyboardFlag(c.arg(0, 0)))
+		if len(t.keyFlags) > maxKeyFlags {
+			t.keyFlags = t.keyFlags[1:]
+		}
+	case '<': // pop
+		n := clamp(c.arg(0, 1), 0, len(t.keyFlags))
+		t.keyFlags = t.keyFlags[:len(t.keyFlags)-n]
+	case '=': // set
+		flags := KeyboardFlag(c.arg(0, 0))
+		switch c.arg(1, 1) {
+		case 2:
+			flags = current | flags
+		case 3:
+			flags = current &^ flags
+		}
+
+		if len(t.keyFlags) == 0 {
+			t.keyFlags = append(t.keyFlags, flags)
+			return
+		}
+		t.keyFlags[len(t.keyFlags)-1] = flags
+	}
+}
+
 func (t *State) setAttr(attr []int) {
 	if len(attr) == 0 {
 		attr = []int{0}
@@ -729,7 +944,7 @@ func (t *State) setAttr(attr []int) {
 				if !between(r, 0, 255) || !between(g, 0, 255) || !between(b, 0, 255) {
 					t.logf("bad fg rgb color (%d,%d,%d)\n", r, g, b)
 				} else {
-					t.cur.Attr.FG = Color(r<<16 | g<<8 | b)
+					t.cur.Attr.FG = NewRGB(uint8(r), uint8(g), uint8(b))
 				}
 			} else {
 				t.logf("gfx attr %d unknown\n", a)
@@ -750,7 +965,7 @@ func (t *State) setAttr(attr []int) {
 				if !between(r, 0, 255) || !between(g, 0, 255) || !between(b, 0, 255) {
 					t.logf("bad bg rgb color (%d,%d,%d)\n", r, g, b)
 				} else {
-					t.cur.Attr.BG = Color(r<<16 | g<<8 | b)
+					t.cur.Attr.BG = NewRGB(uint8(r), uint8(g), uint8(b))
 				}
 			} else {
 				t.logf("gfx attr %d unknown\n", a)
@@ -810,10 +1025,10 @@ func (t *State) deleteChars(n int) {
 	t.markDirtyLine(t.cur.R)
 
 	if src >= t.cols {
-		t.clear(t.cur.C, t.cur.R, t.cols-1, t.cur.R)
+		t.erase(t.cur.C, t.cur.R, t.cols-1, t.cur.R)
 	} else {
 		copy(t.screen[t.cur.R][dst:dst+size], t.screen[t.cur.R][src:src+size])
-		t.clear(t.cols-n, t.cur.R, t.cols-1, t.cur.R)
+		t.erase(t.cols-n, t.cur.R, t.cols-1, t.cur.R)
 	}
 }
 
@@ -828,18 +1043,18 @@ func (t *State) Root() geom.Vec2 {
 
 	_, history, _ := t.getFlowTarget()
 
-	numHistory := len(history)
+	numHistory := history.Len()
 	root := geom.Vec2{
 		R: numHistory,
 	}
 
-	if !isWrappedLines(history) {
+	if !history.IsWrapped() {
 		return root
 	}
 
 	return geom.Vec2{
 		R: numHistory - 1,
-		C: len(history[numHistory-1]),
+		C: history.LastLength(),
 	}
 }
 
@@ -867,7 +1082,20 @@ func (t *State) Screen() []Line {
 func (t *State) History() []Line {
 	t.Lock()
 	defer t.Unlock()
-	return t.history
+	return t.history.Lines()
+}
+
+func (t *State) HistoryLength() int {
+	t.Lock()
+	defer t.Unlock()
+	return t.history.Len()
+}
+
+func (t *State) TrimmedLines() int {
+	t.RLock()
+	defer t.RUnlock()
+	_, history, _ := t.getFlowTarget()
+	return history.Trimmed()
 }
 
 func (t *State) IsAltMode() bool {
@@ -883,6 +1111,7 @@ func (t *State) String() string {
 		for x := 0; x < t.cols; x++ {
 			attr := t.Cell(x, y)
 			view = append(view, attr.Char)
+			view = append(view, []rune(attr.Combining)...)
 		}
 		view = append(view, '\n')
 	}
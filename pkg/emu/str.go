@@ -69,48 +69,31 @@ func (t *State) handleSTR() {
 			if title != "" {
 				t.setTitle(title)
 			}
-		case 10:
+		case 10, 11, 12:
 			if len(s.args) < 2 {
 				break
 			}
 
 			c := s.argString(1, "")
-			p := &c
-			if p != nil && *p == "?" {
-				t.oscColorResponse(int(DefaultFG), 10)
-			} else if err := t.setColorName(int(DefaultFG), p); err != nil {
-				t.logf("invalid foreground color: %s\n", maybe(p))
-			} else {
-				// TODO: redraw
-			}
-		case 11:
-			if len(s.args) < 2 {
-				break
+			color := DefaultFG
+			switch d {
+			case 11:
+				color = DefaultBG
+			case 12:
+				color = DefaultCursor
 			}
 
-			c := s.argString(1, "")
-			p := &c
-			if p != nil && *p == "?" {
-				t.oscColorResponse(int(DefaultBG), 11)
-			} else if err := t.setColorName(int(DefaultBG), p); err != nil {
-				t.logf("invalid cursor color: %s\n", maybe(p))
-			} else {
-				// TODO: redraw
+			if c == "?" {
+				t.oscColorResponse(color, d)
+			} else if err := t.setColorName(color, &c); err != nil {
+				t.logf("invalid color for OSC %d: %s\n", d, c)
 			}
-		// case 12:
-		// if len(s.args) < 2 {
-		// 	break
-		// }
-
-		// c := s.argString(1, "")
-		// p := &c
-		// if p != nil && *p == "?" {
-		// 	t.oscColorResponse(int(DefaultCursor), 12)
-		// } else if err := t.setColorName(int(DefaultCursor), p); err != nil {
-		// 	t.logf("invalid background color: %s\n", p)
-		// } else {
-		// 	// TODO: redraw
-		// }
+		case 110, 111, 112: // reset default colors
+			delete(t.colorOverride, []Color{
+				DefaultFG,
+				DefaultBG,
+				DefaultCursor,
+			}[d-110])
 		case 4: // color set
 			if len(s.args) < 3 {
 				break
@@ -120,18 +103,27 @@ func (t *State) handleSTR() {
 			p = &c
 			fallthrough
 		case 104: // color reset
-			j := -1
-			if len(s.args) > 1 {
-				j = s.arg(1, 0)
+			// Resetting without any arguments resets the entire
+			// palette
+			if len(s.args) <= 1 {
+				for color := range t.colorOverride {
+					if color.IsPalette() {
+						delete(t.colorOverride, color)
+					}
+				}
+				break
+			}
+
+			j := s.arg(1, -1)
+			if !between(j, 0, 255) {
+				t.logf("invalid color j=%d\n", j)
+				break
 			}
+
 			if p != nil && *p == "?" { // report
 				t.osc4ColorResponse(j)
-			} else if err := t.setColorName(j, p); err != nil {
-				if !(d == 104 && len(s.args) <= 1) {
-					t.logf("invalid color j=%d, p=%s\n", j, maybe(p))
-				}
-			} else {
-				// TODO: redraw
+			} else if err := t.setColorName(Color(j), p); err != nil {
+				t.logf("invalid color j=%d, p=%s\n", j, maybe(p))
 			}
 		default:
 			t.logf("unknown OSC command %d\n", d)
@@ -153,65 +145,59 @@ func (t *State) handleSTR() {
 	}
 }
 
-func (t *State) setColorName(j int, p *string) error {
-	if !between(j, 0, 1<<24) {
-		return fmt.Errorf("invalid color value %d", j)
-	}
-
+func (t *State) setColorName(color Color, p *string) error {
 	if p == nil {
 		// restore color
-		delete(t.colorOverride, Color(j))
-	} else {
-		// set color
-		r, g, b, err := parseColor(*p)
-		if err != nil {
-			return err
-		}
-		t.colorOverride[Color(j)] = Color(r<<16 | g<<8 | b)
+		delete(t.colorOverride, color)
+		return nil
+	}
+
+	// set color
+	rgb, err := ParseColor(*p)
+	if err != nil {
+		return err
 	}
+	t.colorOverride[color] = rgb
 
 	return nil
 }
 
-func (t *State) oscColorResponse(j, num int) {
-	if j < 0 {
-		t.logf("failed to fetch osc color %d\n", j)
-		return
+// resolveColor returns the RGB color that `color` is displayed as, which
+// is used to answer queries for the values of colors.
+func (t *State) resolveColor(color Color) Color {
+	if override, ok := t.colorOverride[color]; ok {
+		return override
 	}
 
-	k, ok := t.colorOverride[Color(j)]
-	if ok {
-		j = int(k)
-	}
+	return t.theme.Resolve(color)
+}
 
-	r, g, b := rgb(j)
+func (t *State) oscColorResponse(color Color, num int) {
+	r, g, b := t.resolveColor(color).RGB()
 	t.w.Write([]byte(fmt.Sprintf("\033]%d;rgb:%02x%02x/%02x%02x/%02x%02x\007", num, r, r, g, g, b, b)))
 }
 
 func (t *State) osc4ColorResponse(j int) {
-	if j < 0 {
-		t.logf("failed to fetch osc4 color %d\n", j)
-		return
-	}
-
-	k, ok := t.colorOverride[Color(j)]
-	if ok {
-		j = int(k)
-	}
-
-	r, g, b := rgb(j)
+	r, g, b := t.resolveColor(Color(j)).RGB()
 	t.w.Write([]byte(fmt.Sprintf("\033]4;%d;rgb:%02x%02x/%02x%02x/%02x%02x\007", j, r, r, g, g, b, b)))
 }
 
-func rgb(j int) (r, g, b int) {
-	return (j >> 16) & 0xff, (j >> 8) & 0xff, j & 0xff
-}
-
 var (
 	RGBPattern  = regexp.MustCompile(`^([\da-f]{1})\/([\da-f]{1})\/([\da-f]{1})$|^([\da-f]{2})\/([\da-f]{2})\/([\da-f]{2})$|^([\da-f]{3})\/([\da-f]{3})\/([\da-f]{3})$|^([\da-f]{4})\/([\da-f]{4})\/([\da-f]{4})$`)
 	HashPattern = regexp.MustCompile(`[\da-f]`)
 )
 
+// ParseColor parses a color specification in one of the forms accepted by
+// XParseColor, such as "#ff0000" or "rgb:ff/00/00", into an RGB Color.
+func ParseColor(spec string) (Color, error) {
+	r, g, b, err := parseColor(spec)
+	if err != nil {
+		return 0, err
+	}
+
+	return NewRGB(uint8(r), uint8(g), uint8(b)), nil
+}
+
 func parseColor(p string) (r, g, b int, err error) {
 	if len(p) == 0 {
 		err = fmt.Errorf("empty color spec")
@@ -15,6 +15,8 @@ func newTerminal(info TerminalInfo) *terminal {
 	t := &terminal{newState(info.w)}
 	t.init(geom.Size{C: info.cols, R: info.rows})
 	t.disableHistory = info.disableHistory
+	t.history.SetLimit(info.scrollback)
+	t.altHistory.SetLimit(info.scrollback)
 	return t
 }
 
@@ -29,8 +31,30 @@ func (t *terminal) init(size geom.Size) {
 func (t *terminal) Parse(p []byte) (written int) {
 	t.dirty.writeId++
 
+	for written < len(p) {
+		written += t.parse(p[written:])
+	}
+	return
+}
+
+// parse advances the parser until it reaches the end of `p` or processing
+// a byte panics. Output comes from untrusted programs, so a bug in the
+// handling of a single sequence should not bring down the process; instead
+// the offending byte is skipped and the state is made consistent again.
+func (t *terminal) parse(p []byte) (written int) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+
+		t.logf("recovered from panic while parsing %02x: %v\n", p[written], r)
+		t.repair()
+		written++
+	}()
+
 	for _, b := range p {
-		t.parser.Advance(b)
+		t.advance(b)
 		written++
 	}
 	return
@@ -109,6 +109,45 @@ func TestPrompt(t *testing.T) {
 	require.True(t, ok)
 }
 
+func TestPromptParams(t *testing.T) {
+	term := New()
+	dirty := term.Changes()
+	dirty.SetHooks([]string{"cy"})
+
+	_, err := term.Write([]byte("\033Pcy;exit=1;cwd=/tmp\033\\"))
+	require.NoError(t, err)
+
+	value, _ := dirty.Hook("cy")
+	require.True(t, value)
+	require.Equal(t, "exit=1;cwd=/tmp", dirty.HookParams("cy"))
+
+	dirty.Reset()
+	require.Equal(t, "", dirty.HookParams("cy"))
+
+	// Other hooks are not affected
+	_, err = term.Write([]byte("\033Pfoo;cy\033\\"))
+	require.NoError(t, err)
+	value, _ = dirty.Hook("cy")
+	require.False(t, value)
+
+	// A parameter too long to record is dropped, but the hook and the
+	// parameters before it are kept
+	dirty.Reset()
+	cwd := "/" + strings.Repeat("a", 2048)
+	_, err = term.Write([]byte("\033Pcy;exit=0;cwd=" + cwd + "\033\\"))
+	require.NoError(t, err)
+	value, _ = dirty.Hook("cy")
+	require.True(t, value)
+	require.Equal(t, "exit=0", dirty.HookParams("cy"))
+
+	dirty.Reset()
+	_, err = term.Write([]byte("\033Pcy;cwd=" + cwd + "\033\\"))
+	require.NoError(t, err)
+	value, _ = dirty.Hook("cy")
+	require.True(t, value)
+	require.Equal(t, "", dirty.HookParams("cy"))
+}
+
 func TestTabs(t *testing.T) {
 	term := New()
 	// This is the simplest example of a bug that I encountered with tabs.
@@ -119,3 +158,125 @@ func TestTabs(t *testing.T) {
 	index := strings.Index(first, "trace.prof")
 	require.NotEqual(t, -1, index)
 }
+
+func TestGraphemeClusters(t *testing.T) {
+	term := New()
+	term.Resize(geom.Vec2{C: 10, R: 3})
+	term.Write([]byte(LineFeedMode))
+
+	// Combining accent stays in the same cell
+	term.Write([]byte("cafe\u0301!"))
+	require.Equal(t, "e\u0301", term.Cell(3, 0).String())
+	require.Equal(t, '!', term.Cell(4, 0).Char)
+	require.Equal(t, "cafe\u0301!", term.Screen()[0][:5].String())
+
+	// Regional indicators become a single double-width flag
+	term.Write([]byte("\n\U0001F1FA\U0001F1F8x"))
+	require.Equal(t, "\U0001F1FA\U0001F1F8", term.Cell(0, 1).String())
+	require.Equal(t, 2, term.Cell(0, 1).Width())
+	require.Equal(t, 'x', term.Cell(2, 1).Char)
+
+	// ZWJ sequences occupy a single cell
+	term.Write([]byte("\n\U0001F468\u200D\U0001F469!"))
+	require.Equal(t, "\U0001F468\u200D\U0001F469", term.Cell(0, 2).String())
+	require.Equal(t, '!', term.Cell(2, 2).Char)
+
+	// Combining characters do not join across control sequences
+	term.Write([]byte("\033[1;1Ha\033[m\u0301"))
+	require.Equal(t, "a", term.Cell(0, 0).String())
+}
+
+func TestModeReport(t *testing.T) {
+	var out strings.Builder
+	term := New(WithWriter(&out))
+
+	term.Write([]byte("\033[?2004h\033[?2026h"))
+	require.NotEqual(t, ModeFlag(0), term.Mode()&ModeBracketedPaste)
+	require.NotEqual(t, ModeFlag(0), term.Mode()&ModeSyncUpdate)
+
+	term.Write([]byte("\033[?2026l\033[?2026$p\033[?2004$p\033[?9999$p"))
+	require.Equal(t, ModeFlag(0), term.Mode()&ModeSyncUpdate)
+	require.Equal(
+		t,
+		"\033[?2026;2$y\033[?2004;1$y\033[?9999;0$y",
+		out.String(),
+	)
+}
+
+func TestKeyboardFlags(t *testing.T) {
+	var out strings.Builder
+	term := New(WithWriter(&out))
+
+	term.Write([]byte("\033[>1u\033[>9u"))
+	require.Equal(t, KeyboardDisambiguate|KeyboardReportAll, term.KeyboardFlags())
+
+	term.Write([]byte("\033[?u"))
+	require.Equal(t, "\033[?9u", out.String())
+
+	// The alternate screen has its own stack
+	term.Write([]byte(EnterAltScreen))
+	require.Equal(t, KeyboardFlag(0), term.KeyboardFlags())
+	term.Write([]byte("\033[=2;1u"))
+	require.Equal(t, KeyboardReportEvents, term.KeyboardFlags())
+	term.Write([]byte(ExitAltScreen))
+
+	term.Write([]byte("\033[<u"))
+	require.Equal(t, KeyboardDisambiguate, term.KeyboardFlags())
+	term.Write([]byte("\033[<5u"))
+	require.Equal(t, KeyboardFlag(0), term.KeyboardFlags())
+}
+
+func TestDeviceAttributes(t *testing.T) {
+	var out strings.Builder
+	term := New(WithWriter(&out))
+
+	term.Write([]byte("\033[c\033[>c\033[0c"))
+	require.Equal(
+		t,
+		PrimaryAttributes+SecondaryAttributes+PrimaryAttributes,
+		out.String(),
+	)
+
+	out.Reset()
+	term.Write([]byte("\033[>q"))
+	require.True(t, strings.HasPrefix(out.String(), "\033P>|cy("))
+
+	// DECSCUSR still works
+	term.Write([]byte("\033[5 q"))
+	require.Equal(t, CursorStyleBar, term.Cursor().Style)
+}
+
+func TestColors(t *testing.T) {
+	var out strings.Builder
+	term := New(WithWriter(&out))
+
+	// RGB colors that look like palette indices should remain distinct
+	term.Write([]byte("\033[38;2;0;0;5;48;5;5mx"))
+	cell := term.Cell(0, 0)
+	require.True(t, cell.FG.IsRGB())
+	require.Equal(t, NewRGB(0, 0, 5), cell.FG)
+	require.Equal(t, Magenta, cell.BG)
+	require.NotEqual(t, cell.FG, cell.BG)
+
+	r, g, b := Color(196).RGB()
+	require.Equal(t, [3]uint8{0xff, 0, 0}, [3]uint8{r, g, b})
+
+	// Queries are answered using the theme
+	term.SetTheme(Theme{
+		Red:       NewRGB(0x12, 0x34, 0x56),
+		DefaultBG: NewRGB(0xab, 0xcd, 0xef),
+	})
+	term.Write([]byte("\033]4;1;?\007\033]11;?\007\033]10;?\007"))
+	require.Equal(
+		t,
+		"\033]4;1;rgb:1212/3434/5656\007"+
+			"\033]11;rgb:abab/cdcd/efef\007"+
+			"\033]10;rgb:e5e5/e5e5/e5e5\007",
+		out.String(),
+	)
+
+	// Colors set by the program take precedence
+	out.Reset()
+	term.Write([]byte("\033]4;1;#ff0000\007\033]4;1;?\007"))
+	require.Equal(t, "\033]4;1;rgb:ffff/0000/0000\007", out.String())
+}
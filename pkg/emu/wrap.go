@@ -70,12 +70,14 @@ func unwrapLines(lines []Line) (unwrapped []physicalLine) {
 	for row := 0; row < len(lines); row++ {
 		line = lines[row]
 
+		isWrapped := line.IsWrapped() && row != len(lines)-1
+
 		current = append(
 			current,
-			ScreenLine{R: row, C1: line.Length()},
+			ScreenLine{R: row, C1: wrappedLength(line, isWrapped)},
 		)
 
-		if line.IsWrapped() && row != len(lines)-1 {
+		if isWrapped {
 			continue
 		}
 
@@ -86,43 +88,33 @@ func unwrapLines(lines []Line) (unwrapped []physicalLine) {
 	return unwrapped
 }
 
-func resolveLine(lines []Line, screen physicalLine) (line Line) {
-	for _, r := range screen {
-		line = append(line, lines[r.R][r.C0:r.C1]...)
+// wrappedLength returns the number of cells in `line` that are part of its
+// physical line. Rows that wrap onto the next row occupy every cell,
+// including blank ones, except for the final cell if it was skipped because
+// a wide character did not fit.
+func wrappedLength(line Line, isWrapped bool) int {
+	if !isWrapped {
+		return line.Length()
 	}
 
-	// Remove the wrap state
-	for i := range line {
-		line[i].Mode &= ^attrWrap
+	if line[len(line)-1].Mode&attrPad != 0 {
+		return len(line) - 1
 	}
 
-	return
+	return len(line)
 }
 
-// isWrappedLines returns true if the last line in `lines` is wrapped.
-func isWrappedLines(lines []Line) bool {
-	if len(lines) == 0 {
-		return false
-	}
-
-	return lines[len(lines)-1].IsWrapped()
-}
-
-func appendWrapped(lines []Line, line Line) []Line {
-	// TODO(cfoust): 05/13/24 test this?
-	if len(lines) == 0 {
-		return append(lines, copyLine(line))
+func resolveLine(lines []Line, screen physicalLine) (line Line) {
+	for _, r := range screen {
+		line = append(line, lines[r.R][r.C0:r.C1]...)
 	}
 
-	cloned := getOccupiedLine(copyLine(line))
-
-	if !isWrappedLines(lines) {
-		return append(lines, cloned)
+	// Remove the wrap state
+	for i := range line {
+		line[i].Mode &= ^(attrWrap | attrPad)
 	}
 
-	lastIndex := len(lines) - 1
-	lines[lastIndex] = append(lines[lastIndex], cloned...)
-	return lines
+	return
 }
 
 func emptyLine(cols int) Line {
@@ -148,7 +140,7 @@ func getLineLength(line Line) int {
 }
 
 func getOccupiedLine(line Line) Line {
-	if line[len(line)-1].Mode == attrWrap {
+	if line[len(line)-1].Mode&attrWrap != 0 {
 		return line
 	}
 
@@ -314,10 +306,10 @@ func reflow(oldScreen []Line, oldCursor Cursor, cols int) (newLines []Line, newC
 	// 2. Resolve them to full []Lines for calculation purposes
 	oldResolved := resolveLines(oldScreen, oldWrapped)
 
-	// Remove trailing empty lines
+	// Remove trailing empty lines, but keep the one the cursor is on
 	for i := len(oldResolved) - 1; i >= 0; i-- {
 		line := oldResolved[i]
-		if line.Length() != 0 {
+		if line.Length() != 0 || oldWrapped[i][0].R <= oldCursor.R {
 			break
 		}
 		oldResolved = oldResolved[:i]
@@ -354,6 +346,12 @@ func reflow(oldScreen []Line, oldCursor Cursor, cols int) (newLines []Line, newC
 			// Mark wrapped
 			if i != len(physical)-1 {
 				newLine[cols-1].Mode ^= attrWrap
+
+				// The line was broken early to avoid
+				// splitting a wide character
+				if numBlank > 0 {
+					newLine[cols-1].Mode |= attrPad
+				}
 			}
 
 			newLines = append(newLines, newLine)
@@ -126,7 +126,7 @@ func Compose(pos geom.Vec2, dst, src Image) {
 				continue
 			}
 			srcCell := src[row-pos.R][col-pos.C]
-			if srcCell.Char == ' ' && srcCell.BG == emu.DefaultBG {
+			if srcCell.IsEmpty() && srcCell.BG == emu.DefaultBG {
 				continue
 			}
 			dst[row][col] = srcCell
@@ -88,6 +88,20 @@ func NormalizeRange(start, end Vec2) (newStart, newEnd Vec2) {
 	return start, end
 }
 
+// NormalizeBox returns the top-left and bottom-right corners of the
+// rectangle that has `start` and `end` as two of its corners.
+func NormalizeBox(start, end Vec2) (topLeft, bottomRight Vec2) {
+	topLeft = Vec2{
+		R: Min(start.R, end.R),
+		C: Min(start.C, end.C),
+	}
+	bottomRight = Vec2{
+		R: Max(start.R, end.R),
+		C: Max(start.C, end.C),
+	}
+	return
+}
+
 var UnitVec2 = Vec2{R: 1, C: 1}
 
 type Size = Vec2
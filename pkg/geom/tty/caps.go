@@ -0,0 +1,178 @@
+package tty
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cfoust/cy/pkg/emu"
+
+	"github.com/muesli/termenv"
+)
+
+// Capabilities describes the features supported by a client's terminal.
+type Capabilities struct {
+	// Probed is true if the capabilities were obtained by querying the
+	// terminal. Otherwise, everything other than Profile is a guess.
+	Probed bool
+	// Version is the terminal's name and version as reported by
+	// XTVERSION, such as "kitty(0.32.2)".
+	Version string
+	// Profile is the range of colors the terminal can display.
+	Profile termenv.Profile
+	// Attributes contains the primary device attributes (DA1) reported
+	// by the terminal.
+	Attributes []int
+	// SecondaryAttributes contains the secondary device attributes (DA2)
+	// reported by the terminal.
+	SecondaryAttributes []int
+
+	Sixel          bool
+	KittyGraphics  bool
+	KittyKeyboard  bool
+	BracketedPaste bool
+	SyncUpdate     bool
+}
+
+// Protocol returns the best method available for drawing images.
+func (c Capabilities) Protocol() Protocol {
+	switch {
+	case c.KittyGraphics:
+		return ProtocolKitty
+	case c.Sixel:
+		return ProtocolSixel
+	}
+
+	return ProtocolNone
+}
+
+// ProbeQuery is written to a terminal to determine its Capabilities. It
+// ends with a request for the primary device attributes, which every
+// terminal answers, so that the response to that query indicates that
+// the terminal has responded to everything it understood.
+const ProbeQuery = "\x1b[>0q" + // XTVERSION
+	"\x1b[>c" + // DA2
+	"\x1b[?u" + // kitty keyboard protocol
+	"\x1b[?2004$p" + // DECRQM bracketed paste
+	"\x1b[?2026$p" + // DECRQM synchronized output
+	"\x1b_Gi=31,s=1,v=1,a=q,t=d,f=24;AAAA\x1b\\" + // kitty graphics
+	"\x1b[c" // DA1
+
+var (
+	versionRe  = regexp.MustCompile(`\x1bP>\|([^\x1b]*)\x1b\\`)
+	da1Re      = regexp.MustCompile(`\x1b\[\?([0-9;]*)c`)
+	da2Re      = regexp.MustCompile(`\x1b\[>([0-9;]*)c`)
+	keyboardRe = regexp.MustCompile(`\x1b\[\?[0-9]*u`)
+	modeRe     = regexp.MustCompile(`\x1b\[\?([0-9]+);([0-9])\$y`)
+	// Matches a response to any of the queries in ProbeQuery
+	responseRe = regexp.MustCompile(
+		`\x1bP>\|[^\x1b]*\x1b\\|` +
+			`\x1b\[[?>][0-9;]*[cu]|` +
+			`\x1b\[\?[0-9]+;[0-9]\$y|` +
+			`\x1b_G[^\x1b]*\x1b\\`,
+	)
+)
+
+func parseAttributes(params []byte) (attributes []int) {
+	for _, param := range strings.Split(string(params), ";") {
+		value, err := strconv.Atoi(param)
+		if err != nil {
+			continue
+		}
+		attributes = append(attributes, value)
+	}
+	return
+}
+
+func hasAttribute(attributes []int, attribute int) bool {
+	for _, other := range attributes {
+		if other == attribute {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseCapabilities interprets a terminal's response to ProbeQuery. `n` is
+// the number of bytes of the response that were consumed, or -1 if the
+// response is not yet complete.
+func ParseCapabilities(response []byte) (caps Capabilities, n int) {
+	n = -1
+
+	da1 := da1Re.FindSubmatchIndex(response)
+	if da1 == nil {
+		return
+	}
+	n = da1[1]
+	response = response[:n]
+
+	caps.Probed = true
+	caps.Attributes = parseAttributes(response[da1[2]:da1[3]])
+	caps.Sixel = hasAttribute(caps.Attributes, 4)
+
+	if match := da2Re.FindSubmatch(response); match != nil {
+		caps.SecondaryAttributes = parseAttributes(match[1])
+	}
+
+	if match := versionRe.FindSubmatch(response); match != nil {
+		caps.Version = string(match[1])
+	}
+
+	caps.KittyKeyboard = keyboardRe.Match(response)
+	caps.KittyGraphics = bytes.Contains(response, []byte("\x1b_Gi=31;OK\x1b\\"))
+
+	for _, match := range modeRe.FindAllSubmatch(response, -1) {
+		// 0 means the mode is not recognized
+		supported := match[2][0] != '0'
+		switch string(match[1]) {
+		case "2004":
+			caps.BracketedPaste = supported
+		case "2026":
+			caps.SyncUpdate = supported
+		}
+	}
+
+	return
+}
+
+// TrimProbeResponses removes any responses to ProbeQuery from `data`, which
+// is input from a terminal that may have responded after Probe stopped
+// waiting for it. `done` is true if `data` contained the response to the
+// last query, after which the terminal will not send any more responses.
+func TrimProbeResponses(data []byte) (trimmed []byte, done bool) {
+	done = da1Re.Match(data)
+	trimmed = responseRe.ReplaceAll(data, nil)
+	return
+}
+
+// convertColor downgrades `color` to one that can be displayed by a
+// terminal with the given color profile. `ok` is false if the color cannot
+// be displayed at all.
+func convertColor(profile termenv.Profile, color emu.Color) (converted emu.Color, ok bool) {
+	if color == emu.DefaultFG || color == emu.DefaultBG {
+		return color, true
+	}
+
+	if profile == termenv.Ascii {
+		return color, false
+	}
+
+	var original termenv.Color = termenv.ANSI256Color(color)
+	if color.ANSI() {
+		original = termenv.ANSIColor(color)
+	} else if color.IsRGB() {
+		r, g, b := color.RGB()
+		original = termenv.RGBColor(fmt.Sprintf("#%02x%02x%02x", r, g, b))
+	}
+
+	switch c := profile.Convert(original).(type) {
+	case termenv.ANSIColor:
+		return emu.Color(c), true
+	case termenv.ANSI256Color:
+		return emu.Color(c), true
+	}
+
+	return color, true
+}
@@ -0,0 +1,85 @@
+package tty
+
+import (
+	"testing"
+
+	"github.com/cfoust/cy/pkg/emu"
+
+	"github.com/muesli/termenv"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCapabilities(t *testing.T) {
+	// A response resembling kitty's
+	response := "\x1bP>|kitty(0.32.2)\x1b\\" +
+		"\x1b[>1;4000;32c" +
+		"\x1b[?0u" +
+		"\x1b[?2004;2$y" +
+		"\x1b[?2026;2$y" +
+		"\x1b_Gi=31;OK\x1b\\"
+
+	_, n := ParseCapabilities([]byte(response))
+	require.Equal(t, -1, n)
+
+	response += "\x1b[?62;c"
+	caps, n := ParseCapabilities([]byte(response + "abc"))
+	require.Equal(t, len(response), n)
+	require.True(t, caps.Probed)
+	require.Equal(t, "kitty(0.32.2)", caps.Version)
+	require.Equal(t, []int{62}, caps.Attributes)
+	require.Equal(t, []int{1, 4000, 32}, caps.SecondaryAttributes)
+	require.True(t, caps.KittyGraphics)
+	require.True(t, caps.KittyKeyboard)
+	require.True(t, caps.BracketedPaste)
+	require.True(t, caps.SyncUpdate)
+	require.False(t, caps.Sixel)
+	require.Equal(t, ProtocolKitty, caps.Protocol())
+
+	// A terminal that only answers DA1
+	caps, _ = ParseCapabilities([]byte("\x1b[?62;4;22c"))
+	require.True(t, caps.Sixel)
+	require.False(t, caps.KittyKeyboard)
+	require.False(t, caps.BracketedPaste)
+	require.Equal(t, ProtocolSixel, caps.Protocol())
+
+	caps, _ = ParseCapabilities([]byte("\x1b[?2026;0$y\x1b[?1;2c"))
+	require.False(t, caps.SyncUpdate)
+	require.Equal(t, ProtocolNone, caps.Protocol())
+}
+
+func TestConvertColor(t *testing.T) {
+	orange := emu.NewRGB(0xff, 0x87, 0x00)
+
+	color, ok := convertColor(termenv.TrueColor, orange)
+	require.True(t, ok)
+	require.Equal(t, orange, color)
+
+	color, ok = convertColor(termenv.ANSI256, orange)
+	require.True(t, ok)
+	require.Equal(t, emu.Color(208), color)
+
+	color, ok = convertColor(termenv.ANSI, emu.Color(196))
+	require.True(t, ok)
+	require.True(t, color.ANSI())
+
+	_, ok = convertColor(termenv.Ascii, emu.Red)
+	require.False(t, ok)
+
+	color, ok = convertColor(termenv.Ascii, emu.DefaultFG)
+	require.True(t, ok)
+	require.Equal(t, emu.DefaultFG, color)
+}
+
+func TestTrimProbeResponses(t *testing.T) {
+	trimmed, done := TrimProbeResponses([]byte(
+		"a\x1b[?2026;2$y\x1b_Gi=31;OK\x1b\\b",
+	))
+	require.Equal(t, "ab", string(trimmed))
+	require.False(t, done)
+
+	trimmed, done = TrimProbeResponses([]byte(
+		"\x1bP>|kitty(0.32.2)\x1b\\\x1b[>1;4000;32c\x1b[?0u\x1b[?62;ctext\x1b[A",
+	))
+	require.Equal(t, "text\x1b[A", string(trimmed))
+	require.True(t, done)
+}
@@ -0,0 +1,426 @@
+package tty
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"fmt"
+	"image/color"
+	"math"
+
+	"github.com/cfoust/cy/pkg/emu"
+	"github.com/cfoust/cy/pkg/geom"
+	cyImage "github.com/cfoust/cy/pkg/geom/image"
+
+	"github.com/sasha-s/go-deadlock"
+	"github.com/xo/terminfo"
+)
+
+// Protocol is a method of displaying images on a terminal.
+type Protocol int
+
+const (
+	// ProtocolNone draws a placeholder in place of images.
+	ProtocolNone Protocol = iota
+	ProtocolSixel
+	ProtocolKitty
+)
+
+// Placeholder returns the glyph that is drawn as text in place of a cell
+// containing part of an image. It approximates the image using the average
+// color of the cell, which is all that terminals without image support will
+// show.
+func Placeholder(cell emu.Glyph) emu.Glyph {
+	if cell.Image == nil {
+		return cell
+	}
+
+	glyph := emu.EmptyGlyph()
+	glyph.BG = cell.Image.Image.Preview(cell.Image.Offset)
+	return glyph
+}
+
+// Placement is a region of the screen that displays a portion of an image.
+type Placement struct {
+	Image *emu.Image
+	// Offset is the cell of the image that appears in the top-left corner
+	// of Rect.
+	Offset geom.Vec2
+	// Rect is the region of the screen the image covers.
+	Rect geom.Rect
+}
+
+// FindPlacements returns all of the images visible in `i`.
+func FindPlacements(i cyImage.Image) (placements []Placement) {
+	type key struct {
+		image  *emu.Image
+		origin geom.Vec2
+	}
+
+	indices := make(map[key]int)
+	size := i.Size()
+	for row := 0; row < size.R; row++ {
+		for col := 0; col < size.C; col++ {
+			cell := i[row][col].Image
+			if cell == nil {
+				continue
+			}
+
+			pos := geom.Vec2{R: row, C: col}
+			k := key{
+				image:  cell.Image,
+				origin: pos.Sub(cell.Offset),
+			}
+
+			index, ok := indices[k]
+			if !ok {
+				indices[k] = len(placements)
+				placements = append(placements, Placement{
+					Image:  cell.Image,
+					Offset: cell.Offset,
+					Rect: geom.Rect{
+						Position: pos,
+						Size:     geom.Vec2{R: 1, C: 1},
+					},
+				})
+				continue
+			}
+
+			// Grow the placement to include this cell
+			placement := &placements[index]
+			end := placement.Rect.Position.Add(placement.Rect.Size)
+			start := placement.Rect.Position
+			start.C = geom.Min(start.C, col)
+			end.C = geom.Max(end.C, col+1)
+			end.R = geom.Max(end.R, row+1)
+			placement.Rect = geom.Rect{
+				Position: start,
+				Size:     end.Sub(start),
+			}
+			placement.Offset = start.Sub(k.origin)
+		}
+	}
+
+	return
+}
+
+// Graphics draws the images on a client's screen. It keeps track of the
+// images the client can currently see so that they are only redrawn when
+// they change.
+type Graphics struct {
+	deadlock.Mutex
+	protocol Protocol
+	// The placements that are visible on the client's screen.
+	placements []Placement
+	// The images that have been transmitted to the client, which only
+	// applies to the kitty protocol.
+	sent map[uint64]struct{}
+}
+
+// The maximum number of images that will be stored on a client before they
+// are all discarded.
+const maxSentImages = 256
+
+func NewGraphics(protocol Protocol) *Graphics {
+	return &Graphics{
+		protocol: protocol,
+		sent:     make(map[uint64]struct{}),
+	}
+}
+
+// Reset indicates that the client's screen was cleared and all images must
+// be drawn again.
+func (g *Graphics) Reset() {
+	g.Lock()
+	g.placements = nil
+	g.Unlock()
+}
+
+func samePlacements(a, b []Placement) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Update returns the bytes necessary to draw the images in `src` on a
+// client's screen that currently looks like `dst`. It should be called with
+// the same arguments given to Swap.
+func (g *Graphics) Update(
+	info *terminfo.Terminfo,
+	caps Capabilities,
+	dst, src *State,
+) []byte {
+	g.Lock()
+	defer g.Unlock()
+
+	if g.protocol == ProtocolNone {
+		return nil
+	}
+
+	placements := FindPlacements(src.Image)
+
+	// Any text drawn over an image might have erased part of it
+	overwritten := false
+	for _, placement := range placements {
+		rect := placement.Rect
+		for row := rect.Position.R; row < rect.Position.R+rect.Size.R; row++ {
+			for col := rect.Position.C; col < rect.Position.C+rect.Size.C; col++ {
+				if row >= len(dst.Image) || col >= len(dst.Image[row]) {
+					overwritten = true
+					continue
+				}
+
+				if !dst.Image[row][col].Equal(Placeholder(src.Image[row][col])) {
+					overwritten = true
+				}
+			}
+		}
+	}
+
+	if !overwritten && samePlacements(g.placements, placements) {
+		return nil
+	}
+	g.placements = placements
+
+	data := new(bytes.Buffer)
+
+	// Save the cursor
+	data.WriteString("\x1b7")
+
+	if g.protocol == ProtocolKitty {
+		// Remove all existing placements
+		data.WriteString("\x1b_Ga=d,d=a,q=2\x1b\\")
+	}
+
+	for _, placement := range placements {
+		info.Fprintf(
+			data,
+			terminfo.CursorAddress,
+			placement.Rect.Position.R,
+			placement.Rect.Position.C,
+		)
+
+		switch g.protocol {
+		case ProtocolKitty:
+			g.writeKitty(data, placement)
+		case ProtocolSixel:
+			writeSixel(data, placement)
+
+			// sixel images cover the text beneath them, so we need to
+			// draw anything that is supposed to be on top
+			rect := placement.Rect
+			for row := rect.Position.R; row < rect.Position.R+rect.Size.R; row++ {
+				for col := rect.Position.C; col < rect.Position.C+rect.Size.C; col++ {
+					cell := src.Image[row][col]
+					if cell.Image != nil && cell.Image.Image == placement.Image {
+						continue
+					}
+
+					info.Fprintf(data, terminfo.CursorAddress, row, col)
+					writeCell(info, caps, data, Placeholder(cell))
+				}
+			}
+		}
+	}
+
+	// Restore the cursor
+	data.WriteString("\x1b8")
+
+	return data.Bytes()
+}
+
+// kittyID returns the ID used to refer to `img` on the client.
+func kittyID(img *emu.Image) uint32 {
+	return uint32((img.ID-1)%math.MaxUint32) + 1
+}
+
+// The number of bytes of base64-encoded data to send in a single chunk.
+const kittyChunkSize = 4096
+
+// writeKitty draws a placement using the kitty graphics protocol.
+func (g *Graphics) writeKitty(data *bytes.Buffer, placement Placement) {
+	img := placement.Image
+	id := kittyID(img)
+	bounds := img.Pixels.Bounds()
+
+	if _, ok := g.sent[img.ID]; !ok {
+		if len(g.sent) >= maxSentImages {
+			data.WriteString("\x1b_Ga=d,d=A,q=2\x1b\\")
+			g.sent = make(map[uint64]struct{})
+		}
+		g.sent[img.ID] = struct{}{}
+
+		raw := make([]byte, 0, bounds.Dx()*bounds.Dy()*4)
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				c := color.NRGBAModel.Convert(img.Pixels.At(x, y)).(color.NRGBA)
+				raw = append(raw, c.R, c.G, c.B, c.A)
+			}
+		}
+
+		pixels := new(bytes.Buffer)
+		writer := zlib.NewWriter(pixels)
+		writer.Write(raw)
+		writer.Close()
+
+		encoded := base64.StdEncoding.EncodeToString(pixels.Bytes())
+		for i := 0; i < len(encoded) || i == 0; i += kittyChunkSize {
+			end := geom.Min(i+kittyChunkSize, len(encoded))
+			more := 0
+			if end < len(encoded) {
+				more = 1
+			}
+
+			if i == 0 {
+				fmt.Fprintf(
+					data,
+					"\x1b_Ga=t,f=32,o=z,s=%d,v=%d,i=%d,q=2,m=%d;%s\x1b\\",
+					bounds.Dx(),
+					bounds.Dy(),
+					id,
+					more,
+					encoded[i:end],
+				)
+			} else {
+				fmt.Fprintf(
+					data,
+					"\x1b_Gm=%d;%s\x1b\\",
+					more,
+					encoded[i:end],
+				)
+			}
+		}
+	}
+
+	region := img.Region(placement.Offset, placement.Rect.Size)
+	if region.Empty() {
+		return
+	}
+
+	// The image is drawn beneath text so that anything on top of it
+	// remains visible
+	fmt.Fprintf(
+		data,
+		"\x1b_Ga=p,i=%d,x=%d,y=%d,w=%d,h=%d,c=%d,r=%d,C=1,z=-1,q=2\x1b\\",
+		id,
+		region.Min.X-bounds.Min.X,
+		region.Min.Y-bounds.Min.Y,
+		region.Dx(),
+		region.Dy(),
+		placement.Rect.Size.C,
+		placement.Rect.Size.R,
+	)
+}
+
+// sixelLevel converts an 8-bit color channel to one of the six levels used
+// for each channel in the sixel palette.
+func sixelLevel(value uint8) int {
+	return (int(value)*5 + 127) / 255
+}
+
+// writeSixel draws a placement as a sixel image. Since sixel images cannot
+// be scaled by the terminal, the image is resized so that each cell is
+// emu.DefaultCellSize pixels.
+func writeSixel(data *bytes.Buffer, placement Placement) {
+	img := placement.Image
+	region := img.Region(placement.Offset, placement.Rect.Size)
+	if region.Empty() {
+		return
+	}
+
+	width := placement.Rect.Size.C * emu.DefaultCellSize.C
+	height := placement.Rect.Size.R * emu.DefaultCellSize.R
+
+	// The palette index of every pixel, or -1 if it is transparent
+	indices := make([]int, width*height)
+	used := make([]bool, 216)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			srcX := region.Min.X + x*region.Dx()/width
+			srcY := region.Min.Y + y*region.Dy()/height
+			c := color.NRGBAModel.Convert(img.Pixels.At(srcX, srcY)).(color.NRGBA)
+			if c.A < 128 {
+				indices[y*width+x] = -1
+				continue
+			}
+
+			index := sixelLevel(c.R)*36 + sixelLevel(c.G)*6 + sixelLevel(c.B)
+			indices[y*width+x] = index
+			used[index] = true
+		}
+	}
+
+	fmt.Fprintf(data, "\x1bP0;1;0q\"1;1;%d;%d", width, height)
+	for index, isUsed := range used {
+		if !isUsed {
+			continue
+		}
+
+		fmt.Fprintf(
+			data,
+			"#%d;2;%d;%d;%d",
+			index,
+			index/36*20,
+			index/6%6*20,
+			index%6*20,
+		)
+	}
+
+	for band := 0; band < height; band += 6 {
+		if band > 0 {
+			data.WriteByte('-')
+		}
+
+		// The sixels for every color that appears in this band
+		bands := make(map[int][]byte)
+		var order []int
+		for i := 0; i < 6 && band+i < height; i++ {
+			for x := 0; x < width; x++ {
+				index := indices[(band+i)*width+x]
+				if index == -1 {
+					continue
+				}
+
+				bits, ok := bands[index]
+				if !ok {
+					bits = make([]byte, width)
+					bands[index] = bits
+					order = append(order, index)
+				}
+				bits[x] |= 1 << i
+			}
+		}
+
+		for _, index := range order {
+			bits := bands[index]
+			fmt.Fprintf(data, "#%d", index)
+			for x := 0; x < width; {
+				run := 1
+				for x+run < width && bits[x+run] == bits[x] {
+					run++
+				}
+
+				char := bits[x] + '?'
+				if run > 3 {
+					fmt.Fprintf(data, "!%d%c", run, char)
+				} else {
+					for i := 0; i < run; i++ {
+						data.WriteByte(char)
+					}
+				}
+				x += run
+			}
+			data.WriteByte('$')
+		}
+	}
+
+	data.WriteString("\x1b\\")
+}
@@ -11,10 +11,13 @@ import (
 	"github.com/xo/terminfo"
 )
 
-func setColor(info *terminfo.Terminfo, color emu.Color, isBg bool) []byte {
+func setColor(
+	info *terminfo.Terminfo,
+	caps Capabilities,
+	color emu.Color,
+	isBg bool,
+) []byte {
 	data := new(bytes.Buffer)
-	num := uint32(color)
-	maxColors := uint32(info.Nums[terminfo.MaxColors])
 
 	if (!isBg && color == emu.DefaultFG) || (isBg && color == emu.DefaultBG) {
 		return make([]byte, 0)
@@ -22,17 +25,18 @@ func setColor(info *terminfo.Terminfo, color emu.Color, isBg bool) []byte {
 
 	// Special case for reversed text when still set to default
 	if isBg && color == emu.DefaultFG {
-		num = 15
 		color = 15
 	} else if !isBg && color == emu.DefaultBG {
-		num = 0
 		color = 0
 	}
 
-	if num > maxColors {
-		r := color >> 16
-		g := (color >> 8) & 0xff
-		b := color & 0xff
+	color, ok := convertColor(caps.Profile, color)
+	if !ok {
+		return make([]byte, 0)
+	}
+
+	if color.IsRGB() {
+		r, g, b := color.RGB()
 
 		if isBg {
 			fmt.Fprintf(data, "\x1b[48;2;%d;%d;%dm", r, g, b)
@@ -51,9 +55,83 @@ func setColor(info *terminfo.Terminfo, color emu.Color, isBg bool) []byte {
 	return data.Bytes()
 }
 
+// SetPen returns the bytes necessary to make text written to a terminal
+// afterwards have the attributes and colors of `pen`, which is typically the
+// Attr of an emu.Cursor.
+func SetPen(
+	info *terminfo.Terminfo,
+	caps Capabilities,
+	pen emu.Glyph,
+) []byte {
+	data := new(bytes.Buffer)
+	info.Fprintf(data, terminfo.ExitAttributeMode)
+
+	if pen.Mode&emu.AttrBold != 0 {
+		info.Fprintf(data, terminfo.EnterBoldMode)
+	}
+
+	if pen.Mode&emu.AttrUnderline != 0 {
+		info.Fprintf(data, terminfo.EnterUnderlineMode)
+	}
+
+	if pen.Mode&emu.AttrItalic != 0 {
+		info.Fprintf(data, terminfo.EnterItalicsMode)
+	}
+
+	if pen.Mode&emu.AttrBlink != 0 {
+		info.Fprintf(data, terminfo.EnterBlinkMode)
+	}
+
+	if pen.Mode&emu.AttrReverse != 0 {
+		info.Fprintf(data, terminfo.EnterReverseMode)
+	}
+
+	data.Write(setColor(info, caps, pen.FG, false))
+	data.Write(setColor(info, caps, pen.BG, true))
+	return data.Bytes()
+}
+
+// writeCell writes the escape sequences necessary to draw `cell` at the
+// current cursor position.
+func writeCell(
+	info *terminfo.Terminfo,
+	caps Capabilities,
+	data *bytes.Buffer,
+	cell emu.Glyph,
+) {
+	mode := cell.Mode
+
+	// note: emu.AttrReverse is handled virtually, since
+	// it's just a color change
+
+	if mode&emu.AttrBold != 0 {
+		info.Fprintf(data, terminfo.EnterBoldMode)
+	}
+
+	if mode&emu.AttrUnderline != 0 {
+		info.Fprintf(data, terminfo.EnterUnderlineMode)
+	}
+
+	if mode&emu.AttrItalic != 0 {
+		info.Fprintf(data, terminfo.EnterItalicsMode)
+	}
+
+	if mode&emu.AttrBlink != 0 {
+		info.Fprintf(data, terminfo.EnterBlinkMode)
+	}
+
+	data.Write(setColor(info, caps, cell.FG, false))
+	data.Write(setColor(info, caps, cell.BG, true))
+
+	data.Write([]byte(cell.String()))
+
+	info.Fprintf(data, terminfo.ExitAttributeMode)
+}
+
 // Calculate the minimum string to transform `src` in to `dst`.
 func swapImage(
 	info *terminfo.Terminfo,
+	caps Capabilities,
 	dst, src image.Image,
 ) []byte {
 	data := new(bytes.Buffer)
@@ -65,41 +143,14 @@ func swapImage(
 	for row := 0; row < max.R; row++ {
 		for col := 0; col < max.C; col++ {
 			dstCell := dst.Cell(col, row)
-			srcCell := src.Cell(col, row)
+			srcCell := Placeholder(src.Cell(col, row))
 
 			if dstCell.Equal(srcCell) {
 				continue
 			}
 
 			info.Fprintf(data, terminfo.CursorAddress, row, col)
-
-			mode := srcCell.Mode
-
-			// note: emu.AttrReverse is handled virtually, since
-			// it's just a color change
-
-			if mode&emu.AttrBold != 0 {
-				info.Fprintf(data, terminfo.EnterBoldMode)
-			}
-
-			if mode&emu.AttrUnderline != 0 {
-				info.Fprintf(data, terminfo.EnterUnderlineMode)
-			}
-
-			if mode&emu.AttrItalic != 0 {
-				info.Fprintf(data, terminfo.EnterItalicsMode)
-			}
-
-			if mode&emu.AttrBlink != 0 {
-				info.Fprintf(data, terminfo.EnterBlinkMode)
-			}
-
-			data.Write(setColor(info, srcCell.FG, false))
-			data.Write(setColor(info, srcCell.BG, true))
-
-			data.Write([]byte(string(srcCell.Char)))
-
-			info.Fprintf(data, terminfo.ExitAttributeMode)
+			writeCell(info, caps, data, srcCell)
 
 			// CJK characters
 			col += srcCell.Width() - 1
@@ -111,12 +162,15 @@ func swapImage(
 	return data.Bytes()
 }
 
+// Swap returns the bytes necessary to change a terminal with the given
+// capabilities from displaying `dst` to displaying `src`.
 func Swap(
 	info *terminfo.Terminfo,
+	caps Capabilities,
 	dst, src *State,
 ) []byte {
 	data := new(bytes.Buffer)
-	data.Write(swapImage(info, dst.Image, src.Image))
+	data.Write(swapImage(info, caps, dst.Image, src.Image))
 
 	dstCursor := dst.Cursor
 	srcCursor := src.Cursor
@@ -0,0 +1,49 @@
+package tty
+
+import (
+	"fmt"
+
+	"github.com/cfoust/cy/pkg/emu"
+)
+
+// ApplyTheme returns a copy of `state` in which every color that `theme`
+// changes has been replaced with the RGB color it maps to. If `theme` is
+// empty, `state` is returned as-is.
+func ApplyTheme(theme emu.Theme, state *State) *State {
+	if len(theme) == 0 {
+		return state
+	}
+
+	themed := &State{
+		Image:         state.Image.Clone(),
+		Cursor:        state.Cursor,
+		CursorVisible: state.CursorVisible,
+	}
+
+	for _, line := range themed.Image {
+		for col := range line {
+			cell := &line[col]
+			if color, ok := theme[cell.FG]; ok {
+				cell.FG = color
+			}
+			if color, ok := theme[cell.BG]; ok {
+				cell.BG = color
+			}
+		}
+	}
+
+	return themed
+}
+
+// SetCursorColor returns the escape sequence that sets the color of the
+// client's cursor to the one in `theme`, or resets it to the terminal's
+// default if `theme` does not specify one.
+func SetCursorColor(theme emu.Theme) []byte {
+	color, ok := theme[emu.DefaultCursor]
+	if !ok {
+		return []byte("\x1b]112\x1b\\")
+	}
+
+	r, g, b := color.RGB()
+	return []byte(fmt.Sprintf("\x1b]12;#%02x%02x%02x\x1b\\", r, g, b))
+}
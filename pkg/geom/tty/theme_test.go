@@ -0,0 +1,32 @@
+package tty
+
+import (
+	"testing"
+
+	"github.com/cfoust/cy/pkg/emu"
+	"github.com/cfoust/cy/pkg/geom"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyTheme(t *testing.T) {
+	state := New(geom.Vec2{R: 1, C: 2})
+	state.Image[0][0].FG = emu.Red
+	state.Image[0][1].FG = emu.Blue
+
+	red := emu.NewRGB(0xff, 0x00, 0x00)
+	black := emu.NewRGB(0x00, 0x00, 0x00)
+	theme := emu.Theme{
+		emu.Red:       red,
+		emu.DefaultBG: black,
+	}
+
+	themed := ApplyTheme(theme, state)
+	require.Equal(t, red, themed.Image[0][0].FG)
+	require.Equal(t, black, themed.Image[0][0].BG)
+	require.Equal(t, emu.Blue, themed.Image[0][1].FG)
+
+	// The original state is left untouched
+	require.Equal(t, emu.Red, state.Image[0][0].FG)
+	require.Equal(t, emu.DefaultBG, state.Image[0][0].BG)
+}
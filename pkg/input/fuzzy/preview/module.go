@@ -18,7 +18,7 @@ func New(
 	case NodeType:
 		return NewNode(ctx, tree, client, args)
 	case ReplayType:
-		return NewReplay(ctx, args)
+		return NewReplay(ctx, tree, args)
 	case TextType:
 		return NewText(ctx, args)
 	case ScrollbackType:
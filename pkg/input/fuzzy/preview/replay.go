@@ -3,15 +3,16 @@ package preview
 import (
 	"context"
 	"fmt"
-	"io"
 
 	"github.com/cfoust/cy/pkg/bind"
 	"github.com/cfoust/cy/pkg/geom"
 	"github.com/cfoust/cy/pkg/geom/tty"
 	"github.com/cfoust/cy/pkg/mux"
+	"github.com/cfoust/cy/pkg/mux/screen/tree"
 	"github.com/cfoust/cy/pkg/replay"
 	"github.com/cfoust/cy/pkg/replay/player"
 	"github.com/cfoust/cy/pkg/sessions"
+	"github.com/cfoust/cy/pkg/sessions/search"
 	"github.com/cfoust/cy/pkg/taro"
 	"github.com/cfoust/cy/pkg/util"
 
@@ -20,12 +21,18 @@ import (
 )
 
 type ReplayType struct {
+	// The path to a .borg file
 	Path string
+	// The ID of a pane whose history should be shown instead of a file
+	Id *tree.NodeID
+	// The moment in the recording to show
+	Address *search.Address
 }
 
 type Replay struct {
 	util.Lifetime
 	ReplayType
+	tree   *tree.Tree
 	render *taro.Renderer
 	replay *taro.Program
 	err    error
@@ -39,28 +46,38 @@ type loadedEvent struct {
 	err    error
 }
 
+// getEvents returns the events that should be shown in the preview.
+func (r *Replay) getEvents() ([]sessions.Event, error) {
+	if r.Id == nil {
+		return sessions.ReadAll(r.Path)
+	}
+
+	pane, ok := r.tree.PaneById(*r.Id)
+	if !ok {
+		return nil, fmt.Errorf("pane %d not found", *r.Id)
+	}
+
+	replayable, ok := pane.Screen().(*replay.Replayable)
+	if !ok {
+		return nil, fmt.Errorf("pane %d has no history", *r.Id)
+	}
+
+	return replayable.Events(), nil
+}
+
 func (r *Replay) Init() tea.Cmd {
 	size := r.size
 	return func() tea.Msg {
-		reader, err := sessions.Open(r.Path)
+		events, err := r.getEvents()
 		if err != nil {
 			return loadedEvent{
 				err: err,
 			}
 		}
 
-		events := make([]sessions.Event, 0)
-		for {
-			event, err := reader.Read()
-			if err == io.EOF || err == io.ErrUnexpectedEOF {
-				break
-			}
-			if err != nil {
-				return loadedEvent{
-					err: err,
-				}
-			}
-			events = append(events, event)
+		var options []replay.Option
+		if r.Address != nil && len(events) > 0 {
+			options = append(options, replay.WithAddress(*r.Address))
 		}
 
 		ctx := r.Lifetime.Ctx()
@@ -69,6 +86,7 @@ func (r *Replay) Init() tea.Cmd {
 			player.FromEvents(events),
 			bind.NewBindScope(nil),
 			bind.NewBindScope(nil),
+			options...,
 		)
 		replay.Resize(size)
 
@@ -101,7 +119,7 @@ func (r *Replay) View(state *tty.State) {
 				geom.DEFAULT_SIZE.C,
 				geom.DEFAULT_SIZE.R,
 				lipgloss.Center, lipgloss.Center,
-				fmt.Sprintf("loading %s...", r.Path),
+				"loading...",
 			),
 		)
 		return
@@ -136,11 +154,13 @@ func (r *Replay) Update(msg tea.Msg) (taro.Model, tea.Cmd) {
 
 func NewReplay(
 	ctx context.Context,
+	tree *tree.Tree,
 	args ReplayType,
 ) mux.Screen {
 	l := util.NewLifetime(ctx)
 	return taro.New(l.Ctx(), &Replay{
 		Lifetime:   l,
+		tree:       tree,
 		render:     taro.NewRenderer(),
 		ReplayType: args,
 	})
@@ -4,6 +4,8 @@ import (
 	"fmt"
 
 	"github.com/cfoust/cy/pkg/janet"
+	"github.com/cfoust/cy/pkg/mux/screen/tree"
+	"github.com/cfoust/cy/pkg/sessions/search"
 )
 
 var (
@@ -17,6 +19,14 @@ type previewInput struct {
 	Type janet.Keyword
 }
 
+// replayInput is the Janet representation of ReplayType, in which only
+// one of the path or the pane ID is required.
+type replayInput struct {
+	Path    *string
+	Id      *tree.NodeID
+	Address *search.Address
+}
+
 func Unmarshal(input *janet.Value) (result interface{}, err error) {
 	preview := previewInput{}
 	err = input.Unmarshal(&preview)
@@ -41,12 +51,25 @@ func Unmarshal(input *janet.Value) (result interface{}, err error) {
 		result = node
 		return
 	case KEYWORD_REPLAY:
-		replay := ReplayType{}
+		replay := replayInput{}
 		err = input.Unmarshal(&replay)
 		if err != nil {
 			return
 		}
-		result = replay
+
+		if (replay.Path == nil) == (replay.Id == nil) {
+			err = fmt.Errorf("replay preview needs one of :path or :id")
+			return
+		}
+
+		args := ReplayType{
+			Id:      replay.Id,
+			Address: replay.Address,
+		}
+		if replay.Path != nil {
+			args.Path = *replay.Path
+		}
+		result = args
 	case KEYWORD_SCROLLBACK:
 		scrollback := ScrollbackType{}
 		err = input.Unmarshal(&scrollback)
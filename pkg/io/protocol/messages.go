@@ -54,12 +54,31 @@ func (i SizeMessage) Vec() geom.Vec2 {
 	return geom.Vec2{R: i.Rows, C: i.Columns}
 }
 
+// Capabilities describes the features supported by the client's terminal,
+// which the client determined by probing the terminal before connecting.
+type Capabilities struct {
+	// Version is the terminal's name and version as reported by
+	// XTVERSION.
+	Version             string
+	Attributes          []int
+	SecondaryAttributes []int
+	Sixel               bool
+	KittyGraphics       bool
+	KittyKeyboard       bool
+	BracketedPaste      bool
+	SyncUpdate          bool
+}
+
 // The initial information necessary to render to the client.
 type HandshakeMessage struct {
 	Env     map[string]string
 	Shell   string
 	Size    geom.Vec2
 	Profile termenv.Profile
+	// Capabilities is nil if the client's terminal did not respond to
+	// probing or the client predates it, in which case the server guesses
+	// what the terminal supports.
+	Capabilities *Capabilities
 }
 
 func (i HandshakeMessage) Type() MessageType { return MessageTypeHandshake }
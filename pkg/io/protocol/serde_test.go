@@ -3,6 +3,9 @@ package protocol
 import (
 	"testing"
 
+	"github.com/cfoust/cy/pkg/geom"
+
+	"github.com/muesli/termenv"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -18,3 +21,49 @@ func TestSerialization(t *testing.T) {
 	after, err := Decode(encoded)
 	assert.Equal(t, &before, after, "should yield same result")
 }
+
+// legacyHandshake is the handshake sent by clients that did not probe the
+// terminal.
+type legacyHandshake struct {
+	Env     map[string]string
+	Shell   string
+	Size    geom.Vec2
+	Profile termenv.Profile
+}
+
+func (l legacyHandshake) Type() MessageType { return MessageTypeHandshake }
+
+func TestHandshake(t *testing.T) {
+	before := HandshakeMessage{
+		Env:     map[string]string{"TERM": "xterm-256color"},
+		Shell:   "/bin/bash",
+		Size:    geom.Vec2{R: 24, C: 80},
+		Profile: termenv.ANSI256,
+		Capabilities: &Capabilities{
+			Version:       "kitty(0.32.2)",
+			Attributes:    []int{62},
+			KittyGraphics: true,
+		},
+	}
+
+	encoded, err := Encode(before)
+	assert.NoError(t, err)
+
+	after, err := Decode(encoded)
+	assert.NoError(t, err)
+	assert.Equal(t, &before, after)
+
+	// Handshakes from older clients do not include capabilities
+	encoded, err = Encode(legacyHandshake{
+		Env:     before.Env,
+		Shell:   before.Shell,
+		Size:    before.Size,
+		Profile: before.Profile,
+	})
+	assert.NoError(t, err)
+
+	after, err = Decode(encoded)
+	assert.NoError(t, err)
+	before.Capabilities = nil
+	assert.Equal(t, &before, after)
+}
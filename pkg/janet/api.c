@@ -39,3 +39,7 @@ Janet wrap_keyword(const char *str) {
 int tuple_length(const Janet *t) {
     return janet_tuple_length(t);
 }
+
+int string_length(const uint8_t *s) {
+    return janet_string_length(s);
+}
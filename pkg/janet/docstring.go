@@ -0,0 +1,70 @@
+package janet
+
+/*
+#cgo CFLAGS: -std=c99
+#cgo LDFLAGS: -lm -ldl
+
+#include <janet.h>
+#include <api.h>
+*/
+import "C"
+
+import (
+	"context"
+)
+
+type docstringRequest struct {
+	value  *Value
+	result chan docstringResult
+}
+
+type docstringResult struct {
+	docstring string
+	err       error
+}
+
+func (v *VM) runDocstring(req docstringRequest) {
+	out, err := v.callBoot(v.docstring, req.value.janet)
+	if err != nil {
+		req.result <- docstringResult{err: err}
+		return
+	}
+
+	var docstring string
+	if C.janet_checktype(out, C.JANET_STRING) == 1 {
+		err = v.unmarshal(out, &docstring)
+	}
+
+	req.result <- docstringResult{
+		docstring: docstring,
+		err:       err,
+	}
+}
+
+// Docstring gets the docstring of the binding in the VM's environment whose
+// value is `value`, such as the function defined by a `defn`. It returns an
+// empty string if there is no such binding or it has no docstring.
+func (v *VM) Docstring(ctx context.Context, value *Value) (string, error) {
+	if value.IsFree() {
+		return "", ERROR_FREED
+	}
+
+	req := docstringRequest{
+		value: value,
+		// Buffered so that the VM never blocks if the caller gives up
+		result: make(chan docstringResult, 1),
+	}
+
+	select {
+	case v.requests <- req:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+
+	select {
+	case result := <-req.result:
+		return result.docstring, result.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
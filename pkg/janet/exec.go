@@ -19,6 +19,10 @@ import (
 type CallOptions struct {
 	// Whether to allow the code to mutate the current environment.
 	UpdateEnv bool
+	// Whether the code is being entered interactively, such as in a
+	// REPL. Interactive calls capture anything the code prints and report
+	// the value of the last expression.
+	Interactive bool
 }
 
 var DEFAULT_CALL_OPTIONS = CallOptions{
@@ -48,6 +52,17 @@ func CallString(code string) Call {
 type callRequest struct {
 	Params
 	Call
+	// Populated when the call completes if Call.Options.Interactive is
+	// set.
+	Evaluation *Evaluation
+}
+
+// Evaluation contains the results of an interactive call.
+type Evaluation struct {
+	// Everything the code wrote to stdout or stderr.
+	Output string
+	// The pretty-printed value of the last expression.
+	Result string
 }
 
 // Run code without using our evaluation function. This can panic.
@@ -64,7 +79,11 @@ func (v *VM) runCodeUnsafe(code []byte, source string) {
 	C.free(unsafe.Pointer(sourcePtr))
 }
 
-func (v *VM) handleCodeResult(params Params, call Call) error {
+func (v *VM) handleCodeResult(
+	params Params,
+	call Call,
+	evaluation *Evaluation,
+) error {
 	var out *Value
 	select {
 	case <-params.Context.Done():
@@ -89,6 +108,36 @@ func (v *VM) handleCodeResult(params Params, call Call) error {
 		return fmt.Errorf(message)
 	}
 
+	if call.Options.Interactive && resultType == C.JANET_STRUCT {
+		var interactive struct {
+			Env    *Value
+			Error  *string
+			Output string
+			Result *string
+		}
+		// Unmarshaling a struct creates Janet values, so this must
+		// happen on the VM's thread
+		err := v.Unmarshal(result, &interactive)
+		if err != nil {
+			return err
+		}
+		defer interactive.Env.Free()
+
+		if evaluation != nil {
+			evaluation.Output = interactive.Output
+			if interactive.Result != nil {
+				evaluation.Result = *interactive.Result
+			}
+		}
+
+		if interactive.Error != nil {
+			return fmt.Errorf(*interactive.Error)
+		}
+
+		result = interactive.Env.janet
+		resultType = C.janet_type(result)
+	}
+
 	if resultType != C.JANET_TABLE {
 		return fmt.Errorf("evaluate returned unexpected type")
 	}
@@ -109,7 +158,7 @@ func (v *VM) handleCodeResult(params Params, call Call) error {
 
 // Run a string containing Janet code and return any error that occurs.
 // TODO(cfoust): 07/20/23 send error to errc with timeout
-func (v *VM) runCode(params Params, call Call) {
+func (v *VM) runCode(params Params, call Call, evaluation *Evaluation) {
 	sourcePtr := C.CString(call.SourcePath)
 
 	var env *C.JanetTable = C.janet_core_env(nil)
@@ -123,6 +172,11 @@ func (v *VM) runCode(params Params, call Call) {
 		return
 	}
 
+	var interactive C.int
+	if call.Options.Interactive {
+		interactive = 1
+	}
+
 	args := []C.Janet{
 		C.janet_wrap_string(
 			C.janet_string(
@@ -134,6 +188,7 @@ func (v *VM) runCode(params Params, call Call) {
 		C.janet_wrap_string(
 			C.janet_cstring(sourcePtr),
 		),
+		C.janet_wrap_boolean(interactive),
 	}
 
 	fiber := v.createFiber(
@@ -148,7 +203,7 @@ func (v *VM) runCode(params Params, call Call) {
 
 	go func() {
 		v.runFiber(subParams, fiber, nil)
-		err := v.handleCodeResult(subParams, call)
+		err := v.handleCodeResult(subParams, call, evaluation)
 		if err != nil {
 			params.Error(err)
 			return
@@ -195,6 +250,36 @@ func (v *VM) ExecuteCall(ctx context.Context, user interface{}, call Call) error
 	return req.Wait()
 }
 
+// Evaluate executes `call` interactively and returns the output it produced
+// along with the value of its last expression. The Evaluation is returned
+// even if an error occurred so that the caller can still show any output.
+func (v *VM) Evaluate(
+	ctx context.Context,
+	user interface{},
+	call Call,
+) (*Evaluation, error) {
+	call.Options.Interactive = true
+	evaluation := &Evaluation{}
+	result := make(chan Result)
+	req := callRequest{
+		Params: Params{
+			Context: ctx,
+			User:    user,
+			Result:  result,
+		},
+		Call:       call,
+		Evaluation: evaluation,
+	}
+	v.requests <- req
+
+	err := req.Wait()
+	if ctx.Err() != nil {
+		return nil, err
+	}
+
+	return evaluation, err
+}
+
 func (v *VM) Execute(ctx context.Context, code string) error {
 	return v.ExecuteCall(ctx, nil, CallString(code))
 }
@@ -15,6 +15,7 @@ import (
 	"fmt"
 	"reflect"
 	"runtime"
+	"sort"
 	"strings"
 	"unsafe"
 
@@ -283,6 +284,11 @@ func (v *VM) setupCallback(params Params, args []C.Janet) (partial *PartialCallb
 	partial = &PartialCallback{
 		Type: callbackType,
 		invoke: func() []reflect.Value {
+			// The variadic arguments arrive as a single tuple
+			if callbackType.IsVariadic() {
+				return callback.function.CallSlice(callbackArgs)
+			}
+
 			return callback.function.Call(callbackArgs)
 		},
 	}
@@ -361,8 +367,15 @@ func isParamType(type_ reflect.Type) bool {
 }
 
 type Callback struct {
-	source   interface{}
-	function reflect.Value
+	source    interface{}
+	function  reflect.Value
+	docstring string
+}
+
+// Docstring returns the documentation provided for this callback when it was
+// registered.
+func (c *Callback) Docstring() string {
+	return c.docstring
 }
 
 func (c *Callback) Source() (file string, line int) {
@@ -437,7 +450,11 @@ func validateFunction(in, out []reflect.Type) error {
 	return nil
 }
 
-func getPrototype(name string, in, out []reflect.Type) (string, error) {
+func getPrototype(
+	name string,
+	in, out []reflect.Type,
+	variadic bool,
+) (string, error) {
 	var argCount int
 	var argList []string
 	var namedParams []string
@@ -465,6 +482,16 @@ func getPrototype(name string, in, out []reflect.Type) (string, error) {
 	}
 
 	invocation := argList
+	if variadic && len(argList) > 0 {
+		// The last argument collects all of the remaining arguments
+		last := len(argList) - 1
+		argList = append(
+			append([]string{}, argList[:last]...),
+			"&",
+			argList[last],
+		)
+	}
+
 	if len(namedParams) > 0 {
 		argList = append(argList, "&named")
 		argList = append(argList, namedParams...)
@@ -519,20 +546,26 @@ func (v *VM) registerCallback(
 		return fmt.Errorf("could not register %s: %s", name, err.Error())
 	}
 
+	docstring = strings.TrimSpace(docstring)
+
 	v.Lock()
 	v.callbacks[name] = &Callback{
-		source:   source,
-		function: callback,
+		source:    source,
+		function:  callback,
+		docstring: docstring,
 	}
 	v.Unlock()
 
-	prototype, err := getPrototype(name, in, out)
+	prototype, err := getPrototype(
+		name,
+		in,
+		out,
+		callback.Type().IsVariadic(),
+	)
 	if err != nil {
 		return err
 	}
 
-	docstring = strings.TrimSpace(docstring)
-
 	format := "(defn %s %s %s)"
 
 	// You can provide a custom method prototype by providing a docstring
@@ -587,6 +620,19 @@ func (v *VM) Lookup(name string) (callback *Callback, ok bool) {
 	return
 }
 
+// Symbols returns the Janet names of all of the registered callbacks in
+// sorted order.
+func (v *VM) Symbols() (symbols []string) {
+	v.RLock()
+	for name := range v.callbacks {
+		symbols = append(symbols, name)
+	}
+	v.RUnlock()
+
+	sort.Strings(symbols)
+	return
+}
+
 func (v *VM) Module(name string, module interface{}) error {
 	type_ := reflect.TypeOf(module)
 
@@ -13,6 +13,7 @@ import _ "embed"
 import (
 	"context"
 	"runtime"
+	"unsafe"
 
 	"github.com/sasha-s/go-deadlock"
 )
@@ -27,6 +28,10 @@ type VM struct {
 
 	callbacks map[string]*Callback
 	evaluate  C.Janet
+	// go/marshal and go/unmarshal
+	serialize, deserialize C.Janet
+	// go/docstring
+	docstring C.Janet
 
 	requests chan Request
 
@@ -45,6 +50,17 @@ func (v *VM) Env() *Table {
 	return v.env
 }
 
+// resolveBoot looks up a function defined in go-boot.janet and roots it so
+// that it is never garbage collected.
+func resolveBoot(env *C.JanetTable, name string) (value C.Janet) {
+	namePtr := C.CString(name)
+	defer C.free(unsafe.Pointer(namePtr))
+
+	C.janet_resolve(env, C.janet_csymbol(namePtr), &value)
+	C.janet_gcroot(value)
+	return
+}
+
 // Wait for code calls and process them.
 func (v *VM) poll(ctx context.Context, ready chan bool) {
 	// All Janet state is thread-local, so we explicitly want to execute
@@ -59,10 +75,10 @@ func (v *VM) poll(ctx context.Context, ready chan bool) {
 	v.runCodeUnsafe(GO_BOOT_FILE, "go-boot.janet")
 
 	// Then store our evaluation function
-	var evaluate C.Janet
-	C.janet_resolve(env, C.janet_csymbol(C.CString("go/evaluate")), &evaluate)
-	C.janet_gcroot(evaluate)
-	v.evaluate = evaluate
+	v.evaluate = resolveBoot(env, "go/evaluate")
+	v.serialize = resolveBoot(env, "go/marshal")
+	v.deserialize = resolveBoot(env, "go/unmarshal")
+	v.docstring = resolveBoot(env, "go/docstring")
 
 	ready <- true
 
@@ -74,7 +90,7 @@ func (v *VM) poll(ctx context.Context, ready chan bool) {
 			switch req := req.(type) {
 			case callRequest:
 				params := req.Params
-				v.runCode(params, req.Call)
+				v.runCode(params, req.Call, req.Evaluation)
 			case fiberRequest:
 				params := req.Params
 				v.continueFiber(params, req.Fiber, req.In)
@@ -105,6 +121,10 @@ func (v *VM) poll(ctx context.Context, ready chan bool) {
 					req.Fiber,
 					v.value(wrapped),
 				)
+			case serializeRequest:
+				v.runSerialize(req)
+			case docstringRequest:
+				v.runDocstring(req)
 			case unmarshalRequest:
 				req.errc <- v.unmarshal(
 					req.source,
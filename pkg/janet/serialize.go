@@ -0,0 +1,152 @@
+package janet
+
+/*
+#cgo CFLAGS: -std=c99
+#cgo LDFLAGS: -lm -ldl
+
+#include <janet.h>
+#include <api.h>
+*/
+import "C"
+
+import (
+	"context"
+	"fmt"
+	"unsafe"
+)
+
+type serializeRequest struct {
+	// Exactly one of these is set. If value is set, it is marshaled,
+	// otherwise data is unmarshaled.
+	value *Value
+	data  []byte
+
+	result chan serializeResult
+}
+
+type serializeResult struct {
+	data  []byte
+	value *Value
+	err   error
+}
+
+// callBoot calls `function`, one of the functions defined in go-boot.janet,
+// with `arg` and the VM's environment. It must be called on the VM's thread.
+func (v *VM) callBoot(function C.Janet, arg C.Janet) (C.Janet, error) {
+	var env *C.JanetTable = C.janet_core_env(nil)
+	if v.env != nil {
+		env = v.env.table
+	}
+
+	// None of these functions yield, so unlike other calls, there is no
+	// need to run the fiber asynchronously
+	fiber := v.createFiber(
+		C.janet_unwrap_function(function),
+		[]C.Janet{arg, C.janet_wrap_table(env)},
+	)
+	defer fiber.unroot()
+
+	var out C.Janet
+	signal := C.janet_continue(fiber.fiber, C.janet_wrap_nil(), &out)
+	if signal != C.JANET_SIGNAL_OK {
+		var message string
+		if err := v.unmarshal(out, &message); err != nil {
+			message = prettyPrint(out)
+		}
+		return out, fmt.Errorf("%s", message)
+	}
+
+	return out, nil
+}
+
+func (v *VM) runSerialize(req serializeRequest) {
+	function := v.serialize
+	var arg C.Janet
+	if req.value != nil {
+		arg = req.value.janet
+	} else {
+		function = v.deserialize
+		dataPtr := unsafe.Pointer(nil)
+		if len(req.data) > 0 {
+			dataPtr = unsafe.Pointer(&req.data[0])
+		}
+		arg = C.janet_wrap_string(
+			C.janet_string(
+				(*C.uchar)(dataPtr),
+				C.int(len(req.data)),
+			),
+		)
+	}
+
+	out, err := v.callBoot(function, arg)
+	if err != nil {
+		req.result <- serializeResult{err: err}
+		return
+	}
+
+	if req.value == nil {
+		req.result <- serializeResult{value: v.value(out)}
+		return
+	}
+
+	str := C.janet_unwrap_string(out)
+	req.result <- serializeResult{
+		data: C.GoBytes(
+			unsafe.Pointer(str),
+			C.string_length(str),
+		),
+	}
+}
+
+func (v *VM) runSerializeRequest(
+	ctx context.Context,
+	req serializeRequest,
+) (serializeResult, error) {
+	// The channel is buffered so that the VM never blocks if the caller
+	// gives up
+	req.result = make(chan serializeResult, 1)
+
+	select {
+	case v.requests <- req:
+	case <-ctx.Done():
+		return serializeResult{}, ctx.Err()
+	}
+
+	select {
+	case result := <-req.result:
+		return result, result.err
+	case <-ctx.Done():
+		return serializeResult{}, ctx.Err()
+	}
+}
+
+// Serialize converts `value` to bytes using Janet's `marshal`. Values that are
+// bound in the VM's environment, such as API functions, are stored by name,
+// so the result can only be deserialized by a VM that defines the same
+// names.
+func (v *VM) Serialize(ctx context.Context, value *Value) ([]byte, error) {
+	if value.IsFree() {
+		return nil, ERROR_FREED
+	}
+
+	result, err := v.runSerializeRequest(ctx, serializeRequest{
+		value: value,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.data, nil
+}
+
+// Deserialize converts bytes created by Serialize back into a Janet value.
+func (v *VM) Deserialize(ctx context.Context, data []byte) (*Value, error) {
+	result, err := v.runSerializeRequest(ctx, serializeRequest{
+		data: data,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.value, nil
+}
@@ -69,7 +69,8 @@ func isValidType(type_ reflect.Type) bool {
 			return true
 		}
 
-		return false
+		// nil pointers become nil
+		return isValidType(type_.Elem())
 	case reflect.Struct:
 		value := reflect.New(type_).Elem()
 		for i := 0; i < type_.NumField(); i++ {
@@ -115,9 +116,12 @@ func (v *VM) marshal(item interface{}) (result C.Janet, err error) {
 			return
 		}
 
-		// TODO(cfoust): 06/23/23 maybe support this someday
-		err = fmt.Errorf("cannot marshal pointer to value")
-		return
+		// Nil pointers become nil, which is useful for optional fields
+		if value.IsNil() {
+			return
+		}
+
+		return v.marshal(value.Elem().Interface())
 	}
 
 	switch type_.Kind() {
@@ -357,7 +361,7 @@ func (v *VM) unmarshal(source C.Janet, dest interface{}) error {
 			}
 			value.Set(ptr)
 		} else {
-			value.Set(reflect.ValueOf(nil))
+			value.Set(reflect.Zero(type_))
 		}
 
 		//return fmt.Errorf("unimplemented pointer type: %s (%s)", type_.String(), type_.Kind().String())
@@ -207,6 +207,35 @@ func TestVM(t *testing.T) {
 		require.Error(t, err)
 	})
 
+	t.Run("variadic callback", func(t *testing.T) {
+		var (
+			first int
+			rest  []string
+		)
+		err = vm.Callback("test-variadic", "", func(
+			context interface{},
+			value int,
+			values ...string,
+		) {
+			first = value
+			rest = values
+		})
+		require.NoError(t, err)
+
+		err = vm.Execute(ctx, `(test-variadic 1 "two" "three")`)
+		require.NoError(t, err)
+		require.Equal(t, 1, first)
+		require.Equal(t, []string{"two", "three"}, rest)
+
+		err = vm.Execute(ctx, `(test-variadic 2)`)
+		require.NoError(t, err)
+		require.Equal(t, 2, first)
+		require.Empty(t, rest)
+
+		err = vm.Execute(ctx, `(test-variadic 1 2)`)
+		require.Error(t, err)
+	})
+
 	t.Run("module registration", func(t *testing.T) {
 		m := &TestModule{}
 		err = vm.Module("test", m)
@@ -256,6 +285,115 @@ func TestVM(t *testing.T) {
 		require.NoError(t, err)
 	})
 
+	t.Run("evaluate", func(t *testing.T) {
+		evaluation, err := vm.Evaluate(
+			ctx,
+			nil,
+			CallString(`(def repl-value 2) (print "hello") (+ repl-value 1)`),
+		)
+		require.NoError(t, err)
+		require.Equal(t, "hello\n", evaluation.Output)
+		require.Contains(t, evaluation.Result, "3")
+
+		// Definitions should persist across evaluations
+		evaluation, err = vm.Evaluate(
+			ctx,
+			nil,
+			CallString(`repl-value`),
+		)
+		require.NoError(t, err)
+		require.Empty(t, evaluation.Output)
+		require.Contains(t, evaluation.Result, "2")
+
+		// Callbacks should work as usual
+		ok = false
+		_, err = vm.Evaluate(ctx, nil, CallString(`(test)`))
+		require.NoError(t, err)
+		require.True(t, ok, "should have been called")
+
+		// Output that occurs before an error is still reported
+		evaluation, err = vm.Evaluate(
+			ctx,
+			nil,
+			CallString(`(print "before") (error "oops")`),
+		)
+		require.Error(t, err)
+		require.Equal(t, "before\n", evaluation.Output)
+
+		// Regular calls should not be affected by the capture
+		err = vm.Execute(ctx, `(assert (= (dyn :out) nil))`)
+		require.NoError(t, err)
+	})
+
+	t.Run("serialize", func(t *testing.T) {
+		var value *Value
+		err = vm.Callback("test-serialize", "", func(v *Value) {
+			value = v
+		})
+		require.NoError(t, err)
+
+		// Functions that refer to bindings in the environment should
+		// survive the round trip
+		err = vm.Execute(ctx, `
+(def serialize-count @[0])
+(test-serialize (fn [] (update serialize-count 0 inc) (test)))
+`)
+		require.NoError(t, err)
+
+		data, err := vm.Serialize(ctx, value)
+		require.NoError(t, err)
+		value.Free()
+
+		restored, err := vm.Deserialize(ctx, data)
+		require.NoError(t, err)
+
+		var fun *Function
+		require.NoError(t, restored.Unmarshal(&fun))
+		restored.Free()
+
+		ok = false
+		require.NoError(t, fun.Call(ctx))
+		require.True(t, ok)
+
+		// Values that cannot be marshaled produce an error
+		err = vm.Execute(ctx, `(test-serialize (file/temp))`)
+		require.NoError(t, err)
+		_, err = vm.Serialize(ctx, value)
+		require.Error(t, err)
+	})
+
+	t.Run("docstring", func(t *testing.T) {
+		var value *Value
+		err = vm.Callback("test-docstring", "", func(v *Value) {
+			value = v
+		})
+		require.NoError(t, err)
+
+		err = vm.Execute(ctx, `
+(defn documented "Does something useful." [] nil)
+(test-docstring documented)
+`)
+		require.NoError(t, err)
+		docstring, err := vm.Docstring(ctx, value)
+		require.NoError(t, err)
+		require.Contains(t, docstring, "Does something useful.")
+		value.Free()
+
+		// Anonymous functions have no docstring
+		err = vm.Execute(ctx, `(test-docstring (fn []))`)
+		require.NoError(t, err)
+		docstring, err = vm.Docstring(ctx, value)
+		require.NoError(t, err)
+		require.Empty(t, docstring)
+		value.Free()
+	})
+
+	t.Run("symbols", func(t *testing.T) {
+		symbols := vm.Symbols()
+		require.Contains(t, symbols, "test")
+		require.Contains(t, symbols, "test-callback")
+	})
+
 	t.Run("translation", func(t *testing.T) {
 		initJanet()
 		defer deInitJanet()
@@ -287,6 +425,14 @@ func TestVM(t *testing.T) {
 		}
 		cmp(t, vm, structValue)
 
+		// pointers
+		type Optional struct {
+			Present *int
+			Missing *int
+		}
+		present := 2
+		cmp(t, vm, Optional{Present: &present})
+
 		before, err := vm.marshal(structValue)
 		require.NoError(t, err)
 
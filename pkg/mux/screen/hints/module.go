@@ -0,0 +1,175 @@
+package hints
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cfoust/cy/pkg/geom"
+	"github.com/cfoust/cy/pkg/geom/tty"
+	"github.com/cfoust/cy/pkg/taro"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// A Hint describes one way to complete the key sequence the user is typing.
+type Hint struct {
+	// The keys that complete the sequence.
+	Keys []string
+	// A short description of the action that will run.
+	Description string
+	Tag         string
+}
+
+// Hints replaces the hints that are shown. A Hints with no Hints hides them.
+type Hints struct {
+	// Identifies this set of hints. Hints with an ID lower than one that
+	// was already received are ignored, which means that hints computed
+	// asynchronously cannot replace newer ones.
+	ID int
+	// The keys the user has typed so far.
+	Prefix []string
+	Hints  []Hint
+	// How long to wait before showing the hints.
+	Delay time.Duration
+}
+
+type showHints struct {
+	id int
+}
+
+type Hinter struct {
+	render *taro.Renderer
+
+	id      int
+	visible bool
+	current Hints
+}
+
+var _ taro.Model = (*Hinter)(nil)
+
+func (h *Hinter) Init() taro.Cmd {
+	return nil
+}
+
+func (h *Hinter) Update(msg tea.Msg) (taro.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case showHints:
+		if msg.id == h.id && len(h.current.Hints) > 0 {
+			h.visible = true
+		}
+		return h, nil
+	case Hints:
+		if msg.ID < h.id {
+			return h, nil
+		}
+
+		h.id = msg.ID
+		h.current = msg
+		h.visible = false
+
+		if len(msg.Hints) == 0 {
+			return h, nil
+		}
+
+		if msg.Delay <= 0 {
+			h.visible = true
+			return h, nil
+		}
+
+		return h, func() tea.Msg {
+			time.Sleep(msg.Delay)
+			return showHints{id: msg.ID}
+		}
+	}
+
+	return h, nil
+}
+
+// The maximum width of the hint box, including its border.
+const HINT_WIDTH = 60
+
+func (h *Hinter) View(state *tty.State) {
+	if !h.visible {
+		return
+	}
+
+	size := state.Image.Size()
+	width := geom.Min(HINT_WIDTH, size.C-2)
+	// Leave room for the border and the title
+	maxRows := size.R - 3
+	if width < 10 || maxRows < 1 {
+		return
+	}
+
+	hints := h.current.Hints
+	var more int
+	if len(hints) > maxRows {
+		more = len(hints) - maxRows + 1
+		hints = hints[:maxRows-1]
+	}
+
+	keyWidth := 0
+	for _, hint := range hints {
+		keyWidth = geom.Max(keyWidth, len(strings.Join(hint.Keys, " ")))
+	}
+
+	keyStyle := h.render.NewStyle().
+		Foreground(lipgloss.Color("6")).
+		Width(keyWidth + 2)
+	tagStyle := h.render.NewStyle().
+		Foreground(lipgloss.Color("8"))
+
+	// The width of the content inside of the border
+	inner := width - 2
+	var lines []string
+	for _, hint := range hints {
+		description := hint.Description
+		if len(hint.Tag) > 0 {
+			description += " " + tagStyle.Render("["+hint.Tag+"]")
+		}
+
+		line := keyStyle.Render(strings.Join(hint.Keys, " ")) + description
+		lines = append(lines, h.render.NewStyle().
+			MaxWidth(inner).
+			Render(line),
+		)
+	}
+
+	if more > 0 {
+		lines = append(lines, tagStyle.Render(
+			fmt.Sprintf("... and %d more", more),
+		))
+	}
+
+	title := h.render.NewStyle().
+		Bold(true).
+		Render(strings.Join(h.current.Prefix, " "))
+
+	box := h.render.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("6")).
+		Background(lipgloss.Color("0")).
+		BorderBackground(lipgloss.Color("0")).
+		Width(inner).
+		Render(lipgloss.JoinVertical(
+			lipgloss.Left,
+			append([]string{title}, lines...)...,
+		))
+
+	boxSize := lipgloss.Height(box)
+	h.render.RenderAt(
+		state.Image,
+		geom.Max(size.R-boxSize, 0),
+		geom.Max(size.C-width-1, 0),
+		box,
+	)
+}
+
+func New(ctx context.Context) *taro.Program {
+	return taro.New(ctx, &Hinter{
+		render: taro.NewRenderer(),
+	})
+}
@@ -0,0 +1,32 @@
+package hints
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cfoust/cy/pkg/taro"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHinter(t *testing.T) {
+	h := &Hinter{render: taro.NewRenderer()}
+	hints := []Hint{{Keys: []string{"x"}, Description: "test"}}
+
+	// Hints are shown after the delay
+	_, cmd := h.Update(Hints{ID: 1, Hints: hints, Delay: time.Millisecond})
+	require.False(t, h.visible)
+	require.NotNil(t, cmd)
+	h.Update(cmd())
+	require.True(t, h.visible)
+
+	// Hints that are out of date are ignored
+	h.Update(Hints{ID: 0})
+	require.True(t, h.visible)
+
+	// Empty hints hide the overlay, even if it was about to be shown
+	_, cmd = h.Update(Hints{ID: 2, Hints: hints, Delay: time.Millisecond})
+	h.Update(Hints{ID: 3})
+	h.Update(cmd())
+	require.False(t, h.visible)
+}
@@ -1,6 +1,7 @@
 package screen
 
 import (
+	"github.com/cfoust/cy/pkg/emu"
 	"github.com/cfoust/cy/pkg/mux"
 )
 
@@ -8,3 +9,9 @@ type Screen = mux.Screen
 type Stream = mux.Stream
 type Updater = mux.Updater
 type Size = mux.Size
+
+// Themeable is implemented by Screens that contain a terminal emulator,
+// which reports the colors in the theme to programs that ask for them.
+type Themeable interface {
+	SetTheme(theme emu.Theme)
+}
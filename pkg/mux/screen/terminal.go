@@ -1,19 +1,37 @@
 package screen
 
 import (
+	"bytes"
 	"context"
 	"io"
+	"time"
 
 	"github.com/cfoust/cy/pkg/emu"
 	"github.com/cfoust/cy/pkg/geom/tty"
 	"github.com/cfoust/cy/pkg/mux"
 	"github.com/cfoust/cy/pkg/taro"
+
+	"github.com/sasha-s/go-deadlock"
 )
 
+// SYNC_TIMEOUT is the maximum amount of time a program can hold off
+// updates to the screen with a synchronized update.
+const SYNC_TIMEOUT = 200 * time.Millisecond
+
 type Terminal struct {
 	*mux.UpdatePublisher
+	deadlock.RWMutex
 	terminal emu.Terminal
 	stream   Stream
+
+	// Whether the program is in the middle of a synchronized update.
+	syncing bool
+	// Whether the current synchronized update took too long.
+	syncExpired bool
+	syncTimer   *time.Timer
+	// The most recent state of the terminal before the synchronized
+	// update began.
+	lastState *tty.State
 }
 
 var _ Screen = (*Terminal)(nil)
@@ -23,7 +41,55 @@ func (t *Terminal) Kill() {
 }
 
 func (t *Terminal) State() *tty.State {
-	return tty.Capture(t.terminal)
+	t.Lock()
+	defer t.Unlock()
+
+	if t.syncing && t.lastState != nil {
+		return t.lastState
+	}
+
+	state := tty.Capture(t.terminal)
+	t.lastState = state
+	return state
+}
+
+// beginSync holds off updates until the program finishes its synchronized
+// update or SYNC_TIMEOUT elapses. It returns true if updates should not be
+// published.
+func (t *Terminal) beginSync() bool {
+	t.Lock()
+	defer t.Unlock()
+
+	if t.syncExpired {
+		return false
+	}
+
+	if t.syncing {
+		return true
+	}
+
+	t.syncing = true
+	t.syncTimer = time.AfterFunc(SYNC_TIMEOUT, func() {
+		t.Lock()
+		t.syncing = false
+		t.syncExpired = true
+		t.Unlock()
+		t.Notify()
+	})
+	return true
+}
+
+func (t *Terminal) endSync() {
+	t.Lock()
+	defer t.Unlock()
+
+	if t.syncTimer != nil {
+		t.syncTimer.Stop()
+		t.syncTimer = nil
+	}
+
+	t.syncing = false
+	t.syncExpired = false
 }
 
 func (t *Terminal) Resize(size Size) error {
@@ -42,6 +108,12 @@ func (t *Terminal) IsAltMode() bool {
 	return t.terminal.IsAltMode()
 }
 
+// SetTheme changes the colors reported to programs that query the
+// terminal's palette.
+func (t *Terminal) SetTheme(theme emu.Theme) {
+	t.terminal.SetTheme(theme)
+}
+
 func (t *Terminal) Send(msg mux.Msg) {
 	input := make([]byte, 0)
 	mode := t.terminal.Mode()
@@ -49,8 +121,21 @@ func (t *Terminal) Send(msg mux.Msg) {
 	switch msg := msg.(type) {
 	case taro.KeyMsg:
 		// TODO(cfoust): 01/22/24 error handling
-		data, _ := taro.KeysToBytes(msg)
+		data, _ := taro.KittyKeysToBytes(
+			t.terminal.KeyboardFlags(),
+			msg,
+		)
 		input = data
+
+		if !msg.Paste || mode&emu.ModeBracketedPaste == 0 {
+			break
+		}
+
+		// Pasted text must not be able to end the paste early
+		data = bytes.ReplaceAll(data, []byte(emu.PasteEnd), nil)
+		input = []byte(emu.PasteStart)
+		input = append(input, data...)
+		input = append(input, []byte(emu.PasteEnd)...)
 	case taro.MouseMsg:
 		switch mode & emu.ModeMouseMask {
 		case emu.ModeMouseX10:
@@ -86,6 +171,14 @@ func (t *Terminal) Write(p []byte) (n int, err error) {
 		return 0, err
 	}
 
+	if t.terminal.Mode()&emu.ModeSyncUpdate != 0 {
+		if t.beginSync() {
+			return n, err
+		}
+	} else {
+		t.endSync()
+	}
+
 	// Let any clients know that this pane changed
 	t.Notify()
 
@@ -8,20 +8,56 @@ import (
 	"syscall"
 
 	"github.com/cfoust/cy/pkg/geom"
+	"github.com/cfoust/cy/pkg/geom/tty"
 	"github.com/cfoust/cy/pkg/mux"
+	"github.com/cfoust/cy/pkg/taro"
 
 	"github.com/muesli/termenv"
 	"github.com/xo/terminfo"
 	"golang.org/x/term"
 )
 
+type attachOptions struct {
+	input io.Reader
+	caps  *tty.Capabilities
+}
+
+type AttachOption func(*attachOptions)
+
+// WithInput reads the user's input from `input` rather than the `in`
+// provided to Attach. This is necessary after calling Probe.
+func WithInput(input io.Reader) AttachOption {
+	return func(options *attachOptions) {
+		options.input = input
+	}
+}
+
+// WithCapabilities only enables the terminal features that the terminal
+// is known to support. By default, Attach enables all of them.
+func WithCapabilities(caps tty.Capabilities) AttachOption {
+	return func(options *attachOptions) {
+		options.caps = &caps
+	}
+}
+
 // Attach connects a mux.Stream to a tty defined by `in` and `out`, which are
 // typically `os.Stdin` and `os.Stdout`.
 func Attach(
 	ctx context.Context,
 	stream mux.Stream,
 	in, out *os.File,
+	opts ...AttachOption,
 ) error {
+	options := attachOptions{input: in}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	// Terminals that were not probed may still support these features
+	caps := options.caps
+	useKittyKeys := caps == nil || !caps.Probed || caps.KittyKeyboard
+	usePaste := caps == nil || !caps.Probed || caps.BracketedPaste
+
 	output := termenv.NewOutput(out)
 
 	info, err := terminfo.LoadFromEnv()
@@ -31,6 +67,12 @@ func Attach(
 
 	output.AltScreen()
 	output.EnableMouseAllMotion()
+	if usePaste {
+		output.EnableBracketedPaste()
+	}
+	if useKittyKeys {
+		out.WriteString(taro.EnableKittyKeys)
+	}
 	oldState, err := term.MakeRaw(int(in.Fd()))
 	if err != nil {
 		return err
@@ -38,11 +80,19 @@ func Attach(
 	defer func() {
 		output.ExitAltScreen()
 		output.DisableMouseAllMotion()
+		if usePaste {
+			output.DisableBracketedPaste()
+		}
+		if useKittyKeys {
+			out.WriteString(taro.DisableKittyKeys)
+		}
 		info.Fprintf(out, terminfo.CursorVisible)
+		// The client's theme may have changed the cursor color
+		out.Write(tty.SetCursorColor(nil))
 		term.Restore(int(in.Fd()), oldState)
 	}()
 
-	go func() { _, _ = io.Copy(stream, in) }()
+	go func() { _, _ = io.Copy(stream, options.input) }()
 	go func() { _, _ = io.Copy(out, stream) }()
 
 	// Handle window size changes
@@ -0,0 +1,132 @@
+package cli
+
+import (
+	"io"
+	"os"
+	"time"
+
+	"github.com/cfoust/cy/pkg/geom/tty"
+
+	"github.com/muesli/termenv"
+	"golang.org/x/term"
+)
+
+const (
+	// PROBE_TIMEOUT is the amount of time Probe waits for the terminal
+	// to respond before giving up.
+	PROBE_TIMEOUT = 1 * time.Second
+	// PROBE_GRACE is the amount of time after PROBE_TIMEOUT during which
+	// responses that arrive late are still removed from the input.
+	PROBE_GRACE = 5 * time.Second
+)
+
+// probeReader yields the input read by Probe that was not part of the
+// terminal's response followed by anything the user types afterwards.
+type probeReader struct {
+	pending []byte
+	chunks  chan []byte
+	// If non-zero, responses to the probe are removed from input read
+	// before this time.
+	filterUntil time.Time
+}
+
+var _ io.Reader = (*probeReader)(nil)
+
+func newProbeReader(in io.Reader) *probeReader {
+	reader := &probeReader{
+		chunks: make(chan []byte),
+	}
+
+	go func() {
+		defer close(reader.chunks)
+		for {
+			buffer := make([]byte, 4096)
+			n, err := in.Read(buffer)
+			if n > 0 {
+				reader.chunks <- buffer[:n]
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	return reader
+}
+
+func (p *probeReader) Read(b []byte) (n int, err error) {
+	for len(p.pending) == 0 {
+		chunk, ok := <-p.chunks
+		if !ok {
+			return 0, io.EOF
+		}
+
+		if time.Now().Before(p.filterUntil) {
+			var done bool
+			chunk, done = tty.TrimProbeResponses(chunk)
+			if done {
+				p.filterUntil = time.Time{}
+			}
+		}
+
+		p.pending = chunk
+	}
+
+	n = copy(b, p.pending)
+	p.pending = p.pending[n:]
+	return n, nil
+}
+
+// Probe queries the terminal connected to `in` and `out` to determine
+// which features it supports. If the terminal does not respond in time,
+// the returned Capabilities will not be marked as probed.
+//
+// The user might type while Probe is waiting for a response, so Probe
+// also returns a reader that must be used in place of `in` afterwards.
+func Probe(
+	in, out *os.File,
+	profile termenv.Profile,
+) (tty.Capabilities, io.Reader, error) {
+	caps := tty.Capabilities{Profile: profile}
+
+	oldState, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return caps, in, err
+	}
+	defer term.Restore(int(in.Fd()), oldState)
+
+	_, err = out.WriteString(tty.ProbeQuery)
+	if err != nil {
+		return caps, in, err
+	}
+
+	input := newProbeReader(in)
+	timeout := time.After(PROBE_TIMEOUT)
+
+	var response []byte
+	for {
+		select {
+		case chunk, ok := <-input.chunks:
+			if !ok {
+				return caps, input, nil
+			}
+
+			response = append(response, chunk...)
+			probed, n := tty.ParseCapabilities(response)
+			if n == -1 {
+				continue
+			}
+
+			probed.Profile = profile
+			input.pending = response[n:]
+			return probed, input, nil
+		case <-timeout:
+			// Whatever we received is most likely part of a
+			// response, which we do not want to treat as input.
+			// The rest of the response may still arrive, so keep
+			// removing it from the input for a while.
+			input.filterUntil = time.Now().Add(PROBE_GRACE)
+			return caps, input, nil
+		}
+	}
+}
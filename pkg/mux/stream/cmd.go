@@ -75,6 +75,13 @@ func (c *Cmd) GetStatus() CmdStatus {
 	return status
 }
 
+// Options returns the options the Cmd was created with.
+func (c *Cmd) Options() CmdOptions {
+	c.RLock()
+	defer c.RUnlock()
+	return c.options
+}
+
 func (c *Cmd) Path() (string, error) {
 	c.RLock()
 	proc := c.proc
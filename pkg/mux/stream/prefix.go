@@ -0,0 +1,38 @@
+package stream
+
+import (
+	"github.com/sasha-s/go-deadlock"
+)
+
+// Prefixed is a Stream that produces some fixed data before anything read
+// from the Stream it wraps.
+type Prefixed struct {
+	Stream
+
+	lock   deadlock.Mutex
+	prefix []byte
+}
+
+var _ Stream = (*Prefixed)(nil)
+
+func (p *Prefixed) Read(data []byte) (n int, err error) {
+	p.lock.Lock()
+	if len(p.prefix) > 0 {
+		n = copy(data, p.prefix)
+		p.prefix = p.prefix[n:]
+		p.lock.Unlock()
+		return n, nil
+	}
+	p.lock.Unlock()
+
+	return p.Stream.Read(data)
+}
+
+// NewPrefixed returns a Stream that reads `prefix` and then whatever `stream`
+// produces. Writes and resizes go directly to `stream`.
+func NewPrefixed(stream Stream, prefix []byte) *Prefixed {
+	return &Prefixed{
+		Stream: stream,
+		prefix: prefix,
+	}
+}
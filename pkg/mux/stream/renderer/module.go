@@ -11,6 +11,7 @@ import (
 	"github.com/cfoust/cy/pkg/mux/screen"
 	"github.com/cfoust/cy/pkg/taro"
 
+	"github.com/sasha-s/go-deadlock"
 	"github.com/xo/terminfo"
 )
 
@@ -27,6 +28,20 @@ type Renderer struct {
 	r      *io.PipeReader
 	w      *io.PipeWriter
 	info   *terminfo.Terminfo
+
+	// The features supported by the client's terminal.
+	caps tty.Capabilities
+	// graphics draws the images on the screen, if the client supports
+	// them.
+	graphics *tty.Graphics
+
+	themeLock deadlock.Mutex
+	// The colors the client's terminal should use in place of its own.
+	theme emu.Theme
+	// Whether the theme changed since the last frame.
+	themeChanged bool
+	// Wakes up poll when something other than the screen changes.
+	refresh chan struct{}
 }
 
 var _ mux.Stream = (*Renderer)(nil)
@@ -44,9 +59,33 @@ func (r *Renderer) Resize(size geom.Size) error {
 	r.raw.Resize(size)
 	r.clearScreen(r.raw)
 	r.clearScreen(r.w)
+	r.graphics.Reset()
 	return r.screen.Resize(size)
 }
 
+// SetTheme changes the colors used to draw the screen. The theme is applied
+// to the screen before it is sent to the client, so it works on any
+// terminal that supports true color.
+func (r *Renderer) SetTheme(theme emu.Theme) {
+	r.themeLock.Lock()
+	r.theme = theme
+	r.themeChanged = true
+	r.themeLock.Unlock()
+
+	select {
+	case r.refresh <- struct{}{}:
+	default:
+	}
+}
+
+func (r *Renderer) getTheme() (theme emu.Theme, changed bool) {
+	r.themeLock.Lock()
+	defer r.themeLock.Unlock()
+	theme, changed = r.theme, r.themeChanged
+	r.themeChanged = false
+	return
+}
+
 func (r *Renderer) Send(msg mux.Msg) {
 	r.screen.Send(msg)
 }
@@ -69,12 +108,30 @@ func (r *Renderer) poll(ctx context.Context) error {
 	subscriber := r.screen.Subscribe(ctx)
 
 	for {
-		changes := tty.Swap(
-			r.info,
-			tty.Capture(r.raw),
-			r.screen.State(),
-		)
+		theme, themeChanged := r.getTheme()
+		dst := tty.Capture(r.raw)
+		src := tty.ApplyTheme(theme, r.screen.State())
+		changes := tty.Swap(r.info, r.caps, dst, src)
 		r.raw.Write(changes)
+
+		if themeChanged {
+			changes = append(changes, tty.SetCursorColor(theme)...)
+		}
+
+		// Images are not sent to the raw terminal, which only tracks
+		// the placeholders drawn beneath them
+		changes = append(
+			changes,
+			r.graphics.Update(r.info, r.caps, dst, src)...,
+		)
+
+		// Prevent the client from drawing partial frames
+		if r.caps.SyncUpdate {
+			frame := []byte(emu.BeginSyncUpdate)
+			frame = append(frame, changes...)
+			changes = append(frame, []byte(emu.EndSyncUpdate)...)
+		}
+
 		_, err := r.w.Write(changes)
 		if err != nil {
 			return err
@@ -85,26 +142,46 @@ func (r *Renderer) poll(ctx context.Context) error {
 			return nil
 		case <-subscriber.Recv():
 			continue
+		case <-r.refresh:
+			continue
 		}
 	}
 }
 
+type RendererOption func(*Renderer)
+
+// WithCapabilities describes the features supported by the client's
+// terminal, which the Renderer uses to adapt its output. By default, the
+// Renderer assumes the client supports true color but not images.
+func WithCapabilities(caps tty.Capabilities) RendererOption {
+	return func(r *Renderer) {
+		r.caps = caps
+	}
+}
+
 func NewRenderer(
 	ctx context.Context,
 	info *terminfo.Terminfo,
 	initialSize geom.Size,
 	screen mux.Screen,
+	options ...RendererOption,
 ) *Renderer {
 	r, w := io.Pipe()
 	target := emu.New(emu.WithSize(initialSize))
 	screen.Resize(initialSize)
 	renderer := &Renderer{
-		raw:    target,
-		screen: screen,
-		r:      r,
-		w:      w,
-		info:   info,
+		raw:     target,
+		screen:  screen,
+		r:       r,
+		w:       w,
+		info:    info,
+		refresh: make(chan struct{}, 1),
+	}
+
+	for _, option := range options {
+		option(renderer)
 	}
+	renderer.graphics = tty.NewGraphics(renderer.caps.Protocol())
 
 	go renderer.poll(ctx)
 
@@ -29,6 +29,10 @@ type defaultParams struct {
 	// (input/find). If this is an empty array, all built-in animations
 	// will be enabled.
 	Animations []string
+	// The maximum number of pieces of copied text that are kept in the
+	// copy history, which is shared by all clients. If set to 0, the copy
+	// history is disabled.
+	CopyHistory int
 	// The directory in which .borg files will be saved. This is [inferred
 	// on startup](replay-mode.md#recording-terminal-sessions-to-disk). If
 	// set to an empty string, recording to disk is disabled.
@@ -39,6 +43,16 @@ type defaultParams struct {
 	// The frame used for all new clients. A blank string means a random
 	// frame will be chosen from all frames.
 	DefaultFrame string
+	// Whether to show a list of the keys that can complete a key sequence
+	// after you begin typing one.
+	ShowHints bool
+	// The number of milliseconds to wait after a key in a sequence is
+	// pressed before showing hints.
+	HintDelay int
+	// The maximum number of lines of scrollback that new panes keep in
+	// memory. Once the limit is reached, the oldest lines are discarded.
+	// If set to 0, the scrollback buffer grows without bound.
+	ScrollbackLines int
 	// Whether to avoid blocking on (input/*) calls. Just for testing.
 	skipInput bool
 }
@@ -46,9 +60,12 @@ type defaultParams struct {
 var (
 	defaults = defaultParams{
 		Animate:       true,
+		CopyHistory:   50,
 		DataDirectory: "",
 		DefaultFrame:  "",
 		DefaultShell:  "/bin/bash",
+		ShowHints:     true,
+		HintDelay:     300,
 		skipInput:     false,
 	}
 )
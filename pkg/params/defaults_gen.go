@@ -8,12 +8,16 @@ import (
 )
 
 const (
-	ParamAnimate       = "animate"
-	ParamAnimations    = "animations"
-	ParamDataDirectory = "data-directory"
-	ParamDefaultFrame  = "default-frame"
-	ParamDefaultShell  = "default-shell"
-	ParamSkipInput     = "---skip-input"
+	ParamAnimate         = "animate"
+	ParamAnimations      = "animations"
+	ParamCopyHistory     = "copy-history"
+	ParamDataDirectory   = "data-directory"
+	ParamDefaultFrame    = "default-frame"
+	ParamDefaultShell    = "default-shell"
+	ParamHintDelay       = "hint-delay"
+	ParamScrollbackLines = "scrollback-lines"
+	ParamShowHints       = "show-hints"
+	ParamSkipInput       = "---skip-input"
 )
 
 func (p *Parameters) Animate() bool {
@@ -52,6 +56,24 @@ func (p *Parameters) SetAnimations(value []string) {
 	p.set(ParamAnimations, value)
 }
 
+func (p *Parameters) CopyHistory() int {
+	value, ok := p.Get(ParamCopyHistory)
+	if !ok {
+		return defaults.CopyHistory
+	}
+
+	realValue, ok := value.(int)
+	if !ok {
+		return defaults.CopyHistory
+	}
+
+	return realValue
+}
+
+func (p *Parameters) SetCopyHistory(value int) {
+	p.set(ParamCopyHistory, value)
+}
+
 func (p *Parameters) DataDirectory() string {
 	value, ok := p.Get(ParamDataDirectory)
 	if !ok {
@@ -106,6 +128,60 @@ func (p *Parameters) SetDefaultShell(value string) {
 	p.set(ParamDefaultShell, value)
 }
 
+func (p *Parameters) HintDelay() int {
+	value, ok := p.Get(ParamHintDelay)
+	if !ok {
+		return defaults.HintDelay
+	}
+
+	realValue, ok := value.(int)
+	if !ok {
+		return defaults.HintDelay
+	}
+
+	return realValue
+}
+
+func (p *Parameters) SetHintDelay(value int) {
+	p.set(ParamHintDelay, value)
+}
+
+func (p *Parameters) ScrollbackLines() int {
+	value, ok := p.Get(ParamScrollbackLines)
+	if !ok {
+		return defaults.ScrollbackLines
+	}
+
+	realValue, ok := value.(int)
+	if !ok {
+		return defaults.ScrollbackLines
+	}
+
+	return realValue
+}
+
+func (p *Parameters) SetScrollbackLines(value int) {
+	p.set(ParamScrollbackLines, value)
+}
+
+func (p *Parameters) ShowHints() bool {
+	value, ok := p.Get(ParamShowHints)
+	if !ok {
+		return defaults.ShowHints
+	}
+
+	realValue, ok := value.(bool)
+	if !ok {
+		return defaults.ShowHints
+	}
+
+	return realValue
+}
+
+func (p *Parameters) SetShowHints(value bool) {
+	p.set(ParamShowHints, value)
+}
+
 func (p *Parameters) SkipInput() bool {
 	value, ok := p.Get(ParamSkipInput)
 	if !ok {
@@ -130,12 +206,20 @@ func (p *Parameters) isDefault(key string) bool {
 		return true
 	case ParamAnimations:
 		return true
+	case ParamCopyHistory:
+		return true
 	case ParamDataDirectory:
 		return true
 	case ParamDefaultFrame:
 		return true
 	case ParamDefaultShell:
 		return true
+	case ParamHintDelay:
+		return true
+	case ParamScrollbackLines:
+		return true
+	case ParamShowHints:
+		return true
 	case ParamSkipInput:
 		return true
 
@@ -184,6 +268,25 @@ func (p *Parameters) setDefault(key string, value interface{}) error {
 		p.set(key, translated)
 		return nil
 
+	case ParamCopyHistory:
+		if !janetOk {
+			realValue, ok := value.(int)
+			if !ok {
+				return fmt.Errorf("invalid value for ParamCopyHistory, should be int")
+			}
+			p.set(key, realValue)
+			return nil
+		}
+
+		var translated int
+		err := janetValue.Unmarshal(&translated)
+		if err != nil {
+			janetValue.Free()
+			return fmt.Errorf("invalid value for :copy-history: %s", err)
+		}
+		p.set(key, translated)
+		return nil
+
 	case ParamDataDirectory:
 		if !janetOk {
 			realValue, ok := value.(string)
@@ -241,6 +344,63 @@ func (p *Parameters) setDefault(key string, value interface{}) error {
 		p.set(key, translated)
 		return nil
 
+	case ParamHintDelay:
+		if !janetOk {
+			realValue, ok := value.(int)
+			if !ok {
+				return fmt.Errorf("invalid value for ParamHintDelay, should be int")
+			}
+			p.set(key, realValue)
+			return nil
+		}
+
+		var translated int
+		err := janetValue.Unmarshal(&translated)
+		if err != nil {
+			janetValue.Free()
+			return fmt.Errorf("invalid value for :hint-delay: %s", err)
+		}
+		p.set(key, translated)
+		return nil
+
+	case ParamScrollbackLines:
+		if !janetOk {
+			realValue, ok := value.(int)
+			if !ok {
+				return fmt.Errorf("invalid value for ParamScrollbackLines, should be int")
+			}
+			p.set(key, realValue)
+			return nil
+		}
+
+		var translated int
+		err := janetValue.Unmarshal(&translated)
+		if err != nil {
+			janetValue.Free()
+			return fmt.Errorf("invalid value for :scrollback-lines: %s", err)
+		}
+		p.set(key, translated)
+		return nil
+
+	case ParamShowHints:
+		if !janetOk {
+			realValue, ok := value.(bool)
+			if !ok {
+				return fmt.Errorf("invalid value for ParamShowHints, should be bool")
+			}
+			p.set(key, realValue)
+			return nil
+		}
+
+		var translated bool
+		err := janetValue.Unmarshal(&translated)
+		if err != nil {
+			janetValue.Free()
+			return fmt.Errorf("invalid value for :show-hints: %s", err)
+		}
+		p.set(key, translated)
+		return nil
+
 	case ParamSkipInput:
 		if !janetOk {
 			realValue, ok := value.(bool)
@@ -269,6 +429,11 @@ func init() {
 			Docstring: "A list of all of the enabled animations that will be used by\n(input/find). If this is an empty array, all built-in animations\nwill be enabled.",
 			Default:   defaults.Animations,
 		},
+		{
+			Name:      "copy-history",
+			Docstring: "The maximum number of pieces of copied text that are kept in the\ncopy history, which is shared by all clients. If set to 0, the copy\nhistory is disabled.",
+			Default:   defaults.CopyHistory,
+		},
 		{
 			Name:      "data-directory",
 			Docstring: "The directory in which .borg files will be saved. This is [inferred\non startup](replay-mode.md#recording-terminal-sessions-to-disk). If\nset to an empty string, recording to disk is disabled.",
@@ -284,5 +449,20 @@ func init() {
 			Docstring: "The default shell with which to start panes. Defaults to the value\nof `$SHELL` on startup.",
 			Default:   defaults.DefaultShell,
 		},
+		{
+			Name:      "hint-delay",
+			Docstring: "The number of milliseconds to wait after a key in a sequence is\npressed before showing hints.",
+			Default:   defaults.HintDelay,
+		},
+		{
+			Name:      "scrollback-lines",
+			Docstring: "The maximum number of lines of scrollback that new panes keep in\nmemory. Once the limit is reached, the oldest lines are discarded.\nIf set to 0, the scrollback buffer grows without bound.",
+			Default:   defaults.ScrollbackLines,
+		},
+		{
+			Name:      "show-hints",
+			Docstring: "Whether to show a list of the keys that can complete a key sequence\nafter you begin typing one.",
+			Default:   defaults.ShowHints,
+		},
 	}
 }
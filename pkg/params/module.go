@@ -10,19 +10,29 @@ type Parameters struct {
 	deadlock.RWMutex
 	parent *Parameters
 	table  map[string]interface{}
+
+	// The number of Snapshots of these parameters that have not been
+	// restored or released yet.
+	snapshots int
+	// Janet values that were replaced while a Snapshot was outstanding.
+	// They cannot be freed until we know that no Snapshot will restore
+	// them.
+	retained []*janet.Value
 }
 
 func (p *Parameters) set(key string, value interface{}) error {
 	p.Lock()
 	existing, ok := p.table[key]
 	p.table[key] = value
-	p.Unlock()
 
-	if !ok {
-		return nil
+	janetValue, isJanet := existing.(*janet.Value)
+	if ok && isJanet && p.snapshots > 0 {
+		p.retained = append(p.retained, janetValue)
+		isJanet = false
 	}
+	p.Unlock()
 
-	if janetValue, ok := existing.(*janet.Value); ok {
+	if ok && isJanet {
 		janetValue.Free()
 	}
 
@@ -54,6 +64,115 @@ func (p *Parameters) Get(key string) (value interface{}, ok bool) {
 	return nil, false
 }
 
+// Local returns a copy of the parameters set directly on `p`, ignoring any
+// that are inherited from its parents.
+func (p *Parameters) Local() map[string]interface{} {
+	p.RLock()
+	defer p.RUnlock()
+
+	local := make(map[string]interface{}, len(p.table))
+	for key, value := range p.table {
+		local[key] = value
+	}
+
+	return local
+}
+
+// A Snapshot records the parameters set directly on a Parameters so that they
+// can be restored later. Every Snapshot must be either restored or released.
+type Snapshot struct {
+	params *Parameters
+	table  map[string]interface{}
+}
+
+// Snapshot captures the parameters set directly on `p`.
+func (p *Parameters) Snapshot() *Snapshot {
+	p.Lock()
+	defer p.Unlock()
+
+	p.snapshots++
+
+	table := make(map[string]interface{}, len(p.table))
+	for key, value := range p.table {
+		table[key] = value
+	}
+
+	return &Snapshot{
+		params: p,
+		table:  table,
+	}
+}
+
+// finish marks the Snapshot as complete and frees any Janet values that are
+// no longer referenced. The caller must hold the lock of the Parameters.
+func (s *Snapshot) finish() (unused []*janet.Value) {
+	p := s.params
+	if s.table == nil {
+		return
+	}
+	s.table = nil
+
+	p.snapshots--
+	if p.snapshots > 0 {
+		return
+	}
+
+	live := make(map[*janet.Value]struct{})
+	for _, value := range p.table {
+		if janetValue, ok := value.(*janet.Value); ok {
+			live[janetValue] = struct{}{}
+		}
+	}
+
+	for _, value := range p.retained {
+		if _, ok := live[value]; ok {
+			continue
+		}
+		live[value] = struct{}{}
+		unused = append(unused, value)
+	}
+	p.retained = nil
+	return
+}
+
+// Restore replaces the parameters set directly on the Parameters with the
+// ones that were set when the Snapshot was taken.
+func (s *Snapshot) Restore() {
+	p := s.params
+	p.Lock()
+	if s.table == nil {
+		p.Unlock()
+		return
+	}
+
+	// Values set after the Snapshot was taken might not be referenced
+	// anymore
+	for _, value := range p.table {
+		if janetValue, ok := value.(*janet.Value); ok {
+			p.retained = append(p.retained, janetValue)
+		}
+	}
+	p.table = s.table
+	unused := s.finish()
+	p.Unlock()
+
+	for _, value := range unused {
+		value.Free()
+	}
+}
+
+// Release discards the Snapshot, keeping the current parameters.
+func (s *Snapshot) Release() {
+	p := s.params
+	p.Lock()
+	unused := s.finish()
+	p.Unlock()
+
+	for _, value := range unused {
+		value.Free()
+	}
+}
+
 func (p *Parameters) NewChild() *Parameters {
 	child := New()
 	child.parent = p
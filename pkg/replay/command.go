@@ -104,6 +104,7 @@ func (r *Replay) jumpSelectCommand(isForward bool) (taro.Model, tea.Cmd) {
 		return r, nil
 	}
 	r.isSelecting = true
+	r.selectMode = SelectChar
 	r.selectStart = command.Output.To
 	r.movement.Goto(command.Output.From)
 	return r, nil
@@ -28,6 +28,8 @@ type PlaybackRateEvent struct {
 const (
 	PLAYBACK_FPS   = 30
 	IDLE_THRESHOLD = time.Second
+	// The largest count that can precede a motion
+	MAX_COUNT = 9999
 )
 
 type PlaybackEvent struct {
@@ -36,6 +38,8 @@ type PlaybackEvent struct {
 
 type CopyEvent struct {
 	Text string
+	// The register the text should be copied into, if any
+	Register string
 }
 
 type Mode uint8
@@ -50,6 +54,22 @@ const (
 	ModeInput
 )
 
+// SelectMode determines which cells are included in a selection.
+type SelectMode uint8
+
+const (
+	// SelectChar selects every cell between the start of the selection
+	// and the cursor, like vim's "v". In image mode it selects a
+	// rectangle instead.
+	SelectChar SelectMode = iota
+	// SelectLine selects every line between the start of the selection
+	// and the cursor, like vim's "V".
+	SelectLine
+	// SelectBlock selects the rectangle with the start of the selection
+	// and the cursor at its corners, like vim's "ctrl+v".
+	SelectBlock
+)
+
 const (
 	ActionQuit ActionType = iota
 
@@ -157,6 +177,8 @@ const (
 	// rectangle-off
 	// rectangle-toggle                             v               R
 	ActionSelect
+	// (vim's ctrl+v)
+	ActionSelectBlock
 	// refresh-from-pane                            r               r
 	// scroll-down                                  C-e             C-Down
 	ActionScrollDown
@@ -172,6 +194,7 @@ const (
 	// search-forward-text <for>
 	// search-reverse                               N               N
 	// select-line                                  V
+	ActionSelectLine
 	// select-word
 	// set-mark                                     X               X
 	// start-of-line                                0               C-a
@@ -232,6 +255,29 @@ const (
 	ActionBigWordEndForward
 	// gE
 	ActionBigWordEndBackward
+
+	// Counts and text objects
+	//////////////////////////
+	// [1-9], and 0 after another digit
+	ActionCount
+	// y
+	ActionYank
+	// i{object}
+	ActionInnerObject
+	// a{object}
+	ActionAroundObject
+	// "{register}
+	ActionRegister
+
+	// Marks
+	////////
+	// m{a-z}
+	ActionMark
+	// '{a-z}
+	ActionJumpMark
+
+	// Show or hide the timeline
+	ActionToggleTimeline
 )
 
 var MOTIONS = map[ActionType]motion.Motion{
@@ -1,6 +1,8 @@
 package detect
 
 import (
+	"strings"
+
 	"github.com/cfoust/cy/pkg/emu"
 	"github.com/cfoust/cy/pkg/geom"
 	"github.com/cfoust/cy/pkg/sessions/search"
@@ -30,6 +32,13 @@ type Command struct {
 	Executed int
 	// The event at which the command finished executing (its output ended)
 	Completed int
+
+	// The working directory of the shell when the command was entered, if
+	// the prompt reported it
+	Directory string
+	// The exit code of the command, if the prompt that followed it
+	// reported one
+	ExitCode *int
 }
 
 func (c Command) InputStart() geom.Vec2 {
@@ -39,3 +48,62 @@ func (c Command) InputStart() geom.Vec2 {
 
 	return c.Input[0].From
 }
+
+// shiftVec moves `v` up by `delta` rows. Locations in rows that no longer
+// exist are clamped to the beginning of the first row.
+func shiftVec(v geom.Vec2, delta int) geom.Vec2 {
+	v.R -= delta
+	if v.R < 0 {
+		return geom.Vec2{}
+	}
+	return v
+}
+
+// shift moves the locations in `c` up by `delta` rows, which is necessary
+// when lines are discarded from the beginning of the scrollback buffer.
+// Input on discarded lines is removed and output is clamped to the lines
+// that remain.
+func (c Command) shift(delta int) Command {
+	input := make([]search.Selection, 0, len(c.Input))
+	for _, selection := range c.Input {
+		if selection.From.R < delta {
+			continue
+		}
+
+		input = append(input, search.Selection{
+			From: shiftVec(selection.From, delta),
+			To:   shiftVec(selection.To, delta),
+		})
+	}
+	c.Input = input
+
+	c.Output.From = shiftVec(c.Output.From, delta)
+	c.Output.To = shiftVec(c.Output.To, delta)
+	return c
+}
+
+// Output returns the lines of output produced by `command`. `term` must be
+// the terminal in which the command was detected.
+func Output(term emu.Terminal, command Command) (lines []string) {
+	from, to := command.Output.From, command.Output.To
+	if !from.LT(to) {
+		return
+	}
+
+	for i, line := range term.GetLines(from.R, to.R) {
+		row := from.R + i
+
+		// The end of the selection is exclusive
+		if row == to.R {
+			line = line[:geom.Clamp(to.C, 0, len(line))]
+		}
+
+		if row == from.R {
+			line = line[geom.Clamp(from.C, 0, len(line)):]
+		}
+
+		lines = append(lines, strings.TrimRight(line.String(), " "))
+	}
+
+	return
+}
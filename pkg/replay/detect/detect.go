@@ -14,6 +14,8 @@ func (d *Detector) Detect(
 	dirty := term.Changes()
 	defer dirty.Reset()
 
+	d.updateTrimmed(term)
+
 	if term.IsAltMode() {
 		return
 	}
@@ -41,10 +43,14 @@ func (d *Detector) Detect(
 		return
 	}
 
+	params := parsePromptParams(dirty.HookParams(CY_HOOK))
+
 	from := d.from
 	fromID := d.fromID
+	fromParams := d.fromParams
 	d.from = to
 	d.fromID = toID
+	d.fromParams = params
 
 	// We do nothing on the first prompt, just make a note of it
 	if !d.havePrompt {
@@ -65,6 +71,10 @@ func (d *Detector) Detect(
 
 	command.Completed = nextPromptIndex - 1
 
+	// The prompt that follows a command reports its exit code
+	command.Directory = fromParams.directory
+	command.ExitCode = params.exitCode
+
 	ok = d.completeCommand(term, events, &command)
 	if !ok {
 		return
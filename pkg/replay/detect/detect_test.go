@@ -284,3 +284,115 @@ func TestPendingMulti(t *testing.T) {
 		"output\n",
 	)
 }
+
+// detectAll runs the detector over every event in `setup`.
+func detectAll(setup ...interface{}) (emu.Terminal, []Command) {
+	events := sessions.NewSimulator().
+		Defaults().
+		Add(setup...).
+		Events()
+
+	d := New()
+	term := emu.New()
+	term.Changes().SetHooks([]string{CY_HOOK})
+	for i, event := range events {
+		switch e := event.Message.(type) {
+		case P.OutputMessage:
+			term.Parse(e.Data)
+			d.Detect(term, events[0:i+1])
+		case P.SizeMessage:
+			term.Resize(e.Vec())
+		}
+	}
+
+	return term, d.Commands(term, events)
+}
+
+func TestPromptParams(t *testing.T) {
+	_, commands := detectAll(
+		"\033Pcy;cwd=/tmp\033\\$ ", "true\n",
+		"\033Pcy;exit=1;cwd=/home\033\\$ ", "false\n",
+		"\033Pcy;exit=foo\033\\$ ", "pending\n",
+	)
+	require.Equal(t, 3, len(commands))
+
+	first := commands[0]
+	require.Equal(t, "true", first.Text)
+	require.Equal(t, "/tmp", first.Directory)
+	require.NotNil(t, first.ExitCode)
+	require.Equal(t, 1, *first.ExitCode)
+
+	second := commands[1]
+	require.Equal(t, "/home", second.Directory)
+	// Invalid exit codes are ignored
+	require.Nil(t, second.ExitCode)
+
+	pending := commands[2]
+	require.True(t, pending.Pending)
+	require.Equal(t, "", pending.Directory)
+	require.Nil(t, pending.ExitCode)
+}
+
+func TestOutput(t *testing.T) {
+	term, commands := detectAll(
+		TEST_PROMPT, "command\n",
+		"foo\n",
+		"bar\n",
+		TEST_PROMPT, "command\n",
+		"foo\n",
+		"baz\n",
+		TEST_PROMPT,
+	)
+	require.Equal(t, 2, len(commands))
+	require.Equal(t, []string{"foo", "bar"}, Output(term, commands[0]))
+	require.Equal(t, []string{"foo", "baz"}, Output(term, commands[1]))
+}
+
+func TestTrimmedScrollback(t *testing.T) {
+	events := sessions.NewSimulator().
+		Defaults().
+		Add(
+			geom.Size{R: 4, C: 20},
+			TEST_PROMPT, "first\n",
+			"a\nb\n",
+			TEST_PROMPT, "second\n",
+			"c\nd\ne\nf\n",
+			TEST_PROMPT,
+		).
+		Events()
+
+	d := New()
+	term := emu.New(emu.WithScrollback(3))
+	term.Changes().SetHooks([]string{CY_HOOK})
+	for i, event := range events {
+		switch e := event.Message.(type) {
+		case P.OutputMessage:
+			term.Parse(e.Data)
+			d.Detect(term, events[0:i+1])
+		case P.SizeMessage:
+			term.Resize(e.Vec())
+		}
+	}
+	require.Equal(t, 2, term.TrimmedLines())
+
+	commands := d.Commands(term, events)
+	require.Len(t, commands, 2)
+
+	// The first command's prompt was discarded along with part of its
+	// output
+	first := commands[0]
+	require.Equal(t, "first", first.Text)
+	require.Empty(t, first.Input)
+	require.Equal(t, []string{"b"}, Output(term, first))
+
+	second := commands[1]
+	require.Equal(t, "second", second.Text)
+	require.Equal(t, []string{"c", "d", "e", "f"}, Output(term, second))
+	input := second.Input[0]
+	line := term.GetLines(input.From.R, input.From.R)[0]
+	require.Equal(
+		t,
+		"second",
+		line[input.From.C:input.To.C].String(),
+	)
+}
@@ -1,6 +1,9 @@
 package detect
 
 import (
+	"strconv"
+	"strings"
+
 	"github.com/cfoust/cy/pkg/emu"
 	"github.com/cfoust/cy/pkg/geom"
 	P "github.com/cfoust/cy/pkg/io/protocol"
@@ -23,6 +26,43 @@ type Detector struct {
 
 	from   geom.Vec2
 	fromID emu.WriteID
+	// The parameters of the most recent prompt
+	fromParams promptParams
+
+	// The number of lines that had been discarded from the terminal's
+	// scrollback buffer when the locations in `commands` and `from` were
+	// last updated.
+	trimmed int
+}
+
+// promptParams contains the information a shell can include in the prompt
+// hook, such as "\033Pcy;exit=0;cwd=/home/user\033\\".
+type promptParams struct {
+	// The exit code of the previous command
+	exitCode *int
+	// The current working directory
+	directory string
+}
+
+func parsePromptParams(params string) (result promptParams) {
+	for _, param := range strings.Split(params, ";") {
+		key, value, ok := strings.Cut(param, "=")
+		if !ok {
+			continue
+		}
+
+		switch key {
+		case "exit":
+			code, err := strconv.Atoi(value)
+			if err != nil {
+				continue
+			}
+			result.exitCode = &code
+		case "cwd":
+			result.directory = value
+		}
+	}
+	return
 }
 
 func (d *Detector) getLine(
@@ -88,23 +128,69 @@ func (d *Detector) Commands(
 ) []Command {
 	d.mu.RLock()
 	var (
-		complete  = d.commands
-		from      = d.from
-		fromWrite = d.fromID
+		complete   = d.commands
+		from       = d.from
+		fromWrite  = d.fromID
+		fromParams = d.fromParams
+		trimmed    = d.trimmed
 	)
 	d.mu.RUnlock()
 
 	commands := make([]Command, len(complete))
 	copy(commands, complete)
 
+	// Lines may have been discarded since the detector last saw the
+	// terminal
+	fromLost := false
+	if delta := term.TrimmedLines() - trimmed; delta > 0 {
+		for i, command := range commands {
+			commands[i] = command.shift(delta)
+		}
+		fromLost = from.R < delta
+		from = shiftVec(from, delta)
+	}
+
+	// The pending command cannot be detected if its prompt was discarded
+	if fromLost {
+		return commands
+	}
+
 	pending, ok := d.detectPending(term, events, from, fromWrite)
 	if ok {
+		pending.Directory = fromParams.directory
 		commands = append(commands, pending)
 	}
 
 	return commands
 }
 
+// updateTrimmed moves the locations of detected commands to account for
+// lines that have been discarded from the scrollback buffer since they were
+// detected.
+func (d *Detector) updateTrimmed(term emu.Terminal) {
+	trimmed := term.TrimmedLines()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delta := trimmed - d.trimmed
+	if delta <= 0 {
+		return
+	}
+
+	for i, command := range d.commands {
+		d.commands[i] = command.shift(delta)
+	}
+
+	// If the most recent prompt was discarded, the command that follows
+	// it cannot be detected, so we wait for the next prompt
+	if d.from.R < delta {
+		d.havePrompt = false
+	}
+	d.from = shiftVec(d.from, delta)
+	d.trimmed = trimmed
+}
+
 func New() *Detector {
 	return &Detector{}
 }
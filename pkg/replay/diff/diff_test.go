@@ -0,0 +1,221 @@
+package diff
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/cfoust/cy/pkg/geom"
+	"github.com/cfoust/cy/pkg/replay"
+	"github.com/cfoust/cy/pkg/replay/detect"
+	"github.com/cfoust/cy/pkg/sessions"
+	"github.com/cfoust/cy/pkg/sessions/search"
+	"github.com/cfoust/cy/pkg/taro"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/require"
+)
+
+// apply reconstructs both texts from a diff.
+func apply(lines []Line) (a, b []string) {
+	for _, line := range lines {
+		if line.Op != OpInsert {
+			a = append(a, line.Text)
+		}
+		if line.Op != OpDelete {
+			b = append(b, line.Text)
+		}
+	}
+	return
+}
+
+func ops(lines []Line) (result string) {
+	for _, line := range lines {
+		switch line.Op {
+		case OpEqual:
+			result += "="
+		case OpDelete:
+			result += "-"
+		case OpInsert:
+			result += "+"
+		}
+	}
+	return
+}
+
+func TestLines(t *testing.T) {
+	for _, test := range []struct {
+		a, b []string
+		ops  string
+	}{
+		{nil, nil, ""},
+		{[]string{"a"}, nil, "-"},
+		{nil, []string{"a"}, "+"},
+		{[]string{"a", "b", "c"}, []string{"a", "b", "c"}, "==="},
+		{[]string{"a", "b", "c"}, []string{"a", "x", "c"}, "=-+="},
+		{[]string{"a", "b", "c"}, []string{"a", "c"}, "=-="},
+		{[]string{"a", "c"}, []string{"a", "b", "c"}, "=+="},
+		{
+			[]string{"a", "b", "c", "a", "b", "b", "a"},
+			[]string{"c", "b", "a", "b", "a", "c"},
+			// The shortest edit script has five edits
+			"--=+==-=+",
+		},
+	} {
+		lines := Lines(test.a, test.b)
+		require.Equal(t, test.ops, ops(lines))
+
+		a, b := apply(lines)
+		require.Equal(t, len(test.a), len(a))
+		require.Equal(t, len(test.b), len(b))
+		for i := range a {
+			require.Equal(t, test.a[i], a[i])
+		}
+		for i := range b {
+			require.Equal(t, test.b[i], b[i])
+		}
+
+		for _, line := range lines {
+			if line.Op != OpInsert {
+				require.Equal(t, test.a[line.Old], line.Text)
+			}
+			if line.Op != OpDelete {
+				require.Equal(t, test.b[line.New], line.Text)
+			}
+		}
+	}
+}
+
+func TestLinesLarge(t *testing.T) {
+	const numLines = 20000
+
+	var a, b []string
+	for i := 0; i < numLines; i++ {
+		a = append(a, fmt.Sprintf("a%d", i))
+		b = append(b, fmt.Sprintf("b%d", i))
+	}
+
+	// Completely different texts exceed MAX_EDITS and are treated as
+	// having been replaced
+	lines := Lines(a, b)
+	require.Equal(t, 2*numLines, len(lines))
+	require.Equal(t, OpDelete, lines[0].Op)
+	require.Equal(t, OpInsert, lines[len(lines)-1].Op)
+
+	// Scattered changes within large texts still produce the shortest
+	// diff
+	b = append([]string{}, a...)
+	for i := 0; i < numLines; i += 50 {
+		b[i] = "changed"
+	}
+	lines = Lines(a, b)
+	require.Equal(t, numLines+numLines/50, len(lines))
+
+	oldLines, newLines := apply(lines)
+	require.Equal(t, a, oldLines)
+	require.Equal(t, b, newLines)
+}
+
+func TestRows(t *testing.T) {
+	lines := Lines(
+		[]string{"a", "b", "c", "d"},
+		[]string{"a", "x", "y", "d", "e"},
+	)
+
+	rows := SideBySide(lines)
+	require.Equal(t, 5, len(rows))
+	require.Equal(t, "b", rows[1].Old.Text)
+	require.Equal(t, "x", rows[1].New.Text)
+	require.Equal(t, "c", rows[2].Old.Text)
+	require.Equal(t, "y", rows[2].New.Text)
+	require.Nil(t, rows[4].Old)
+	require.Equal(t, []Hunk{{1, 3}, {4, 5}}, Hunks(rows))
+
+	rows = Unified(lines)
+	require.Equal(t, 7, len(rows))
+	require.Equal(t, []Hunk{{1, 5}, {6, 7}}, Hunks(rows))
+}
+
+func TestSnapshot(t *testing.T) {
+	events := sessions.NewSimulator().
+		Defaults().
+		Add(
+			detect.TEST_PROMPT, "command\n",
+			"foo\n",
+			"bar\n",
+			detect.TEST_PROMPT, "command\n",
+			"foo\n",
+			"baz\n",
+			detect.TEST_PROMPT,
+		).
+		Events()
+
+	// Right after the first command finished
+	require.Equal(
+		t,
+		[]string{"$ command", "foo", "bar"},
+		Snapshot(events, search.Address{Index: 5, Offset: -1}),
+	)
+}
+
+// getCopied sends `msgs` to the viewer and returns the text that was
+// copied, if any.
+func getCopied(t *testing.T, v *Viewer, msgs ...interface{}) string {
+	var cmd tea.Cmd
+	for _, msg := range msgs {
+		_, cmd = v.Update(taro.KeyMsg{
+			Type:  taro.KeyRunes,
+			Runes: []rune(msg.(string)),
+		})
+	}
+
+	if cmd == nil {
+		return ""
+	}
+
+	msg, ok := cmd().(taro.PublishMsg)
+	require.True(t, ok)
+	event, ok := msg.Msg.(replay.CopyEvent)
+	require.True(t, ok)
+	return event.Text
+}
+
+func TestViewer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	v := newViewer(
+		ctx,
+		[]string{"a", "b", "c", "d", "e", "f"},
+		[]string{"a", "x", "c", "d", "e", "y", "z"},
+	)
+	test := taro.Test(v)
+	test(geom.DEFAULT_SIZE)
+
+	// The viewer starts at the first hunk
+	require.Equal(t, 1, v.cursor)
+	require.Equal(t, "x", getCopied(t, v, "y"))
+	require.Equal(t, "b", getCopied(t, v, "h", "y"))
+
+	test("n")
+	require.Equal(t, 5, v.cursor)
+	require.Equal(t, "f", getCopied(t, v, "Y"))
+	require.Equal(t, "y\nz", getCopied(t, v, "l", "Y"))
+
+	// There is no next hunk
+	test("n")
+	require.Equal(t, 5, v.cursor)
+
+	test("N")
+	require.Equal(t, 1, v.cursor)
+
+	// Switching layouts keeps the same line selected
+	test("tab")
+	require.True(t, v.isUnified)
+	require.Equal(t, "b", v.rows[v.cursor].Old.Text)
+
+	test("G")
+	require.Equal(t, len(v.rows)-1, v.cursor)
+	test("g")
+	require.Equal(t, 0, v.cursor)
+}
@@ -0,0 +1,301 @@
+// Package diff compares the contents of a terminal at two moments in time.
+package diff
+
+// MAX_EDITS is the largest number of edits the diff algorithm will search
+// for before giving up and treating the rest of the text as having been
+// replaced. The scrollback buffer can be very large, and finding the
+// smallest diff between two completely different screens is not worth the
+// time or memory.
+const MAX_EDITS = 2000
+
+type Op uint8
+
+const (
+	// The line appears in both the old and the new text
+	OpEqual Op = iota
+	// The line only appears in the old text
+	OpDelete
+	// The line only appears in the new text
+	OpInsert
+)
+
+// A Line is a single line in a diff.
+type Line struct {
+	Op   Op
+	Text string
+	// The index of the line in the old text and the new text, or -1 if
+	// it does not appear there
+	Old, New int
+}
+
+// Lines returns the shortest sequence of lines that transforms `a` into
+// `b` using the algorithm described in Eugene Myers' "An O(ND) Difference
+// Algorithm and Its Variations".
+func Lines(a, b []string) (lines []Line) {
+	// Most of the time the two texts share a long prefix (the scrollback
+	// that came before both moments) so we skip it before searching
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(a)-prefix &&
+		suffix < len(b)-prefix &&
+		a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	for i := 0; i < prefix; i++ {
+		lines = append(lines, Line{
+			Op:   OpEqual,
+			Text: a[i],
+			Old:  i,
+			New:  i,
+		})
+	}
+
+	lines = append(lines, myers(
+		a[prefix:len(a)-suffix],
+		b[prefix:len(b)-suffix],
+		prefix,
+		prefix,
+	)...)
+
+	for i := suffix; i > 0; i-- {
+		lines = append(lines, Line{
+			Op:   OpEqual,
+			Text: a[len(a)-i],
+			Old:  len(a) - i,
+			New:  len(b) - i,
+		})
+	}
+
+	return lines
+}
+
+// replace treats every line in `a` as deleted and every line in `b` as
+// inserted.
+func replace(a, b []string, oldStart, newStart int) (lines []Line) {
+	for i, text := range a {
+		lines = append(lines, Line{
+			Op:   OpDelete,
+			Text: text,
+			Old:  oldStart + i,
+			New:  -1,
+		})
+	}
+
+	for i, text := range b {
+		lines = append(lines, Line{
+			Op:   OpInsert,
+			Text: text,
+			Old:  -1,
+			New:  newStart + i,
+		})
+	}
+
+	return lines
+}
+
+func myers(a, b []string, oldStart, newStart int) []Line {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 {
+		return replace(a, b, oldStart, newStart)
+	}
+
+	max := n + m
+	offset := max
+	v := make([]int, 2*max+2)
+
+	// trace contains the state of `v` before each round of the search,
+	// which we use to reconstruct the path afterwards. Round `d` only
+	// reads diagonals -d through d, so that is all we keep.
+	var trace [][]int
+	for d := 0; d <= max; d++ {
+		if d > MAX_EDITS {
+			return replace(a, b, oldStart, newStart)
+		}
+
+		state := make([]int, 2*d+1)
+		copy(state, v[offset-d:offset+d+1])
+		trace = append(trace, state)
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, a, b, oldStart, newStart)
+			}
+		}
+	}
+
+	return replace(a, b, oldStart, newStart)
+}
+
+func backtrack(trace [][]int, a, b []string, oldStart, newStart int) []Line {
+	x, y := len(a), len(b)
+
+	var reversed []Line
+	snake := func(prevX, prevY int) {
+		for x > prevX && y > prevY {
+			reversed = append(reversed, Line{
+				Op:   OpEqual,
+				Text: a[x-1],
+				Old:  oldStart + x - 1,
+				New:  newStart + y - 1,
+			})
+			x--
+			y--
+		}
+	}
+
+	for d := len(trace) - 1; d >= 0; d-- {
+		// The path always begins at the top left corner
+		if d == 0 {
+			snake(0, 0)
+			break
+		}
+
+		// trace[d] contains diagonals -d through d
+		state := trace[d]
+		v := func(k int) int {
+			return state[k+d]
+		}
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && v(k-1) < v(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+
+		prevX := v(prevK)
+		prevY := prevX - prevK
+		snake(prevX, prevY)
+
+		if x == prevX {
+			reversed = append(reversed, Line{
+				Op:   OpInsert,
+				Text: b[y-1],
+				Old:  -1,
+				New:  newStart + y - 1,
+			})
+			y--
+		} else {
+			reversed = append(reversed, Line{
+				Op:   OpDelete,
+				Text: a[x-1],
+				Old:  oldStart + x - 1,
+				New:  -1,
+			})
+			x--
+		}
+	}
+
+	lines := make([]Line, len(reversed))
+	for i, line := range reversed {
+		lines[len(reversed)-1-i] = line
+	}
+	return lines
+}
+
+// A Row is a single row of a diff as it is shown on the screen. In a
+// unified diff, each Row contains a single line. In a side-by-side diff,
+// deleted lines are shown next to the lines that replaced them, so either
+// side of a Row may be missing.
+type Row struct {
+	Old, New *Line
+}
+
+// IsChange reports whether the Row contains an insertion or deletion.
+func (r Row) IsChange() bool {
+	return (r.Old != nil && r.Old.Op != OpEqual) ||
+		(r.New != nil && r.New.Op != OpEqual)
+}
+
+// Unified returns one Row for each line.
+func Unified(lines []Line) []Row {
+	rows := make([]Row, 0, len(lines))
+	for i := range lines {
+		line := &lines[i]
+		switch line.Op {
+		case OpEqual:
+			rows = append(rows, Row{Old: line, New: line})
+		case OpDelete:
+			rows = append(rows, Row{Old: line})
+		case OpInsert:
+			rows = append(rows, Row{New: line})
+		}
+	}
+	return rows
+}
+
+// SideBySide returns Rows in which each run of deleted lines is shown next
+// to the run of inserted lines that follows it.
+func SideBySide(lines []Line) []Row {
+	var rows []Row
+	for i := 0; i < len(lines); {
+		line := &lines[i]
+		if line.Op == OpEqual {
+			rows = append(rows, Row{Old: line, New: line})
+			i++
+			continue
+		}
+
+		var deleted, inserted []*Line
+		for ; i < len(lines) && lines[i].Op == OpDelete; i++ {
+			deleted = append(deleted, &lines[i])
+		}
+		for ; i < len(lines) && lines[i].Op == OpInsert; i++ {
+			inserted = append(inserted, &lines[i])
+		}
+
+		for j := 0; j < len(deleted) || j < len(inserted); j++ {
+			var row Row
+			if j < len(deleted) {
+				row.Old = deleted[j]
+			}
+			if j < len(inserted) {
+				row.New = inserted[j]
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// A Hunk is a run of Rows that contain changes. End is exclusive.
+type Hunk struct {
+	Start, End int
+}
+
+// Hunks returns all of the Hunks in `rows`.
+func Hunks(rows []Row) (hunks []Hunk) {
+	for i := 0; i < len(rows); i++ {
+		if !rows[i].IsChange() {
+			continue
+		}
+
+		start := i
+		for i < len(rows) && rows[i].IsChange() {
+			i++
+		}
+
+		hunks = append(hunks, Hunk{Start: start, End: i})
+	}
+	return
+}
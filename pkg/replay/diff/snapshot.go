@@ -0,0 +1,45 @@
+package diff
+
+import (
+	"strings"
+
+	"github.com/cfoust/cy/pkg/emu"
+	"github.com/cfoust/cy/pkg/geom"
+	"github.com/cfoust/cy/pkg/replay/player"
+	"github.com/cfoust/cy/pkg/sessions"
+	"github.com/cfoust/cy/pkg/sessions/search"
+)
+
+// terminalLines returns every line in the scrollback and on the screen of
+// `term`. Trailing whitespace and empty lines at the end of the screen are
+// removed, since they usually only differ in how much of the screen has
+// been used so far.
+func terminalLines(term emu.Terminal) (lines []string) {
+	numLines := term.Flow(term.Size(), term.Root()).NumLines
+	if numLines == 0 {
+		return
+	}
+
+	for _, line := range term.GetLines(0, numLines-1) {
+		lines = append(lines, strings.TrimRight(line.String(), " "))
+	}
+
+	for len(lines) > 0 && len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+
+	return
+}
+
+// Snapshot returns the lines of the terminal, including its scrollback, as
+// they were at `address` in `events`.
+func Snapshot(events []sessions.Event, address search.Address) []string {
+	if len(events) == 0 {
+		return nil
+	}
+
+	index := geom.Clamp(address.Index, 0, len(events)-1)
+	p := player.FromEvents(events[:index+1])
+	p.Goto(index, address.Offset)
+	return terminalLines(p)
+}
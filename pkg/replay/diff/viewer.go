@@ -0,0 +1,448 @@
+package diff
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cfoust/cy/pkg/geom"
+	"github.com/cfoust/cy/pkg/geom/tty"
+	"github.com/cfoust/cy/pkg/replay"
+	"github.com/cfoust/cy/pkg/taro"
+	"github.com/cfoust/cy/pkg/util"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+)
+
+// Side is one of the two texts being compared.
+type Side uint8
+
+const (
+	SideOld Side = iota
+	SideNew
+)
+
+// Viewer shows the differences between two texts and lets the user move
+// between hunks and copy text from either side.
+type Viewer struct {
+	util.Lifetime
+	render *taro.Renderer
+	size   geom.Size
+
+	oldTitle, newTitle string
+
+	lines []Line
+	rows  []Row
+	hunks []Hunk
+
+	// Whether to show a unified diff rather than a side-by-side one
+	isUnified bool
+	// The side that text is copied from
+	side Side
+	// The index of the selected row
+	cursor int
+	// The index of the row at the top of the screen
+	offset int
+}
+
+var _ taro.Model = (*Viewer)(nil)
+
+type Setting func(*Viewer)
+
+// WithTitles sets the titles shown above the old and new text.
+func WithTitles(oldTitle, newTitle string) Setting {
+	return func(v *Viewer) {
+		v.oldTitle = oldTitle
+		v.newTitle = newTitle
+	}
+}
+
+// WithUnified shows a unified diff instead of a side-by-side one.
+func WithUnified() Setting {
+	return func(v *Viewer) {
+		v.isUnified = true
+	}
+}
+
+func (v *Viewer) Init() tea.Cmd {
+	return nil
+}
+
+// numBodyRows returns the number of rows available for the diff itself,
+// which excludes the header and the status bar.
+func (v *Viewer) numBodyRows() int {
+	return geom.Max(v.size.R-2, 1)
+}
+
+// layout recomputes the rows of the diff, keeping the same line selected.
+func (v *Viewer) layout() {
+	var selected *Line
+	if v.cursor >= 0 && v.cursor < len(v.rows) {
+		row := v.rows[v.cursor]
+		selected = row.Old
+		if selected == nil {
+			selected = row.New
+		}
+	}
+
+	if v.isUnified {
+		v.rows = Unified(v.lines)
+	} else {
+		v.rows = SideBySide(v.lines)
+	}
+	v.hunks = Hunks(v.rows)
+
+	v.cursor = 0
+	for i, row := range v.rows {
+		if selected != nil && (row.Old == selected || row.New == selected) {
+			v.cursor = i
+			break
+		}
+	}
+	v.setCursor(v.cursor)
+}
+
+// setCursor moves the cursor to `row` and scrolls the screen so that it is
+// visible.
+func (v *Viewer) setCursor(row int) {
+	v.cursor = geom.Clamp(row, 0, geom.Max(len(v.rows)-1, 0))
+
+	numRows := v.numBodyRows()
+	if v.cursor < v.offset {
+		v.offset = v.cursor
+	}
+	if v.cursor >= v.offset+numRows {
+		v.offset = v.cursor - numRows + 1
+	}
+	v.offset = geom.Clamp(v.offset, 0, geom.Max(len(v.rows)-numRows, 0))
+}
+
+// currentHunk returns the index of the hunk that contains the cursor.
+func (v *Viewer) currentHunk() (index int, ok bool) {
+	for i, hunk := range v.hunks {
+		if v.cursor >= hunk.Start && v.cursor < hunk.End {
+			return i, true
+		}
+	}
+	return
+}
+
+func (v *Viewer) nextHunk(isForward bool) {
+	if isForward {
+		for _, hunk := range v.hunks {
+			if hunk.Start > v.cursor {
+				v.setCursor(hunk.Start)
+				return
+			}
+		}
+		return
+	}
+
+	for i := len(v.hunks) - 1; i >= 0; i-- {
+		hunk := v.hunks[i]
+		if hunk.End <= v.cursor {
+			v.setCursor(hunk.Start)
+			return
+		}
+	}
+}
+
+// sideText returns the text on the selected side of `row`.
+func (v *Viewer) sideText(row Row) (text string, ok bool) {
+	line := row.Old
+	if v.side == SideNew {
+		line = row.New
+	}
+
+	if line == nil {
+		return
+	}
+
+	return line.Text, true
+}
+
+func (v *Viewer) publishCopy(text string) tea.Cmd {
+	return func() tea.Msg {
+		return taro.PublishMsg{
+			Msg: replay.CopyEvent{
+				Text: text,
+			},
+		}
+	}
+}
+
+// copyLine copies the selected side of the current row.
+func (v *Viewer) copyLine() tea.Cmd {
+	if v.cursor >= len(v.rows) {
+		return nil
+	}
+
+	text, ok := v.sideText(v.rows[v.cursor])
+	if !ok {
+		return nil
+	}
+
+	return v.publishCopy(text)
+}
+
+// copyHunk copies the selected side of the hunk under the cursor.
+func (v *Viewer) copyHunk() tea.Cmd {
+	index, ok := v.currentHunk()
+	if !ok {
+		return nil
+	}
+
+	hunk := v.hunks[index]
+	var lines []string
+	for _, row := range v.rows[hunk.Start:hunk.End] {
+		if text, ok := v.sideText(row); ok {
+			lines = append(lines, text)
+		}
+	}
+
+	if len(lines) == 0 {
+		return nil
+	}
+
+	return v.publishCopy(strings.Join(lines, "\n"))
+}
+
+func (v *Viewer) Update(msg tea.Msg) (taro.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		v.size = geom.Size{
+			R: msg.Height,
+			C: msg.Width,
+		}
+		v.setCursor(v.cursor)
+	case taro.KeyMsg:
+		halfPage := geom.Max(v.numBodyRows()/2, 1)
+
+		switch msg.String() {
+		case "j", "down":
+			v.setCursor(v.cursor + 1)
+		case "k", "up":
+			v.setCursor(v.cursor - 1)
+		case "ctrl+d", "pgdown":
+			v.setCursor(v.cursor + halfPage)
+		case "ctrl+u", "pgup":
+			v.setCursor(v.cursor - halfPage)
+		case "g", "home":
+			v.setCursor(0)
+		case "G", "end":
+			v.setCursor(len(v.rows) - 1)
+		case "n", "]":
+			v.nextHunk(true)
+		case "N", "[":
+			v.nextHunk(false)
+		case "h", "left":
+			v.side = SideOld
+		case "l", "right":
+			v.side = SideNew
+		case "tab":
+			v.isUnified = !v.isUnified
+			v.layout()
+		case "y":
+			return v, v.copyLine()
+		case "Y":
+			return v, v.copyHunk()
+		}
+	}
+
+	return v, nil
+}
+
+// fit pads or truncates `text` so that it occupies exactly `width` cells.
+func fit(text string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+
+	return runewidth.FillRight(runewidth.Truncate(text, width, ""), width)
+}
+
+func (v *Viewer) View(state *tty.State) {
+	size := state.Image.Size()
+	state.CursorVisible = false
+	if size.R < 2 || size.C == 0 {
+		return
+	}
+
+	var (
+		base = v.render.NewStyle().
+			Foreground(lipgloss.Color("7"))
+		deleteStyle = base.Copy().
+				Foreground(lipgloss.Color("1"))
+		insertStyle = base.Copy().
+				Foreground(lipgloss.Color("2"))
+		barStyle = v.render.NewStyle().
+				Foreground(lipgloss.Color("15")).
+				Background(lipgloss.Color("8"))
+		titleStyle = barStyle.Copy().Bold(true)
+		selected   = lipgloss.Color("238")
+	)
+
+	lineStyle := func(line *Line) lipgloss.Style {
+		if line == nil {
+			return base
+		}
+
+		switch line.Op {
+		case OpDelete:
+			return deleteStyle
+		case OpInsert:
+			return insertStyle
+		}
+		return base
+	}
+
+	halfWidth := (size.C - 1) / 2
+
+	// Draw the header
+	////////////////////////////
+	oldTitle := "--- " + v.oldTitle
+	newTitle := "+++ " + v.newTitle
+	if v.isUnified {
+		v.render.RenderAt(
+			state.Image,
+			0, 0,
+			titleStyle.Render(fit(oldTitle+"  "+newTitle, size.C)),
+		)
+	} else {
+		v.render.RenderAt(
+			state.Image,
+			0, 0,
+			titleStyle.Render(
+				fit(oldTitle, halfWidth)+
+					"│"+
+					fit(newTitle, size.C-halfWidth-1),
+			),
+		)
+	}
+
+	// Draw the diff
+	////////////////////////////
+	for i := 0; i < v.numBodyRows(); i++ {
+		index := v.offset + i
+		if index >= len(v.rows) {
+			break
+		}
+
+		row := v.rows[index]
+		isSelected := index == v.cursor
+
+		if v.isUnified {
+			line := row.New
+			prefix := "+ "
+			if row.Old != nil {
+				line = row.Old
+				prefix = "- "
+			}
+			if line.Op == OpEqual {
+				prefix = "  "
+			}
+
+			style := lineStyle(line)
+			if isSelected {
+				style = style.Copy().Background(selected)
+			}
+
+			v.render.RenderAt(
+				state.Image,
+				i+1, 0,
+				style.Render(fit(prefix+line.Text, size.C)),
+			)
+			continue
+		}
+
+		renderSide := func(line *Line, side Side, width int) string {
+			style := lineStyle(line)
+			if isSelected && v.side == side {
+				style = style.Copy().Background(selected)
+			}
+
+			text := ""
+			if line != nil {
+				text = line.Text
+			}
+			return style.Render(fit(text, width))
+		}
+
+		v.render.RenderAt(
+			state.Image,
+			i+1, 0,
+			renderSide(row.Old, SideOld, halfWidth)+
+				"│"+
+				renderSide(row.New, SideNew, size.C-halfWidth-1),
+		)
+	}
+
+	// Draw the status bar
+	////////////////////////////
+	status := "no changes"
+	if len(v.hunks) > 0 {
+		status = fmt.Sprintf("%d hunks", len(v.hunks))
+		if index, ok := v.currentHunk(); ok {
+			status = fmt.Sprintf("hunk %d/%d", index+1, len(v.hunks))
+		}
+	}
+
+	side := "old"
+	if v.side == SideNew {
+		side = "new"
+	}
+
+	v.render.RenderAt(
+		state.Image,
+		size.R-1, 0,
+		barStyle.Render(fit(
+			fmt.Sprintf(
+				" %s · copying from %s · n/N: hunks · h/l: side · y/Y: copy line/hunk · tab: layout",
+				status,
+				side,
+			),
+			size.C,
+		)),
+	)
+}
+
+func newViewer(
+	ctx context.Context,
+	oldLines, newLines []string,
+	settings ...Setting,
+) *Viewer {
+	v := &Viewer{
+		Lifetime: util.NewLifetime(ctx),
+		render:   taro.NewRenderer(),
+		size:     geom.DEFAULT_SIZE,
+		lines:    Lines(oldLines, newLines),
+		side:     SideNew,
+		oldTitle: "old",
+		newTitle: "new",
+	}
+
+	for _, setting := range settings {
+		setting(v)
+	}
+
+	v.layout()
+
+	// Start at the first change
+	if len(v.hunks) > 0 {
+		v.setCursor(v.hunks[0].Start)
+	}
+
+	return v
+}
+
+// New creates a Viewer that compares `oldLines` to `newLines`.
+func New(
+	ctx context.Context,
+	oldLines, newLines []string,
+	settings ...Setting,
+) *taro.Program {
+	v := newViewer(ctx, oldLines, newLines, settings...)
+	return taro.New(v.Ctx(), v)
+}
@@ -0,0 +1,273 @@
+package replay
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/cfoust/cy/pkg/emu"
+	"github.com/cfoust/cy/pkg/geom"
+	"github.com/cfoust/cy/pkg/geom/tty"
+	P "github.com/cfoust/cy/pkg/io/protocol"
+	"github.com/cfoust/cy/pkg/sessions"
+	"github.com/cfoust/cy/pkg/sessions/search"
+
+	"github.com/muesli/termenv"
+	"github.com/xo/terminfo"
+)
+
+// normalizeOffset resolves negative byte offsets, which count back from the
+// end of the event at `index`, into positive ones.
+func normalizeOffset(events []sessions.Event, address search.Address) search.Address {
+	output, ok := events[address.Index].Message.(P.OutputMessage)
+	if !ok {
+		address.Offset = 0
+		return address
+	}
+
+	if address.Offset < 0 {
+		address.Offset += len(output.Data)
+	}
+	address.Offset = geom.Clamp(address.Offset, 0, geom.Max(len(output.Data)-1, 0))
+	return address
+}
+
+// synthesizedModes are the DEC private modes that synthesize reproduces,
+// other than the alternate screen, origin, and auto wrap modes, which are
+// handled separately.
+var synthesizedModes = []struct {
+	number int
+	flag   emu.ModeFlag
+}{
+	{1, emu.ModeAppCursor},
+	{5, emu.ModeReverse},
+	{9, emu.ModeMouseX10},
+	{1000, emu.ModeMouseButton},
+	{1002, emu.ModeMouseMotion},
+	{1003, emu.ModeMouseMany},
+	{1004, emu.ModeFocus},
+	{1006, emu.ModeMouseSgr},
+	{2004, emu.ModeBracketedPaste},
+}
+
+// synthesize returns the output necessary to make a new terminal look like
+// `term` and behave like it does for subsequent output. This includes the
+// screen, the scrolling region, the cursor and its pen, the saved cursor,
+// and the terminal's modes. The scrollback buffer, character sets, tab
+// stops, and images are not included.
+func synthesize(term emu.Terminal) ([]byte, error) {
+	info, err := terminfo.Load("xterm-256color")
+	if err != nil {
+		return nil, err
+	}
+
+	caps := tty.Capabilities{Profile: termenv.TrueColor}
+	size := term.Size()
+	data := new(bytes.Buffer)
+
+	// Cells that were never written to are different from cells that
+	// contain spaces, so we only draw the cells that differ from those in
+	// a fresh terminal
+	fresh := emu.New(emu.WithSize(size))
+	blank := tty.Capture(fresh)
+
+	mode := term.Mode()
+	if mode&emu.ModeCRLF != 0 {
+		data.WriteString(emu.LineFeedMode)
+	}
+
+	if title := term.Title(); len(title) > 0 {
+		fmt.Fprintf(data, "\033]2;%s\007", title)
+	}
+
+	// The contents of the main screen are still visible in the flow of the
+	// terminal, so we draw them before switching to the alt screen
+	if term.IsAltMode() {
+		main := tty.Capture(emu.New(emu.WithSize(size)))
+		result := term.Flow(size, term.Root())
+		for row, line := range result.Lines {
+			if row >= size.R {
+				break
+			}
+			copy(main.Image[row], line.Chars)
+		}
+
+		data.Write(tty.Swap(info, caps, blank, main))
+		data.WriteString(emu.EnterAltScreen)
+	}
+
+	data.Write(tty.Swap(
+		info,
+		caps,
+		blank,
+		tty.Capture(term),
+	))
+
+	// Setting the scrolling region moves the cursor, so the cursors are
+	// positioned afterwards
+	top, bottom := term.ScrollRegion()
+	if top != 0 || bottom != size.R-1 {
+		fmt.Fprintf(data, "\033[%d;%dr", top+1, bottom+1)
+	}
+
+	setCursor := func(cursor emu.Cursor) {
+		row := cursor.R
+		if cursor.Origin() {
+			data.WriteString("\033[?6h")
+			row -= top
+		} else {
+			data.WriteString("\033[?6l")
+		}
+
+		info.Fprintf(data, terminfo.CursorAddress, row, cursor.C)
+		data.Write(tty.SetPen(info, caps, cursor.Attr))
+	}
+
+	// DECSC saves the position, pen, and origin mode of the cursor
+	if saved := term.SavedCursor(); saved != fresh.SavedCursor() {
+		setCursor(saved)
+		data.WriteString("\0337")
+	}
+	setCursor(term.Cursor())
+
+	if mode&emu.ModeWrap == 0 {
+		data.WriteString("\033[?7l")
+	}
+
+	if mode&emu.ModeAppKeypad != 0 {
+		data.WriteString("\033=")
+	}
+
+	for _, private := range synthesizedModes {
+		if mode&private.flag == 0 {
+			continue
+		}
+		fmt.Fprintf(data, "\033[?%dh", private.number)
+	}
+
+	if flags := term.KeyboardFlags(); flags != 0 {
+		fmt.Fprintf(data, "\033[>%du", int(flags))
+	}
+
+	return data.Bytes(), nil
+}
+
+// Extract returns the events from `from` to `to` (inclusive) as a
+// standalone recording. The recording begins with a synthesized prefix that
+// reproduces what the terminal looked like just before `from`, so that it
+// can be replayed on its own.
+func Extract(
+	events []sessions.Event,
+	from, to search.Address,
+) ([]sessions.Event, error) {
+	if len(events) == 0 {
+		return nil, fmt.Errorf("no events to extract")
+	}
+
+	for _, address := range []search.Address{from, to} {
+		if address.Index < 0 || address.Index >= len(events) {
+			return nil, fmt.Errorf(
+				"address out of range: %d",
+				address.Index,
+			)
+		}
+	}
+
+	from = normalizeOffset(events, from)
+	to = normalizeOffset(events, to)
+	if to.Before(from) {
+		return nil, fmt.Errorf("end of range is before the start")
+	}
+
+	// Reproduce the state of the terminal just before `from`
+	term := emu.New()
+	apply := func(message P.Message) {
+		switch message := message.(type) {
+		case P.OutputMessage:
+			term.Parse(message.Data)
+		case P.SizeMessage:
+			term.Resize(message.Vec())
+		}
+	}
+
+	for _, event := range events[:from.Index] {
+		apply(event.Message)
+	}
+
+	first := events[from.Index]
+	if output, ok := first.Message.(P.OutputMessage); ok {
+		apply(P.OutputMessage{Data: output.Data[:from.Offset]})
+	}
+
+	prefix, err := synthesize(term)
+	if err != nil {
+		return nil, err
+	}
+
+	size := term.Size()
+	extracted := []sessions.Event{
+		{
+			Stamp: first.Stamp,
+			Message: P.SizeMessage{
+				Rows:    size.R,
+				Columns: size.C,
+			},
+		},
+		{
+			Stamp:   first.Stamp,
+			Message: P.OutputMessage{Data: prefix},
+		},
+	}
+
+	for index := from.Index; index <= to.Index; index++ {
+		event := events[index]
+		output, ok := event.Message.(P.OutputMessage)
+		if !ok {
+			extracted = append(extracted, event)
+			continue
+		}
+
+		data := output.Data
+		if index == to.Index && len(data) > 0 {
+			data = data[:to.Offset+1]
+		}
+		if index == from.Index {
+			data = data[from.Offset:]
+		}
+
+		if len(data) == 0 {
+			continue
+		}
+
+		extracted = append(extracted, sessions.Event{
+			Stamp:   event.Stamp,
+			Message: P.OutputMessage{Data: data},
+		})
+	}
+
+	return extracted, nil
+}
+
+// WriteEvents writes `events` to the file at `path`. Files ending in
+// ".cast" are written in the asciicast format; all others are written as
+// .borg files.
+func WriteEvents(path string, events []sessions.Event) error {
+	if strings.HasSuffix(path, ".cast") {
+		return sessions.WriteAsciinema(path, events)
+	}
+
+	writer, err := sessions.Create(path)
+	if err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		err = writer.Write(event)
+		if err != nil {
+			writer.Close()
+			return err
+		}
+	}
+
+	return writer.Close()
+}
@@ -0,0 +1,206 @@
+package replay
+
+import (
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/cfoust/cy/pkg/emu"
+	"github.com/cfoust/cy/pkg/geom"
+	P "github.com/cfoust/cy/pkg/io/protocol"
+	"github.com/cfoust/cy/pkg/sessions"
+	"github.com/cfoust/cy/pkg/sessions/search"
+
+	"github.com/stretchr/testify/require"
+)
+
+// replayEvents returns a terminal that has processed all of `events`.
+func replayEvents(events []sessions.Event) emu.Terminal {
+	term := emu.New()
+	for _, event := range events {
+		switch message := event.Message.(type) {
+		case P.OutputMessage:
+			term.Parse(message.Data)
+		case P.SizeMessage:
+			term.Resize(message.Vec())
+		}
+	}
+	return term
+}
+
+func requireSameScreen(t *testing.T, expected, actual emu.Terminal) {
+	require.Equal(t, expected.Size(), actual.Size())
+	require.Equal(t, expected.Cursor().Vec2, actual.Cursor().Vec2)
+
+	size := expected.Size()
+	for row := 0; row < size.R; row++ {
+		for col := 0; col < size.C; col++ {
+			require.True(
+				t,
+				expected.Cell(col, row).Equal(actual.Cell(col, row)),
+				"cell %d, %d differs",
+				row, col,
+			)
+		}
+	}
+}
+
+func TestExtract(t *testing.T) {
+	events := sessions.NewSimulator().
+		Defaults().
+		Add(
+			geom.Size{R: 5, C: 20},
+			"\033[31mred\033[0m\n",
+			"foo\n",
+			"bar",
+			"baz\n",
+			"qux",
+		).
+		Events()
+
+	// Start in the middle of "barbaz\n"
+	from := search.Address{Index: 5, Offset: 1}
+	to := search.Address{Index: 6, Offset: -1}
+
+	extracted, err := Extract(events, from, to)
+	require.NoError(t, err)
+
+	// The recording should begin where the terminal was just before
+	// `from`...
+	before := replayEvents(events[:5])
+	before.Parse([]byte("b"))
+	requireSameScreen(t, before, replayEvents(extracted[:2]))
+
+	// ...and end where the terminal was at `to`
+	requireSameScreen(t, replayEvents(events[:7]), replayEvents(extracted))
+
+	_, err = Extract(events, to, from)
+	require.Error(t, err)
+	_, err = Extract(events, from, search.Address{Index: 100})
+	require.Error(t, err)
+}
+
+func TestExtractAltScreen(t *testing.T) {
+	events := sessions.NewSimulator().
+		Defaults().
+		Add(
+			geom.Size{R: 5, C: 20},
+			"main\n",
+			emu.EnterAltScreen,
+			"alt",
+			emu.ExitAltScreen,
+		).
+		Events()
+
+	extracted, err := Extract(
+		events,
+		search.Address{Index: 6},
+		search.Address{Index: 6, Offset: -1},
+	)
+	require.NoError(t, err)
+
+	term := replayEvents(extracted)
+	require.False(t, term.IsAltMode())
+	require.Equal(t, "main", term.Screen()[0].String()[:4])
+}
+
+// TestExtractState checks that output after the start of an extracted
+// recording behaves the same way it did in the original, which depends on
+// terminal state that is not visible on the screen.
+func TestExtractState(t *testing.T) {
+	for _, test := range []struct {
+		name          string
+		before, after string
+	}{
+		{
+			name: "scroll region",
+			before: "1\r\n2\r\n3\r\n4\r\n5\r\n6" +
+				"\033[2;4r\033[4;1H",
+			after: "\nX\nY\nZ",
+		},
+		{
+			name:   "origin mode",
+			before: "\033[2;4r\033[?6h\033[2;3H",
+			after:  "X\033[1;1HY\nZ\nW\nV",
+		},
+		{
+			name:   "pen",
+			before: "\033[1;31;44mred",
+			after:  "still red\033[0mnormal",
+		},
+		{
+			name:   "saved cursor",
+			before: "\033[3;5H\033[32m\0337\033[0m\033[1;1Hfoo",
+			after:  "bar\0338baz",
+		},
+		{
+			name:   "no wrap",
+			before: "\033[?7l",
+			after:  "aaaaaaaaaaaaaaaaaaaaaaaaaa",
+		},
+		{
+			name:   "modes",
+			before: "\033[?1h\033=\033[?2004h\033[?1006h\033[?1000h\033[>1u",
+			after:  "foo",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			events := sessions.NewSimulator().
+				Defaults().
+				Add(
+					geom.Size{R: 6, C: 20},
+					test.before,
+					test.after,
+				).
+				Events()
+
+			last := len(events) - 1
+			extracted, err := Extract(
+				events,
+				search.Address{Index: last},
+				search.Address{Index: last, Offset: -1},
+			)
+			require.NoError(t, err)
+
+			expected := replayEvents(events)
+			actual := replayEvents(extracted)
+			requireSameScreen(t, expected, actual)
+			require.Equal(t, expected.Mode(), actual.Mode())
+			require.Equal(t, expected.KeyboardFlags(), actual.KeyboardFlags())
+			require.Equal(t, expected.Cursor().Attr, actual.Cursor().Attr)
+
+			top, bottom := expected.ScrollRegion()
+			actualTop, actualBottom := actual.ScrollRegion()
+			require.Equal(t, top, actualTop)
+			require.Equal(t, bottom, actualBottom)
+		})
+	}
+}
+
+func TestWriteEvents(t *testing.T) {
+	events := sessions.NewSimulator().
+		Defaults().
+		Add("foo").
+		Events()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.borg")
+	require.NoError(t, WriteEvents(path, events))
+
+	reader, err := sessions.Open(path)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	var read []sessions.Event
+	for {
+		event, err := reader.Read()
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		require.NoError(t, err)
+		read = append(read, event)
+	}
+	require.Equal(t, len(events), len(read))
+
+	require.NoError(t, WriteEvents(filepath.Join(dir, "test.cast"), events))
+}
@@ -0,0 +1,195 @@
+package replay
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cfoust/cy/pkg/sessions"
+	"github.com/cfoust/cy/pkg/sessions/search"
+
+	"github.com/sasha-s/go-deadlock"
+)
+
+// A Mark is a moment in a pane's timeline that the user can return to.
+type Mark struct {
+	// The name of the mark, such as "a". Bookmarks do not have names.
+	Name string `json:",omitempty"`
+	// The text describing a bookmark. Marks do not have labels.
+	Label string `json:",omitempty"`
+	// The location of the mark in the timeline
+	Address search.Address
+	// When the event at Address was recorded
+	Time time.Time
+}
+
+// IsBookmark reports whether the mark is a bookmark rather than a
+// vim-style mark.
+func (m Mark) IsBookmark() bool {
+	return len(m.Name) == 0
+}
+
+// Marks stores the marks and bookmarks for a single timeline. If it has a
+// path, it writes them to that file every time they change.
+type Marks struct {
+	deadlock.RWMutex
+	path      string
+	named     map[string]Mark
+	bookmarks []Mark
+
+	// Held while the marks are written so that concurrent saves do not
+	// share the temporary file and the last one to finish is the newest
+	saveLock deadlock.Mutex
+}
+
+// MarksPath returns the path of the file that stores the marks for the
+// .borg file at `borgPath`.
+func MarksPath(borgPath string) string {
+	return strings.TrimSuffix(borgPath, ".borg") + ".marks.json"
+}
+
+func NewMarks() *Marks {
+	return &Marks{
+		named: make(map[string]Mark),
+	}
+}
+
+// LoadMarks reads the marks stored at `path`, which need not exist yet.
+// Changes to the returned Marks are written back to `path`.
+func LoadMarks(path string) (*Marks, error) {
+	marks := NewMarks()
+	marks.path = path
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return marks, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var saved []Mark
+	err = json.Unmarshal(data, &saved)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, mark := range saved {
+		if mark.IsBookmark() {
+			marks.bookmarks = append(marks.bookmarks, mark)
+			continue
+		}
+
+		marks.named[mark.Name] = mark
+	}
+
+	return marks, nil
+}
+
+// SetPath changes the file that the marks are written to.
+func (m *Marks) SetPath(path string) {
+	m.Lock()
+	defer m.Unlock()
+	m.path = path
+}
+
+// Set stores `mark`, replacing any existing mark with the same name.
+// Bookmarks are always added.
+func (m *Marks) Set(mark Mark) error {
+	m.Lock()
+	if mark.IsBookmark() {
+		m.bookmarks = append(m.bookmarks, mark)
+	} else {
+		m.named[mark.Name] = mark
+	}
+	m.Unlock()
+
+	return m.save()
+}
+
+// Get returns the mark named `name`.
+func (m *Marks) Get(name string) (mark Mark, ok bool) {
+	m.RLock()
+	defer m.RUnlock()
+	mark, ok = m.named[name]
+	return
+}
+
+// Bookmarks returns all of the bookmarks in the order they appear in the
+// timeline.
+func (m *Marks) Bookmarks() []Mark {
+	m.RLock()
+	bookmarks := make([]Mark, len(m.bookmarks))
+	copy(bookmarks, m.bookmarks)
+	m.RUnlock()
+
+	sortMarks(bookmarks)
+	return bookmarks
+}
+
+// All returns all of the marks and bookmarks in the order they appear in
+// the timeline.
+func (m *Marks) All() []Mark {
+	m.RLock()
+	marks := make([]Mark, 0, len(m.named)+len(m.bookmarks))
+	for _, mark := range m.named {
+		marks = append(marks, mark)
+	}
+	marks = append(marks, m.bookmarks...)
+	m.RUnlock()
+
+	sortMarks(marks)
+	return marks
+}
+
+func sortMarks(marks []Mark) {
+	sort.SliceStable(marks, func(i, j int) bool {
+		a, b := marks[i], marks[j]
+		if !a.Address.Equal(b.Address) {
+			return a.Address.Before(b.Address)
+		}
+
+		return a.Name < b.Name
+	})
+}
+
+func (m *Marks) save() error {
+	m.saveLock.Lock()
+	defer m.saveLock.Unlock()
+
+	m.RLock()
+	path := m.path
+	m.RUnlock()
+
+	if len(path) == 0 {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(m.All(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Just like the state file, write to a temporary file first so that
+	// a crash never leaves a partially written file behind
+	temp := path + ".tmp"
+	err = os.WriteFile(temp, data, 0600)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(temp, path)
+}
+
+// markAt creates a Mark for the moment at `address` in `events`.
+func markAt(events []sessions.Event, address search.Address) Mark {
+	mark := Mark{Address: address}
+	if address.Index >= 0 && address.Index < len(events) {
+		mark.Time = events[address.Index].Stamp
+	}
+	return mark
+}
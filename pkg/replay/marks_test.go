@@ -0,0 +1,91 @@
+package replay
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cfoust/cy/pkg/sessions/search"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarksPath(t *testing.T) {
+	require.Equal(
+		t,
+		"/tmp/foo.marks.json",
+		MarksPath("/tmp/foo.borg"),
+	)
+}
+
+func TestSaveMarks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.marks.json")
+	marks, err := LoadMarks(path)
+	require.NoError(t, err)
+	require.Empty(t, marks.All())
+
+	// Nothing is written until a mark is added
+	_, err = os.Stat(path)
+	require.Error(t, err)
+
+	stamp := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, marks.Set(Mark{
+		Name:    "b",
+		Address: search.Address{Index: 3},
+		Time:    stamp,
+	}))
+	require.NoError(t, marks.Set(Mark{
+		Label:   "deploy",
+		Address: search.Address{Index: 1, Offset: 2},
+		Time:    stamp,
+	}))
+	// Marks with the same name are replaced
+	require.NoError(t, marks.Set(Mark{
+		Name:    "b",
+		Address: search.Address{Index: 5},
+		Time:    stamp,
+	}))
+
+	loaded, err := LoadMarks(path)
+	require.NoError(t, err)
+	require.Equal(t, marks.All(), loaded.All())
+
+	all := loaded.All()
+	require.Len(t, all, 2)
+	require.Equal(t, "deploy", all[0].Label)
+	require.Equal(t, 5, all[1].Address.Index)
+
+	require.Len(t, loaded.Bookmarks(), 1)
+}
+
+func TestSaveMarksConcurrently(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.marks.json")
+	marks, err := LoadMarks(path)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 100)
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- marks.Set(Mark{
+				Label:   "bookmark",
+				Address: search.Address{Index: i},
+			})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	// The file reflects every mark once all of the saves are done
+	loaded, err := LoadMarks(path)
+	require.NoError(t, err)
+	require.Len(t, loaded.All(), 100)
+}
@@ -32,10 +32,19 @@ type Replay struct {
 	// whether the player is seeking
 	isSeeking bool
 
-	// the size of the client, but minus one row
-	// we don't want to obscure content
+	// the size of the client
+	size geom.Size
+	// the size of the client, but minus the rows used by the status bar
+	// and timeline; we don't want to obscure content
 	viewport geom.Size
 
+	// Whether to show the timeline above the status bar
+	showTimeline bool
+	timeline     *timeline
+	// Whether the user is dragging the mouse after pressing it on the
+	// timeline
+	timelineDrag bool
+
 	mode Mode
 
 	// Replay allows you to browse the contents of the terminal screen in
@@ -60,6 +69,8 @@ type Replay struct {
 	isSelecting bool
 	// The location in terminal space where the select began
 	selectStart geom.Vec2
+	// The shape of the selection
+	selectMode SelectMode
 
 	isForward bool
 	isWaiting bool
@@ -78,6 +89,20 @@ type Replay struct {
 	wasJumpForward bool
 	// Whether that jump was "to" or up until
 	wasJumpTo bool
+
+	// The count typed before the next motion, or 0 if there is none
+	count int
+	// Whether the next motion or text object will be yanked, ie the user
+	// typed "y" outside of a selection
+	isYanking bool
+	// The count that preceded the operator, which multiplies the count
+	// of the motion that follows it
+	operatorCount int
+	// The register that the next copied text will be put in, if any
+	register string
+
+	// The marks and bookmarks in the timeline
+	marks *Marks
 }
 
 var _ taro.Model = (*Replay)(nil)
@@ -141,6 +166,7 @@ func newReplay(
 		copyBinds:      copyBinds,
 		searchProgress: make(chan int),
 		skipInactivity: true,
+		marks:          NewMarks(),
 	}
 	m.Update(m.gotoIndex(-1, -1)())
 	return m
@@ -176,6 +202,30 @@ func WithLocation(location geom.Vec2) Option {
 	}
 }
 
+// WithMarks makes Replay store marks in `marks`, which allows them to
+// outlive it.
+func WithMarks(marks *Marks) Option {
+	return func(r *Replay) {
+		r.marks = marks
+	}
+}
+
+// WithTimeline shows the timeline above the status bar.
+func WithTimeline(r *Replay) {
+	if r.showTimeline {
+		return
+	}
+
+	r.toggleTimeline()
+}
+
+// WithAddress moves Replay to `address` in the timeline.
+func WithAddress(address search.Address) Option {
+	return func(r *Replay) {
+		r.forceIndex(address.Index, address.Offset)
+	}
+}
+
 // pollBinds subscribes to BindEvents from a binding engine and forwards them
 // to the Replay program so that it can decide whether to emit them (after
 // which they will be executed by cy).
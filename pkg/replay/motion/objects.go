@@ -0,0 +1,277 @@
+package motion
+
+import (
+	"regexp"
+
+	"github.com/cfoust/cy/pkg/geom"
+)
+
+// OBJECT_SEARCH_LINES is the maximum number of lines that are searched in
+// either direction for the delimiters of a text object that can span
+// multiple lines. The scrollback buffer can be very large, so we don't want
+// to scan all of it for a bracket that does not exist.
+const OBJECT_SEARCH_LINES = 500
+
+// A TextObject finds a region of text around the cursor, just like vim's
+// text objects (e.g. "iw" or `a"`). When `inner` is true, the region does
+// not include the surrounding whitespace or delimiters. Both `start` and
+// `end` are inclusive.
+type TextObject func(m Movable, inner bool) (start, end geom.Vec2, ok bool)
+
+func wordObject(re *regexp.Regexp) TextObject {
+	return func(m Movable, inner bool) (start, end geom.Vec2, ok bool) {
+		cursor := m.Cursor()
+		line, haveLine := m.Line(cursor.R)
+		if !haveLine {
+			return
+		}
+
+		// Divide the line into alternating runs of word and non-word
+		// cells
+		var runs [][]int
+		last := 0
+		for _, match := range findAllLine(re, line) {
+			if match[0] > last {
+				runs = append(runs, []int{last, match[0]})
+			}
+			runs = append(runs, match)
+			last = match[1]
+		}
+		if last < len(line) {
+			runs = append(runs, []int{last, len(line)})
+		}
+
+		for i, run := range runs {
+			if cursor.C < run[0] || cursor.C >= run[1] {
+				continue
+			}
+
+			from, to := run[0], run[1]-1
+
+			// "aw" includes the run after the word or, if there is
+			// none, the run before it
+			if !inner {
+				if i+1 < len(runs) {
+					to = runs[i+1][1] - 1
+				} else if i > 0 {
+					from = runs[i-1][0]
+				}
+			}
+
+			return geom.Vec2{R: cursor.R, C: from},
+				geom.Vec2{R: cursor.R, C: to},
+				true
+		}
+
+		return
+	}
+}
+
+// WordObject corresponds to vim's "iw" and "aw".
+var WordObject = wordObject(WORD_REGEX)
+
+// BigWordObject corresponds to vim's "iW" and "aW".
+var BigWordObject = wordObject(NON_WHITESPACE_REGEX)
+
+// LineObject selects the text on the cursor's line. "il" excludes leading
+// and trailing whitespace, "al" only excludes trailing whitespace.
+var LineObject TextObject = func(m Movable, inner bool) (start, end geom.Vec2, ok bool) {
+	cursor := m.Cursor()
+	line, haveLine := m.Line(cursor.R)
+	if !haveLine || line.IsEmpty() {
+		return
+	}
+
+	first, last := line.Whitespace()
+	if !inner {
+		first = 0
+	}
+
+	return geom.Vec2{R: cursor.R, C: first},
+		geom.Vec2{R: cursor.R, C: last},
+		true
+}
+
+// QuoteObject returns a TextObject that selects a string delimited by
+// `quote`, such as vim's `i"` and `a'`. Quoted strings cannot span lines.
+// Just like in vim, quotes are paired starting from the beginning of the
+// line and, if the cursor is not inside of a quoted string, the first one
+// after the cursor is used.
+func QuoteObject(quote rune) TextObject {
+	return func(m Movable, inner bool) (start, end geom.Vec2, ok bool) {
+		cursor := m.Cursor()
+		line, haveLine := m.Line(cursor.R)
+		if !haveLine {
+			return
+		}
+
+		var quotes []int
+		for i, glyph := range line {
+			if glyph.Char == quote {
+				quotes = append(quotes, i)
+			}
+		}
+
+		for i := 0; i+1 < len(quotes); i += 2 {
+			from, to := quotes[i], quotes[i+1]
+			if to < cursor.C {
+				continue
+			}
+
+			if inner {
+				from++
+				to--
+			}
+
+			// The string is empty
+			if from > to {
+				return
+			}
+
+			return geom.Vec2{R: cursor.R, C: from},
+				geom.Vec2{R: cursor.R, C: to},
+				true
+		}
+
+		return
+	}
+}
+
+// step returns the position of the cell after (or before) `pos`, crossing
+// line boundaries if necessary. Empty lines have a single position at
+// column 0.
+func step(m Movable, pos geom.Vec2, isForward bool) (next geom.Vec2, ok bool) {
+	if isForward {
+		line, haveLine := m.Line(pos.R)
+		if haveLine && pos.C+1 < len(line) {
+			return geom.Vec2{R: pos.R, C: pos.C + 1}, true
+		}
+
+		if _, haveNext := m.Line(pos.R + 1); !haveNext {
+			return
+		}
+
+		return geom.Vec2{R: pos.R + 1}, true
+	}
+
+	if pos.C > 0 {
+		return geom.Vec2{R: pos.R, C: pos.C - 1}, true
+	}
+
+	line, havePrev := m.Line(pos.R - 1)
+	if !havePrev {
+		return
+	}
+
+	return geom.Vec2{R: pos.R - 1, C: geom.Max(len(line)-1, 0)}, true
+}
+
+// findUnmatched searches from `pos` (inclusive) for an occurrence of
+// `target` that is not balanced by an occurrence of `other`.
+func findUnmatched(
+	m Movable,
+	pos geom.Vec2,
+	target, other rune,
+	isForward bool,
+) (result geom.Vec2, ok bool) {
+	origin := pos.R
+	depth := 0
+	for {
+		if geom.Abs(pos.R-origin) > OBJECT_SEARCH_LINES {
+			return
+		}
+
+		line, haveLine := m.Line(pos.R)
+		if haveLine && pos.C < len(line) {
+			switch line[pos.C].Char {
+			case target:
+				if depth == 0 {
+					return pos, true
+				}
+				depth--
+			case other:
+				depth++
+			}
+		}
+
+		pos, ok = step(m, pos, isForward)
+		if !ok {
+			return
+		}
+	}
+}
+
+// BracketObject returns a TextObject that selects the text between the
+// innermost pair of `open` and `close` that surrounds the cursor, such as
+// vim's "i(" and "a{". Unlike quotes, brackets can span lines.
+func BracketObject(open, close rune) TextObject {
+	return func(m Movable, inner bool) (start, end geom.Vec2, ok bool) {
+		cursor := m.Cursor()
+
+		// If the cursor is on a closing bracket, that is the pair we
+		// want
+		from := cursor
+		if line, haveLine := m.Line(cursor.R); haveLine &&
+			cursor.C < len(line) &&
+			line[cursor.C].Char == close {
+			from, ok = step(m, cursor, false)
+			if !ok {
+				return
+			}
+		}
+
+		start, ok = findUnmatched(m, from, open, close, false)
+		if !ok {
+			return
+		}
+
+		from, ok = step(m, start, true)
+		if !ok {
+			return
+		}
+
+		end, ok = findUnmatched(m, from, close, open, true)
+		if !ok {
+			return
+		}
+
+		if !inner {
+			return
+		}
+
+		start, _ = step(m, start, true)
+		end, _ = step(m, end, false)
+
+		// There is nothing between the brackets
+		if start.GT(end) {
+			return start, end, false
+		}
+
+		return
+	}
+}
+
+// Object returns the TextObject that corresponds to `char`, which is the
+// character that follows "i" or "a" in vim.
+func Object(char string) (object TextObject, ok bool) {
+	switch char {
+	case "w":
+		return WordObject, true
+	case "W":
+		return BigWordObject, true
+	case "l":
+		return LineObject, true
+	case `"`, "'", "`":
+		return QuoteObject(rune(char[0])), true
+	case "(", ")", "b":
+		return BracketObject('(', ')'), true
+	case "[", "]":
+		return BracketObject('[', ']'), true
+	case "{", "}", "B":
+		return BracketObject('{', '}'), true
+	case "<", ">":
+		return BracketObject('<', '>'), true
+	}
+
+	return
+}
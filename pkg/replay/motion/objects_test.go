@@ -0,0 +1,157 @@
+package motion
+
+import (
+	"testing"
+
+	"github.com/cfoust/cy/pkg/geom"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testObject(
+	t *testing.T,
+	m *testMovable,
+	object TextObject,
+	inner bool,
+	cursor geom.Vec2,
+	start, end geom.Vec2,
+) {
+	m.Goto(cursor)
+	actualStart, actualEnd, ok := object(m, inner)
+	require.True(t, ok)
+	require.Equal(t, start, actualStart)
+	require.Equal(t, end, actualEnd)
+}
+
+func TestWordObject(t *testing.T) {
+	m := fromLines("foo bar  baz")
+
+	testObject(t, m, WordObject, true,
+		geom.Vec2{C: 5},
+		geom.Vec2{C: 4},
+		geom.Vec2{C: 6},
+	)
+
+	// Includes trailing whitespace
+	testObject(t, m, WordObject, false,
+		geom.Vec2{C: 5},
+		geom.Vec2{C: 4},
+		geom.Vec2{C: 8},
+	)
+
+	// Or leading whitespace at the end of the line
+	testObject(t, m, WordObject, false,
+		geom.Vec2{C: 10},
+		geom.Vec2{C: 7},
+		geom.Vec2{C: 11},
+	)
+
+	// On whitespace
+	testObject(t, m, WordObject, true,
+		geom.Vec2{C: 7},
+		geom.Vec2{C: 7},
+		geom.Vec2{C: 8},
+	)
+	testObject(t, m, WordObject, false,
+		geom.Vec2{C: 7},
+		geom.Vec2{C: 7},
+		geom.Vec2{C: 11},
+	)
+}
+
+func TestQuoteObject(t *testing.T) {
+	m := fromLines(`echo "foo bar" "" 'baz'`)
+	object := QuoteObject('"')
+
+	testObject(t, m, object, true,
+		geom.Vec2{C: 7},
+		geom.Vec2{C: 6},
+		geom.Vec2{C: 12},
+	)
+	testObject(t, m, object, false,
+		geom.Vec2{C: 7},
+		geom.Vec2{C: 5},
+		geom.Vec2{C: 13},
+	)
+
+	// Before the first string
+	testObject(t, m, object, true,
+		geom.Vec2{C: 0},
+		geom.Vec2{C: 6},
+		geom.Vec2{C: 12},
+	)
+
+	// Empty strings have no inner text
+	m.Goto(geom.Vec2{C: 15})
+	_, _, ok := object(m, true)
+	require.False(t, ok)
+
+	testObject(t, m, QuoteObject('\''), true,
+		geom.Vec2{C: 20},
+		geom.Vec2{C: 19},
+		geom.Vec2{C: 21},
+	)
+}
+
+func TestBracketObject(t *testing.T) {
+	m := fromLines(
+		"(defn foo [x]",
+		"  (+ x (* 2 x)))",
+	)
+	object := BracketObject('(', ')')
+
+	// Nested brackets
+	testObject(t, m, object, true,
+		geom.Vec2{R: 1, C: 8},
+		geom.Vec2{R: 1, C: 8},
+		geom.Vec2{R: 1, C: 12},
+	)
+	testObject(t, m, object, false,
+		geom.Vec2{R: 1, C: 8},
+		geom.Vec2{R: 1, C: 7},
+		geom.Vec2{R: 1, C: 13},
+	)
+
+	// On a closing bracket
+	testObject(t, m, object, false,
+		geom.Vec2{R: 1, C: 14},
+		geom.Vec2{R: 1, C: 2},
+		geom.Vec2{R: 1, C: 14},
+	)
+
+	// Across lines
+	testObject(t, m, object, true,
+		geom.Vec2{C: 2},
+		geom.Vec2{C: 1},
+		geom.Vec2{R: 1, C: 14},
+	)
+
+	testObject(t, m, BracketObject('[', ']'), true,
+		geom.Vec2{C: 11},
+		geom.Vec2{C: 11},
+		geom.Vec2{C: 11},
+	)
+
+	// No enclosing brackets
+	_, _, ok := BracketObject('{', '}')(m, true)
+	require.False(t, ok)
+}
+
+func TestLineObject(t *testing.T) {
+	m := fromLines("  foo bar  ", "")
+
+	testObject(t, m, LineObject, true,
+		geom.Vec2{C: 4},
+		geom.Vec2{C: 2},
+		geom.Vec2{C: 8},
+	)
+	testObject(t, m, LineObject, false,
+		geom.Vec2{C: 4},
+		geom.Vec2{C: 0},
+		geom.Vec2{C: 8},
+	)
+
+	m.Goto(geom.Vec2{R: 1})
+	_, _, ok := LineObject(m, true)
+	require.False(t, ok)
+}
@@ -3,6 +3,7 @@ package motion
 import (
 	"io"
 	"regexp"
+	"unicode/utf8"
 
 	"github.com/cfoust/cy/pkg/emu"
 	"github.com/cfoust/cy/pkg/geom"
@@ -13,23 +14,30 @@ func makePattern(pattern string) *regexp.Regexp {
 }
 
 // An io.RuneReader that provides a sequence of runes corresponding to the
-// cells in an emu.Line.
+// cells in an emu.Line. Cells that contain a grapheme cluster produce every
+// rune in that cluster.
 type LineReader struct {
-	line emu.Line
-	next int
+	line    emu.Line
+	next    int
+	pending []rune
 }
 
 var _ io.RuneReader = (*LineReader)(nil)
 
 func (s *LineReader) ReadRune() (r rune, size int, err error) {
-	next := s.next
-	if next >= len(s.line) {
-		return 0, 0, io.EOF
+	if len(s.pending) == 0 {
+		next := s.next
+		if next >= len(s.line) {
+			return 0, 0, io.EOF
+		}
+
+		s.pending = []rune(s.line[next].String())
+		s.next += s.line[next].Width()
 	}
 
-	r = s.line[next].Char
-	s.next += s.line[next].Width()
-	size = len([]byte(string(r)))
+	r = s.pending[0]
+	s.pending = s.pending[1:]
+	size = utf8.RuneLen(r)
 	return
 }
 
@@ -38,17 +46,24 @@ func (s *LineReader) ReadRune() (r rune, size int, err error) {
 func translateMatch(src []int, line emu.Line) {
 	var index, j int
 	for i := 0; i < len(line) && j < 2; i++ {
-		if index == src[j] {
+		size := len(line[i].String())
+
+		// A match can begin or end in the middle of a grapheme
+		// cluster, in which case we include the whole cell
+		if j == 0 && src[j] < index+size {
+			src[j] = i
+			j++
+		} else if j == 1 && src[j] <= index {
 			src[j] = i
 			j++
 		}
 
-		index += len([]byte(string(line[i].Char)))
+		index += size
 		i += line[i].Width() - 1
 	}
 
 	// This handles the case where the match was at the end of the line
-	if j == 1 && index == src[j] {
+	if j == 1 {
 		src[j] = len(line)
 	}
 }
@@ -37,6 +37,23 @@ func TestFind(t *testing.T) {
 			emu.LineFromString("foo foo foo"),
 		),
 	)
+	// Combining characters should not change the column of the match
+	require.Equal(
+		t,
+		[]int{4, 7},
+		findLine(
+			makePattern("bar"),
+			emu.LineFromString("cafe\u0301bar"),
+		),
+	)
+	require.Equal(
+		t,
+		[]int{3, 4},
+		findLine(
+			makePattern("e\u0301"),
+			emu.LineFromString("cafe\u0301bar"),
+		),
+	)
 	require.Equal(
 		t,
 		([]int)(nil),
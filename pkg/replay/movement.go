@@ -23,8 +23,10 @@ func (r *Replay) moveCursorY(delta int) {
 }
 
 func (r *Replay) resize(newViewport geom.Size) {
-	// Remove one row for our status line
-	newViewport.R = geom.Max(newViewport.R-1, 0)
+	r.size = newViewport
+
+	// Remove rows for our status line and the timeline
+	newViewport.R = geom.Max(newViewport.R-r.statusRows(), 0)
 	r.viewport = newViewport
 	r.movement.Resize(newViewport)
 }
@@ -65,14 +67,55 @@ func (r *Replay) handleCopy() (taro.Model, tea.Cmd) {
 	}
 
 	r.isSelecting = false
-	text := r.movement.ReadString(r.selectStart, r.movement.Cursor())
-	return r, func() tea.Msg {
-		return taro.PublishMsg{
-			Msg: CopyEvent{
-				Text: text,
-			},
+
+	cursor := r.movement.Cursor()
+	var text string
+	switch r.selectMode {
+	case SelectLine:
+		text = r.movement.ReadLines(r.selectStart.R, cursor.R)
+	case SelectBlock:
+		text = r.movement.ReadBlock(r.selectStart, cursor)
+	default:
+		text = r.movement.ReadString(r.selectStart, cursor)
+	}
+
+	return r, r.publishCopy(text)
+}
+
+// handleSelect starts a selection of type `mode`. If the user is already
+// selecting, it either changes the type of the selection or, if the
+// selection is already of that type, stops selecting, just like in vim.
+func (r *Replay) handleSelect(mode SelectMode) {
+	if !r.isCopyMode() {
+		return
+	}
+
+	if r.isSelecting {
+		if r.selectMode == mode {
+			r.isSelecting = false
+			return
 		}
+
+		r.selectMode = mode
+		return
+	}
+
+	r.isSelecting = true
+	r.selectMode = mode
+	r.selectStart = r.movement.Cursor()
+}
+
+// getSelection returns the region of the screen covered by the selection.
+func (r *Replay) getSelection() (start, end geom.Vec2) {
+	start, end = r.selectStart, r.movement.Cursor()
+	if r.selectMode != SelectLine {
+		return
 	}
+
+	start, end = geom.NormalizeRange(start, end)
+	start.C = 0
+	end = r.lineEnd(end.R)
+	return
 }
 
 func (r *Replay) isFlowMode() bool {
@@ -98,6 +141,9 @@ func (r *Replay) initializeMovement() {
 func (r *Replay) exitCopyMode() {
 	r.mode = ModeTime
 	r.isSelecting = false
+	r.isYanking = false
+	r.count = 0
+	r.register = ""
 	r.isSwapped = false
 	r.initializeMovement()
 }
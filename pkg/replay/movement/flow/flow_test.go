@@ -542,3 +542,58 @@ func TestBlank(t *testing.T) {
 	r.MoveCursorY(-2)
 	require.Equal(t, geom.Vec2{R: 1, C: 0}, r.cursor)
 }
+
+func TestBlock(t *testing.T) {
+	size := geom.Size{R: 4, C: 3}
+	s := sessions.NewSimulator()
+	s.Add(
+		size,
+		emu.LineFeedMode,
+		"foo\nbarbaz\nqux",
+	)
+
+	r := createFlowTest(s.Terminal(), size)
+	r.ScrollTop()
+
+	movement.TestHighlight(t, r, size,
+		[]movement.Highlight{
+			{
+				From:  geom.Vec2{R: 2, C: 2},
+				To:    geom.Vec2{R: 0, C: 1},
+				Block: true,
+			},
+		},
+		"011",
+		"011",
+		// The wrapped part of "barbaz" is outside of the block
+		"000",
+		"011",
+	)
+
+	require.Equal(t, "oo\nar\nux", r.ReadBlock(
+		geom.Vec2{R: 0, C: 1},
+		geom.Vec2{R: 2, C: 2},
+	))
+
+	// Columns past the end of a line are ignored
+	require.Equal(t, "\naz\n", r.ReadBlock(
+		geom.Vec2{R: 0, C: 4},
+		geom.Vec2{R: 2, C: 8},
+	))
+}
+
+func TestReadLines(t *testing.T) {
+	size := geom.Size{R: 4, C: 3}
+	s := sessions.NewSimulator()
+	s.Add(
+		size,
+		emu.LineFeedMode,
+		"foo\nbarbaz\nqux",
+	)
+
+	r := createFlowTest(s.Terminal(), size)
+	r.ScrollTop()
+
+	require.Equal(t, "barbaz\nqux", r.ReadLines(2, 1))
+	require.Equal(t, "foo", r.ReadLines(0, 0))
+}
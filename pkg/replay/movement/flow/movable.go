@@ -123,3 +123,43 @@ func (f *flowMovement) ReadString(start, end geom.Vec2) (result string) {
 
 	return result
 }
+
+func (f *flowMovement) ReadBlock(start, end geom.Vec2) (result string) {
+	start, end = geom.NormalizeBox(start, end)
+
+	for i, line := range f.GetLines(start.R, end.R) {
+		if i > 0 {
+			result += "\n"
+		}
+
+		if start.C >= len(line) {
+			continue
+		}
+
+		line = line[start.C:geom.Min(end.C+1, len(line))]
+		if line.IsEmpty() {
+			continue
+		}
+
+		_, lastChar := line.Whitespace()
+		result += line[:lastChar+1].String()
+	}
+
+	return
+}
+
+func (f *flowMovement) ReadLines(start, end int) (result string) {
+	start, end = geom.Min(start, end), geom.Max(start, end)
+
+	// Physical lines are never broken up by wrapping, so we don't need
+	// to do anything special here
+	for i, line := range f.GetLines(start, end) {
+		if i > 0 {
+			result += "\n"
+		}
+
+		result += line.String()
+	}
+
+	return
+}
@@ -11,6 +11,29 @@ import (
 	"github.com/charmbracelet/lipgloss"
 )
 
+// highlightBlock highlights the cells in `row` that fall inside of a
+// rectangular highlight. Columns refer to cells in physical lines, so a
+// block can cover several screen lines of a physical line that wraps.
+func (f *flowMovement) highlightBlock(
+	row emu.Line,
+	screenLine emu.ScreenLine,
+	highlight movement.Highlight,
+) {
+	from, to := geom.NormalizeBox(highlight.From, highlight.To)
+	if screenLine.R < from.R || screenLine.R > to.R {
+		return
+	}
+
+	startCol := geom.Max(from.C, screenLine.C0) - screenLine.C0
+	endCol := geom.Min(to.C, screenLine.C1-1) - screenLine.C0
+	endCol = geom.Min(endCol, len(row)-1)
+
+	for col := startCol; col <= endCol; col++ {
+		row[col].FG = highlight.FG
+		row[col].BG = highlight.BG
+	}
+}
+
 func (f *flowMovement) highlightRow(
 	row emu.Line,
 	start, end geom.Vec2,
@@ -115,6 +138,11 @@ func (f *flowMovement) View(
 		end = geom.Vec2{R: line.R, C: line.C1}
 
 		for _, highlight := range highlights {
+			if highlight.Block {
+				f.highlightBlock(image[row], line, highlight)
+				continue
+			}
+
 			f.highlightRow(
 				image[row],
 				start, end,
@@ -304,3 +304,21 @@ func TestImageHighlight(t *testing.T) {
 		"000",
 	)
 }
+
+func TestReadLinesImage(t *testing.T) {
+	s := sessions.NewSimulator().
+		Add(
+			geom.Size{R: 5, C: 5},
+			emu.LineFeedMode,
+			"foo\n",
+			"barbazqux\n",
+			"\n",
+			"baz",
+		)
+
+	r := createImageTest(s.Terminal(), geom.Size{R: 5, C: 5})
+
+	// Wrapped lines are joined together
+	require.Equal(t, "barbazqux\n\nbaz", r.ReadLines(1, 4))
+	require.Equal(t, "foo\nbarbazqux", r.ReadLines(2, 0))
+}
@@ -164,7 +164,7 @@ func (i *imageMovement) ScrollYDelta(delta int) {
 func (i *imageMovement) recalculateViewport() {
 	termSize := i.Terminal.Size()
 	i.minOffset = geom.Vec2{
-		R: -len(i.History()),
+		R: -i.HistoryLength(),
 		C: 0, // always, but for clarity
 	}
 	i.maxOffset = geom.Vec2{
@@ -206,7 +206,7 @@ func (i *imageMovement) ReadString(start, end geom.Vec2) (result string) {
 	start = i.clampToTerminal(start)
 	end = i.clampToTerminal(end)
 	start, end = geom.NormalizeRange(start, end)
-	start, end = normalizeBoxRange(start, end)
+	start, end = geom.NormalizeBox(start, end)
 
 	screen := i.Screen()
 	for row := start.R; row <= end.R; row++ {
@@ -227,6 +227,41 @@ func (i *imageMovement) ReadString(start, end geom.Vec2) (result string) {
 	return
 }
 
+// ReadBlock is identical to ReadString, since selections in image mode are
+// always rectangles.
+func (i *imageMovement) ReadBlock(start, end geom.Vec2) string {
+	return i.ReadString(start, end)
+}
+
+func (i *imageMovement) ReadLines(start, end int) (result string) {
+	start, end = geom.Min(start, end), geom.Max(start, end)
+
+	for row := start; row <= end; row++ {
+		line, ok := i.Line(row)
+		if !ok {
+			continue
+		}
+
+		// A line that wraps continues on the next row, so it is not
+		// followed by a line break
+		if line.IsWrapped() {
+			result += line.String()
+			continue
+		}
+
+		if !line.IsEmpty() {
+			_, lastChar := line.Whitespace()
+			result += line[:lastChar+1].String()
+		}
+
+		if row != end {
+			result += "\n"
+		}
+	}
+
+	return
+}
+
 func (i *imageMovement) Resize(size geom.Vec2) {
 	i.viewport = size
 	i.recalculateViewport()
@@ -314,20 +349,6 @@ func (i *imageMovement) highlightRange(state *tty.State, from, to geom.Vec2, fg,
 	}
 }
 
-// normalizeBoxRange normalizes box selections so that `from` is the top-left
-// coordinate and `to` is the bottom right coordinate defining the box.
-func normalizeBoxRange(from, to geom.Vec2) (newFrom, newTo geom.Vec2) {
-	newFrom = geom.Vec2{
-		R: geom.Min(from.R, to.R),
-		C: geom.Min(from.C, to.C),
-	}
-	newTo = geom.Vec2{
-		R: geom.Max(from.R, to.R),
-		C: geom.Max(from.C, to.C),
-	}
-	return
-}
-
 func (i *imageMovement) highlightRow(
 	row emu.Line,
 	start, end geom.Vec2,
@@ -401,7 +422,7 @@ func (i *imageMovement) View(
 		from, to = geom.NormalizeRange(from, to)
 
 		if !highlight.Screen {
-			from, to = normalizeBoxRange(from, to)
+			from, to = geom.NormalizeBox(from, to)
 		}
 
 		highlight.From = from
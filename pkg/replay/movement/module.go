@@ -10,7 +10,11 @@ import (
 type Highlight struct {
 	// Whether this Highlight is in screen space or in the reference frame
 	// of the Movement.
-	Screen   bool
+	Screen bool
+	// Whether this Highlight is a rectangle with `From` and `To` at its
+	// corners, rather than every cell between them. Image mode renders
+	// all highlights that are not in screen space as rectangles.
+	Block    bool
 	From, To geom.Vec2
 	FG, BG   emu.Color
 }
@@ -39,6 +43,16 @@ type Movement interface {
 	// `end` in the reference frame of the Movement.
 	ReadString(start, end geom.Vec2) string
 
+	// ReadBlock reads the string data in the rectangle with corners at
+	// `start` and `end`, which are in the reference frame of the
+	// Movement.
+	ReadBlock(start, end geom.Vec2) string
+
+	// ReadLines reads every line from row `start` to row `end`,
+	// inclusive. Lines that wrap onto the next row of the screen are not
+	// broken up.
+	ReadLines(start, end int) string
+
 	// Resize resizes the Movement and makes any necessary viewport
 	// adjustments.
 	Resize(geom.Size)
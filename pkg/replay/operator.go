@@ -0,0 +1,383 @@
+package replay
+
+import (
+	"strconv"
+
+	"github.com/cfoust/cy/pkg/geom"
+	"github.com/cfoust/cy/pkg/replay/motion"
+	"github.com/cfoust/cy/pkg/taro"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// motionKind determines which text is yanked when a motion follows an
+// operator. See `:help exclusive` and `:help linewise` in vim.
+type motionKind int
+
+const (
+	// The cell at the end of the motion is not included.
+	motionExclusive motionKind = iota
+	// The cell at the end of the motion is included.
+	motionInclusive
+	// Every line the motion touches is included in its entirety.
+	motionLinewise
+)
+
+// MOTION_KINDS contains every action that can follow an operator.
+var MOTION_KINDS = map[ActionType]motionKind{
+	ActionBeginning:           motionLinewise,
+	ActionEnd:                 motionLinewise,
+	ActionSearchAgain:         motionExclusive,
+	ActionSearchReverse:       motionExclusive,
+	ActionCursorDown:          motionLinewise,
+	ActionCursorUp:            motionLinewise,
+	ActionCursorLeft:          motionExclusive,
+	ActionCursorRight:         motionExclusive,
+	ActionScrollDownHalf:      motionLinewise,
+	ActionScrollUpHalf:        motionLinewise,
+	ActionJumpAgain:           motionInclusive,
+	ActionJumpReverse:         motionInclusive,
+	ActionJumpForward:         motionInclusive,
+	ActionJumpBackward:        motionExclusive,
+	ActionJumpToForward:       motionInclusive,
+	ActionJumpToBackward:      motionExclusive,
+	ActionStartOfLine:         motionExclusive,
+	ActionFirstNonBlank:       motionExclusive,
+	ActionEndOfLine:           motionInclusive,
+	ActionLastNonBlank:        motionInclusive,
+	ActionStartOfScreenLine:   motionExclusive,
+	ActionFirstNonBlankScreen: motionExclusive,
+	ActionMiddleOfScreenLine:  motionExclusive,
+	ActionMiddleOfLine:        motionExclusive,
+	ActionEndOfScreenLine:     motionInclusive,
+	ActionLastNonBlankScreen:  motionInclusive,
+	ActionWordForward:         motionExclusive,
+	ActionWordBackward:        motionExclusive,
+	ActionWordEndForward:      motionInclusive,
+	ActionWordEndBackward:     motionInclusive,
+	ActionBigWordForward:      motionExclusive,
+	ActionBigWordBackward:     motionExclusive,
+	ActionBigWordEndForward:   motionInclusive,
+	ActionBigWordEndBackward:  motionInclusive,
+}
+
+// handleCount accumulates the digits of a count, returning true if `msg`
+// was consumed.
+func (r *Replay) handleCount(msg ActionEvent) bool {
+	switch {
+	case msg.Type == ActionCount:
+		digit, err := strconv.Atoi(msg.Arg)
+		if err != nil || digit < 0 || digit > 9 {
+			r.count = 0
+			return true
+		}
+
+		r.count = geom.Min(r.count*10+digit, MAX_COUNT)
+		return true
+	// Just like in vim, "0" continues a count that has already begun
+	case msg.Type == ActionStartOfLine && r.count > 0:
+		r.count = geom.Min(r.count*10, MAX_COUNT)
+		return true
+	}
+
+	return false
+}
+
+// takeCount returns the current count (or 0 if there is none) and resets
+// it.
+func (r *Replay) takeCount() int {
+	count := r.count
+	r.count = 0
+	return count
+}
+
+func (r *Replay) repeatJump(
+	needle string,
+	isForward, isTo bool,
+	count int,
+) {
+	// "2tx" jumps onto the first "x" and then up to the second one
+	for i := 0; i < count-1; i++ {
+		r.handleJump(needle, isForward, false)
+	}
+
+	r.handleJump(needle, isForward, isTo)
+}
+
+// move performs the motion described by `msg`, repeating it `count` times
+// if necessary. It returns false if `msg` is not a motion.
+func (r *Replay) move(msg ActionEvent, count int) (kind motionKind, ok bool) {
+	kind, ok = MOTION_KINDS[msg.Type]
+	if !ok {
+		return
+	}
+
+	repeat := geom.Max(count, 1)
+	viewport := r.viewport
+
+	switch msg.Type {
+	case ActionBeginning, ActionEnd:
+		// In time mode, these move in time instead
+		if !r.isCopyMode() {
+			return kind, false
+		}
+
+		// "10G" and "10gg" both go to the tenth line
+		if count > 0 {
+			r.movement.Goto(geom.Vec2{
+				R: geom.Clamp(
+					count-1,
+					0,
+					geom.Max(r.movement.NumLines()-1, 0),
+				),
+			})
+			return
+		}
+
+		if msg.Type == ActionBeginning {
+			r.movement.ScrollTop()
+		} else {
+			r.movement.ScrollBottom()
+		}
+	case ActionSearchAgain, ActionSearchReverse:
+		if !r.isCopyMode() {
+			return kind, false
+		}
+
+		for i := 0; i < repeat; i++ {
+			r.incr.Next(
+				r.movement,
+				msg.Type == ActionSearchAgain,
+			)
+		}
+	case ActionScrollUpHalf:
+		r.moveCursorY(-(viewport.R / 2) * repeat)
+	case ActionScrollDownHalf:
+		r.moveCursorY((viewport.R / 2) * repeat)
+	case ActionCursorDown:
+		r.moveCursorY(repeat)
+	case ActionCursorUp:
+		r.moveCursorY(-repeat)
+	case ActionCursorLeft:
+		r.moveCursorX(-repeat)
+	case ActionCursorRight:
+		r.moveCursorX(repeat)
+	case ActionJumpReverse, ActionJumpAgain:
+		if len(r.jumpChar) == 0 {
+			return kind, false
+		}
+
+		direction := r.wasJumpForward
+		if msg.Type == ActionJumpReverse {
+			direction = !direction
+		}
+
+		kind = motionInclusive
+		if !direction {
+			kind = motionExclusive
+		}
+
+		r.repeatJump(r.jumpChar, direction, r.wasJumpTo, repeat)
+	case ActionJumpForward, ActionJumpBackward, ActionJumpToForward, ActionJumpToBackward:
+		isForward := msg.Type == ActionJumpForward || msg.Type == ActionJumpToForward
+		isTo := msg.Type == ActionJumpToForward || msg.Type == ActionJumpToBackward
+		r.repeatJump(msg.Arg, isForward, isTo, repeat)
+	case ActionWordForward, ActionWordBackward, ActionWordEndForward, ActionWordEndBackward:
+		isForward := msg.Type == ActionWordForward || msg.Type == ActionWordEndForward
+		isEnd := msg.Type == ActionWordEndForward || msg.Type == ActionWordEndBackward
+		r.mode = ModeCopy
+		for i := 0; i < repeat; i++ {
+			motion.Word(
+				r.movement,
+				isForward,
+				isEnd,
+			)
+		}
+	case ActionBigWordForward, ActionBigWordBackward, ActionBigWordEndForward, ActionBigWordEndBackward:
+		isForward := msg.Type == ActionBigWordForward || msg.Type == ActionBigWordEndForward
+		isEnd := msg.Type == ActionBigWordEndForward || msg.Type == ActionBigWordEndBackward
+		r.mode = ModeCopy
+		for i := 0; i < repeat; i++ {
+			motion.WORD(
+				r.movement,
+				isForward,
+				isEnd,
+			)
+		}
+	default:
+		motion, ok := MOTIONS[msg.Type]
+		if !ok {
+			return kind, false
+		}
+
+		r.mode = ModeCopy
+
+		// "3$" goes to the end of the line two lines down
+		if msg.Type == ActionEndOfLine && repeat > 1 {
+			r.movement.MoveCursorY(repeat - 1)
+		}
+
+		motion(r.movement)
+	}
+
+	return
+}
+
+// yank copies the text between `start` and `end` (inclusive).
+func (r *Replay) yank(start, end geom.Vec2) tea.Cmd {
+	return r.publishCopy(r.movement.ReadString(start, end))
+}
+
+// publishCopy publishes `text` as a CopyEvent, sending it to the register
+// the user chose, if any.
+func (r *Replay) publishCopy(text string) tea.Cmd {
+	register := r.register
+	r.register = ""
+	return func() tea.Msg {
+		return taro.PublishMsg{
+			Msg: CopyEvent{
+				Text:     text,
+				Register: register,
+			},
+		}
+	}
+}
+
+// lineEnd returns the location of the last cell in `row`.
+func (r *Replay) lineEnd(row int) geom.Vec2 {
+	line, _ := r.movement.Line(row)
+	return geom.Vec2{
+		R: row,
+		C: geom.Max(len(line)-1, 0),
+	}
+}
+
+// yankMotion yanks the text covered by a motion that went from `origin` to
+// `dest`.
+func (r *Replay) yankMotion(origin, dest geom.Vec2, kind motionKind) tea.Cmd {
+	// Just like in vim, the cursor ends up at the start of the yanked
+	// text
+	if dest.GT(origin) {
+		r.movement.Goto(origin)
+	}
+
+	start, end := geom.NormalizeRange(origin, dest)
+	switch kind {
+	case motionLinewise:
+		return r.publishCopy(r.movement.ReadLines(start.R, end.R))
+	case motionExclusive:
+		if start == end {
+			return nil
+		}
+
+		// An exclusive motion that ends at the beginning of a line
+		// (such as "yw" on the last word of a line) does not include
+		// the line break
+		if end.C == 0 && end.R > start.R {
+			end = r.lineEnd(end.R - 1)
+		} else {
+			end.C--
+		}
+	}
+
+	return r.yank(start, end)
+}
+
+// yankLines yanks `count` lines starting at the cursor, which is what "yy"
+// does.
+func (r *Replay) yankLines(count int) tea.Cmd {
+	cursor := r.movement.Cursor()
+	last := geom.Min(
+		cursor.R+geom.Max(count, 1)-1,
+		geom.Max(r.movement.NumLines()-1, 0),
+	)
+
+	return r.publishCopy(r.movement.ReadLines(cursor.R, last))
+}
+
+func (r *Replay) findObject(msg ActionEvent) (start, end geom.Vec2, ok bool) {
+	object, ok := motion.Object(msg.Arg)
+	if !ok {
+		return
+	}
+
+	return object(r.movement, msg.Type == ActionInnerObject)
+}
+
+// handleObject selects the text object described by `msg`, if the user is
+// selecting.
+func (r *Replay) handleObject(msg ActionEvent) (taro.Model, tea.Cmd) {
+	if !r.isCopyMode() || !r.isSelecting {
+		return r, nil
+	}
+
+	start, end, ok := r.findObject(msg)
+	if !ok {
+		return r, nil
+	}
+
+	r.selectStart = start
+	r.selectMode = SelectChar
+	r.movement.Goto(end)
+	return r, nil
+}
+
+// handleYank copies the selection if there is one and otherwise waits for
+// a motion or text object that describes the text to copy.
+func (r *Replay) handleYank(count int) (taro.Model, tea.Cmd) {
+	if r.isSelecting {
+		return r.handleCopy()
+	}
+
+	if !r.isCopyMode() {
+		return r, nil
+	}
+
+	r.isYanking = true
+	r.operatorCount = count
+	return r, nil
+}
+
+// multiplyCounts combines the count before an operator with the count
+// before its motion. Just like in vim, they multiply, so "2y3w" yanks six
+// words. The product is subject to the same limit as each count.
+func multiplyCounts(operatorCount, motionCount int) int {
+	if operatorCount == 0 && motionCount == 0 {
+		return 0
+	}
+
+	return geom.Min(
+		geom.Max(operatorCount, 1)*geom.Max(motionCount, 1),
+		MAX_COUNT,
+	)
+}
+
+// handleOperator handles the action that follows "y". Any action that is
+// not a motion or text object cancels the operator.
+func (r *Replay) handleOperator(msg ActionEvent, count int) (taro.Model, tea.Cmd) {
+	r.isYanking = false
+
+	count = multiplyCounts(r.operatorCount, count)
+	r.operatorCount = 0
+
+	switch msg.Type {
+	case ActionYank:
+		return r, r.yankLines(count)
+	case ActionInnerObject, ActionAroundObject:
+		start, end, ok := r.findObject(msg)
+		if !ok {
+			return r, nil
+		}
+
+		r.movement.Goto(start)
+		return r, r.yank(start, end)
+	}
+
+	origin := r.movement.Cursor()
+	kind, ok := r.move(msg, count)
+	if !ok {
+		return r, nil
+	}
+
+	return r, r.yankMotion(origin, r.movement.Cursor(), kind)
+}
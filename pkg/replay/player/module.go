@@ -18,6 +18,8 @@ type Player struct {
 
 	detector *detect.Detector
 	mu       deadlock.RWMutex
+	// The options used to create the Player's terminal.
+	options []emu.TerminalOption
 
 	inUse bool
 
@@ -36,7 +38,7 @@ func (p *Player) Acquire() {
 }
 
 func (p *Player) resetTerminal() {
-	p.Terminal = emu.New()
+	p.Terminal = emu.New(p.options...)
 	p.Terminal.Changes().SetHooks([]string{detect.CY_HOOK})
 }
 
@@ -95,6 +97,29 @@ func (p *Player) Commands() []detect.Command {
 	return p.detector.Commands(p.Terminal, p.events)
 }
 
+// Output returns the lines of output produced by `command`.
+func (p *Player) Output(command detect.Command) []string {
+	return p.Outputs([]detect.Command{command})[0]
+}
+
+// Outputs returns the lines of output produced by each of `commands`. If
+// the player is in use, and thus may be back in time, the output is read
+// from a single separate copy of the terminal.
+func (p *Player) Outputs(commands []detect.Command) [][]string {
+	if p.getInUse() {
+		return FromEvents(p.Events()).Outputs(commands)
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	outputs := make([][]string, len(commands))
+	for i, command := range commands {
+		outputs[i] = detect.Output(p.Terminal, command)
+	}
+	return outputs
+}
+
 // Preview captures a preview with the size `viewport` at `location` in the
 // scrollback of the terminal. You may also provide `highlights` that will be
 // passed to the Flow renderer. Returns nil if the player is "in use", which is
@@ -132,8 +157,13 @@ func (p *Player) Preview(
 	)
 }
 
-func New() *Player {
-	p := &Player{detector: detect.New()}
+// New creates a new Player. Any `options` are passed to the terminal that
+// replays the Player's events.
+func New(options ...emu.TerminalOption) *Player {
+	p := &Player{
+		detector: detect.New(),
+		options:  options,
+	}
 	p.resetTerminal()
 	return p
 }
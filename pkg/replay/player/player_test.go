@@ -5,6 +5,7 @@ import (
 
 	"github.com/cfoust/cy/pkg/emu"
 	"github.com/cfoust/cy/pkg/geom"
+	"github.com/cfoust/cy/pkg/replay/detect"
 	"github.com/cfoust/cy/pkg/sessions"
 	"github.com/cfoust/cy/pkg/sessions/search"
 
@@ -47,3 +48,30 @@ func TestTime(t *testing.T) {
 	require.Equal(t, p.nextDetect, 7)
 	require.Equal(t, "foobar", getLine(p, 0))
 }
+
+func TestOutput(t *testing.T) {
+	events := sessions.NewSimulator().
+		Defaults().
+		Add(
+			detect.TEST_PROMPT, "command\n",
+			"foo\n",
+			detect.TEST_PROMPT,
+		).
+		Events()
+
+	p := FromEvents(events)
+	commands := p.Commands()
+	require.Equal(t, 1, len(commands))
+	require.Equal(t, []string{"foo"}, p.Output(commands[0]))
+
+	// Output is still available when the player is back in time
+	p.Acquire()
+	p.Goto(2, -1)
+	require.Equal(t, []string{"foo"}, p.Output(commands[0]))
+	require.Equal(
+		t,
+		[][]string{{"foo"}, {"foo"}},
+		p.Outputs([]detect.Command{commands[0], commands[0]}),
+	)
+	p.Release()
+}
@@ -351,3 +351,289 @@ func TestIncremental(t *testing.T) {
 	i(ActionSearchAgain)
 	require.Equal(t, geom.Vec2{R: 0, C: 0}, r.movement.Cursor())
 }
+
+// getCopied sends `action` and returns the text it copied, if any.
+func getCopied(t *testing.T, r *Replay, action ActionEvent) string {
+	_, cmd := r.Update(action)
+	if cmd == nil {
+		return ""
+	}
+
+	msg, ok := cmd().(taro.PublishMsg)
+	require.True(t, ok)
+	event, ok := msg.Msg.(CopyEvent)
+	require.True(t, ok)
+	return event.Text
+}
+
+func createCountTest() (*Replay, func(msgs ...interface{})) {
+	s := sessions.NewSimulator().
+		Add(
+			geom.Size{R: 10, C: 30},
+			emu.LineFeedMode,
+			"foo bar baz qux\n",
+			"echo \"one two\" (a (b c))\n",
+			"last line",
+		)
+
+	r, i := createTest(s.Events())
+	i(geom.DEFAULT_SIZE)
+	WithCopyMode(r)
+	r.movement.Goto(geom.Vec2{})
+	return r, i
+}
+
+func count(digits string) (events []interface{}) {
+	for _, digit := range digits {
+		events = append(events, ActionEvent{
+			Type: ActionCount,
+			Arg:  string(digit),
+		})
+	}
+	return
+}
+
+func TestCount(t *testing.T) {
+	r, i := createCountTest()
+
+	i(count("2")...)
+	i(ActionWordForward)
+	require.Equal(t, geom.Vec2{C: 8}, r.movement.Cursor())
+
+	// The count is reset after the motion
+	i(ActionWordForward)
+	require.Equal(t, geom.Vec2{C: 12}, r.movement.Cursor())
+
+	i(count("2")...)
+	i(ActionCursorDown)
+	require.Equal(t, geom.Vec2{R: 2, C: 8}, r.movement.Cursor())
+
+	i(count("1")...)
+	i(ActionStartOfLine)
+	require.Equal(t, 10, r.count)
+	i(ActionCursorLeft)
+	require.Equal(t, geom.Vec2{R: 2, C: 0}, r.movement.Cursor())
+
+	// "2G" goes to the second line
+	i(count("2")...)
+	i(ActionEnd)
+	require.Equal(t, 1, r.movement.Cursor().R)
+
+	// "3fo" jumps to the third "o"
+	i(ActionStartOfLine)
+	i(count("3")...)
+	i(ActionEvent{Type: ActionJumpForward, Arg: "o"})
+	require.Equal(t, geom.Vec2{R: 1, C: 12}, r.movement.Cursor())
+}
+
+func TestMultiplyCounts(t *testing.T) {
+	require.Equal(t, 0, multiplyCounts(0, 0))
+	require.Equal(t, 2, multiplyCounts(2, 0))
+	require.Equal(t, 3, multiplyCounts(0, 3))
+	require.Equal(t, 6, multiplyCounts(2, 3))
+	require.Equal(t, MAX_COUNT, multiplyCounts(MAX_COUNT, MAX_COUNT))
+}
+
+func TestYank(t *testing.T) {
+	r, i := createCountTest()
+
+	i(ActionYank)
+	require.Equal(t, "foo ", getCopied(t, r, ActionEvent{
+		Type: ActionWordForward,
+	}))
+	require.False(t, r.isYanking)
+	require.Equal(t, geom.Vec2{}, r.movement.Cursor())
+
+	// Counts on either side of the operator multiply
+	i(count("2")...)
+	i(ActionYank)
+	i(count("2")...)
+	require.Equal(t, "foo bar baz qux", getCopied(t, r, ActionEvent{
+		Type: ActionWordEndForward,
+	}))
+
+	// "yy"
+	i(count("2")...)
+	i(ActionYank)
+	require.Equal(
+		t,
+		"foo bar baz qux\necho \"one two\" (a (b c))",
+		getCopied(t, r, ActionEvent{Type: ActionYank}),
+	)
+
+	// Text objects
+	r.movement.Goto(geom.Vec2{R: 1, C: 7})
+	i(ActionYank)
+	require.Equal(t, "one two", getCopied(t, r, ActionEvent{
+		Type: ActionInnerObject,
+		Arg:  `"`,
+	}))
+	require.Equal(t, geom.Vec2{R: 1, C: 6}, r.movement.Cursor())
+
+	r.movement.Goto(geom.Vec2{R: 1, C: 17})
+	i(ActionYank)
+	require.Equal(t, "(a (b c))", getCopied(t, r, ActionEvent{
+		Type: ActionAroundObject,
+		Arg:  "b",
+	}))
+
+	// Other actions cancel the operator
+	i(ActionYank, ActionSelect)
+	require.False(t, r.isYanking)
+	require.False(t, r.isSelecting)
+
+	// Text objects also work when selecting
+	r.movement.Goto(geom.Vec2{R: 1, C: 21})
+	i(ActionSelect, ActionEvent{Type: ActionInnerObject, Arg: "("})
+	require.Equal(t, geom.Vec2{R: 1, C: 19}, r.selectStart)
+	require.Equal(t, geom.Vec2{R: 1, C: 21}, r.movement.Cursor())
+	require.Equal(t, "b c", getCopied(t, r, ActionEvent{Type: ActionYank}))
+}
+
+func TestSelectModes(t *testing.T) {
+	r, i := createCountTest()
+
+	// Line
+	r.movement.Goto(geom.Vec2{C: 4})
+	i(ActionSelectLine, ActionCursorDown)
+	require.Equal(t, SelectLine, r.selectMode)
+	start, end := r.getSelection()
+	require.Equal(t, geom.Vec2{}, start)
+	require.Equal(t, 1, end.R)
+	require.Equal(
+		t,
+		"foo bar baz qux\necho \"one two\" (a (b c))",
+		getCopied(t, r, ActionEvent{Type: ActionYank}),
+	)
+	require.False(t, r.isSelecting)
+
+	// Block
+	r.movement.Goto(geom.Vec2{C: 4})
+	i(ActionSelectBlock, ActionCursorDown, ActionCursorDown, ActionCursorRight)
+	require.Equal(t, "ba\n \"\n l", getCopied(t, r, ActionEvent{
+		Type: ActionYank,
+	}))
+
+	// Switching between modes
+	i(ActionSelect)
+	require.Equal(t, SelectChar, r.selectMode)
+	i(ActionSelectBlock)
+	require.True(t, r.isSelecting)
+	require.Equal(t, SelectBlock, r.selectMode)
+	i(ActionSelectBlock)
+	require.False(t, r.isSelecting)
+}
+
+func TestRegister(t *testing.T) {
+	r, i := createCountTest()
+
+	i(ActionEvent{Type: ActionRegister, Arg: "a"})
+	i(ActionYank)
+	_, cmd := r.Update(ActionEvent{Type: ActionWordForward})
+	require.NotNil(t, cmd)
+	msg, ok := cmd().(taro.PublishMsg)
+	require.True(t, ok)
+	require.Equal(t, CopyEvent{
+		Text:     "foo ",
+		Register: "a",
+	}, msg.Msg)
+
+	// The register only applies to a single yank
+	require.Equal(t, "", r.register)
+}
+
+func TestMarks(t *testing.T) {
+	r, i := createTest(createTestSession())
+	i(geom.DEFAULT_SIZE)
+
+	r.forceIndex(2, -1)
+	location := r.Location()
+	i(ActionEvent{Type: ActionMark, Arg: "a"})
+
+	mark, ok := r.marks.Get("a")
+	require.True(t, ok)
+	require.Equal(t, location, mark.Address)
+	require.Equal(t, r.Events()[location.Index].Stamp, mark.Time)
+
+	i(ActionEnd)
+	require.NotEqual(t, location, r.Location())
+	i(ActionEvent{Type: ActionJumpMark, Arg: "a"})
+	require.Equal(t, location, r.Location())
+
+	// Marks that do not exist do nothing
+	i(ActionEvent{Type: ActionJumpMark, Arg: "b"})
+	require.Equal(t, location, r.Location())
+}
+
+func TestTimeline(t *testing.T) {
+	// taro.Test renders at the default size, so the timeline always
+	// has that width
+	size := geom.DEFAULT_SIZE
+	e := sim().
+		Add(size).
+		AddTime(0, "test").
+		AddTime(5*time.Second, "test").
+		AddTime(5*time.Second, "test").
+		Events()
+
+	r, i := createTest(e)
+	i(size)
+	require.Equal(t, size.R-1, r.viewport.R)
+
+	i(ActionToggleTimeline)
+	require.True(t, r.showTimeline)
+	require.Equal(t, size.R-2, r.viewport.R)
+
+	timeline := r.getTimeline(size.C)
+	last := size.C - 1
+	middle, _ := timeline.column(e[2].Stamp)
+	require.True(t, timeline.columns[0].hasResize)
+	require.Equal(t, len("test"), timeline.columns[0].activity)
+	require.Equal(t, 0, timeline.columns[middle-1].activity)
+	require.Equal(t, len("test"), timeline.columns[middle].activity)
+	require.Equal(t, len("test"), timeline.columns[last].activity)
+
+	// Clicking on the timeline jumps to that moment
+	click := func(col int) {
+		i(taro.MouseMsg{
+			Vec2:   geom.Vec2{R: size.R - 2, C: col},
+			Type:   taro.MousePress,
+			Button: taro.MouseLeft,
+			Down:   true,
+		})
+	}
+
+	click(middle + 1)
+	require.Equal(t, 2, r.Location().Index)
+	click(0)
+	require.Equal(t, 1, r.Location().Index)
+
+	// As does dragging
+	i(taro.MouseMsg{
+		Vec2:   geom.Vec2{R: size.R - 2, C: last},
+		Type:   taro.MouseMotion,
+		Button: taro.MouseLeft,
+		Down:   true,
+	})
+	require.Equal(t, 3, r.Location().Index)
+
+	// Dragging continues when the cursor leaves the timeline, until the
+	// button is released
+	click(0)
+	require.Equal(t, 1, r.Location().Index)
+	i(taro.MouseMsg{
+		Vec2:   geom.Vec2{R: 0, C: last},
+		Type:   taro.MouseMotion,
+		Button: taro.MouseLeft,
+		Down:   true,
+	})
+	require.Equal(t, 3, r.Location().Index)
+	i(taro.MouseMsg{
+		Vec2: geom.Vec2{R: 0, C: 0},
+		Type: taro.MousePress,
+	})
+	require.False(t, r.timelineDrag)
+
+	i(ActionToggleTimeline)
+	require.Equal(t, size.R-1, r.viewport.R)
+}
@@ -2,6 +2,7 @@ package replay
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/cfoust/cy/pkg/bind"
 	"github.com/cfoust/cy/pkg/emu"
@@ -29,6 +30,7 @@ type Replayable struct {
 	terminal    *S.Terminal
 	replay      *taro.Program
 	player      *player.Player
+	marks       *Marks
 
 	timeBinds, copyBinds *bind.BindScope
 }
@@ -52,10 +54,50 @@ func (r *Replayable) Screen() mux.Screen {
 	return r.terminal
 }
 
+// SetTheme changes the colors reported to programs that query the
+// terminal's palette.
+func (r *Replayable) SetTheme(theme emu.Theme) {
+	r.terminal.SetTheme(theme)
+}
+
 func (r *Replayable) Commands() []detect.Command {
 	return r.player.Commands()
 }
 
+// Output returns the lines of output produced by `command`, which must be
+// one of the commands returned by Commands.
+func (r *Replayable) Output(command detect.Command) []string {
+	return r.player.Output(command)
+}
+
+// Outputs is like Output, but reads the output of many commands at once,
+// which is much faster when replay mode is open.
+func (r *Replayable) Outputs(commands []detect.Command) [][]string {
+	return r.player.Outputs(commands)
+}
+
+// Events returns all of the events that the Replayable has recorded.
+func (r *Replayable) Events() []sessions.Event {
+	return r.player.Events()
+}
+
+// Marks returns the marks and bookmarks in the Replayable's timeline.
+func (r *Replayable) Marks() *Marks {
+	return r.marks
+}
+
+// Bookmark adds a bookmark with the text `label` at the moment that is
+// currently shown, which is the present if replay mode is not open.
+func (r *Replayable) Bookmark(label string) (Mark, error) {
+	if len(label) == 0 {
+		return Mark{}, fmt.Errorf("bookmark label must not be empty")
+	}
+
+	mark := markAt(r.player.Events(), r.player.Location())
+	mark.Label = label
+	return mark, r.marks.Set(mark)
+}
+
 func (r *Replayable) Preview(
 	location geom.Vec2,
 	highlights []movement.Highlight,
@@ -154,7 +196,7 @@ func (r *Replayable) EnterReplay(options ...Option) {
 		r.player,
 		r.timeBinds,
 		r.copyBinds,
-		options...,
+		append([]Option{WithMarks(r.marks)}, options...)...,
 	)
 
 	replay.Resize(r.size)
@@ -181,10 +223,14 @@ func (r *Replayable) EnterReplay(options ...Option) {
 	}()
 }
 
+// NewReplayable creates a Replayable that records the output of `stream`.
+// Any `options` are used to configure the terminal that holds the
+// Replayable's scrollback buffer.
 func NewReplayable(
 	ctx context.Context,
 	cmd, stream mux.Stream,
 	timeBinds, copyBinds *bind.BindScope,
+	options ...emu.TerminalOption,
 ) *Replayable {
 	lifetime := util.NewLifetime(ctx)
 	r := &Replayable{
@@ -194,7 +240,8 @@ func NewReplayable(
 		copyBinds:       copyBinds,
 		cmd:             cmd,
 		stream:          stream,
-		player:          player.New(),
+		player:          player.New(options...),
+		marks:           NewMarks(),
 	}
 	r.terminal = S.NewTerminal(
 		lifetime.Ctx(),
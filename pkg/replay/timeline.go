@@ -0,0 +1,295 @@
+package replay
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cfoust/cy/pkg/geom"
+	"github.com/cfoust/cy/pkg/geom/tty"
+	P "github.com/cfoust/cy/pkg/io/protocol"
+	"github.com/cfoust/cy/pkg/sessions"
+	"github.com/cfoust/cy/pkg/taro"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// TIMELINE_LEVELS are the glyphs used to show how much output occurred in a
+// column of the timeline, from least to most.
+var TIMELINE_LEVELS = []rune(" ▁▂▃▄▅▆▇█")
+
+// timelineColumn summarizes everything that happened during the span of
+// time covered by one column of the timeline.
+type timelineColumn struct {
+	// The number of bytes of output that were written
+	activity   int
+	hasCommand bool
+	hasResize  bool
+	hasMatch   bool
+	// The name of a mark in this column, or '|' for a bookmark
+	mark rune
+}
+
+// timeline maps the lifetime of a session onto a strip of columns. Columns
+// are proportional to time rather than events, so periods of inactivity
+// take up as much space as periods of activity do.
+type timeline struct {
+	start, end time.Time
+	columns    []timelineColumn
+
+	// The number of events the timeline was built from, used to avoid
+	// recomputing it on every frame
+	numEvents int
+}
+
+func newTimeline(events []sessions.Event, width int) *timeline {
+	t := &timeline{
+		columns:   make([]timelineColumn, geom.Max(width, 0)),
+		numEvents: len(events),
+	}
+
+	if len(events) == 0 {
+		return t
+	}
+
+	t.start = events[0].Stamp
+	t.end = events[len(events)-1].Stamp
+
+	for _, event := range events {
+		column, ok := t.column(event.Stamp)
+		if !ok {
+			continue
+		}
+
+		switch message := event.Message.(type) {
+		case P.OutputMessage:
+			t.columns[column].activity += len(message.Data)
+		case P.SizeMessage:
+			t.columns[column].hasResize = true
+		}
+	}
+
+	return t
+}
+
+// column returns the column that contains `stamp`.
+func (t *timeline) column(stamp time.Time) (column int, ok bool) {
+	width := len(t.columns)
+	if width == 0 {
+		return
+	}
+
+	span := t.end.Sub(t.start)
+	if span <= 0 {
+		return 0, true
+	}
+
+	column = int(
+		float64(stamp.Sub(t.start)) / float64(span) * float64(width-1),
+	)
+	return geom.Clamp(column, 0, width-1), true
+}
+
+// time returns the moment in time at the beginning of `column`.
+func (t *timeline) time(column int) time.Time {
+	width := len(t.columns)
+	if width <= 1 {
+		return t.start
+	}
+
+	span := t.end.Sub(t.start)
+	return t.start.Add(time.Duration(
+		float64(span) * float64(column) / float64(width-1),
+	))
+}
+
+// getTimeline returns the timeline for the current session, only
+// recomputing it if the session or the width of the screen changed.
+func (r *Replay) getTimeline(width int) *timeline {
+	events := r.Events()
+	if r.timeline == nil ||
+		r.timeline.numEvents != len(events) ||
+		len(r.timeline.columns) != width {
+		r.timeline = newTimeline(events, width)
+
+		for _, command := range r.Commands() {
+			if command.Executed < 0 || command.Executed >= len(events) {
+				continue
+			}
+
+			column, ok := r.timeline.column(events[command.Executed].Stamp)
+			if !ok {
+				continue
+			}
+			r.timeline.columns[column].hasCommand = true
+		}
+	}
+
+	return r.timeline
+}
+
+// statusRows returns the number of rows at the bottom of the screen that
+// are taken up by the status bar and the timeline.
+func (r *Replay) statusRows() int {
+	if r.showTimeline {
+		return 2
+	}
+	return 1
+}
+
+func (r *Replay) toggleTimeline() {
+	r.showTimeline = !r.showTimeline
+	r.resize(r.size)
+}
+
+// drawTimeline renders the timeline strip on `row` of the screen.
+func (r *Replay) drawTimeline(state *tty.State, row int) {
+	size := state.Image.Size()
+	events := r.Events()
+	if len(events) == 0 || size.C == 0 {
+		return
+	}
+
+	t := r.getTimeline(size.C)
+
+	// Copy the columns so that search matches and marks, which change
+	// more often than the events do, are not cached
+	columns := make([]timelineColumn, len(t.columns))
+	copy(columns, t.columns)
+
+	for _, match := range r.matches {
+		index := match.Begin.Index
+		if index < 0 || index >= len(events) {
+			continue
+		}
+
+		if column, ok := t.column(events[index].Stamp); ok {
+			columns[column].hasMatch = true
+		}
+	}
+
+	for _, mark := range r.marks.All() {
+		index := mark.Address.Index
+		if index < 0 || index >= len(events) {
+			continue
+		}
+
+		column, ok := t.column(events[index].Stamp)
+		if !ok {
+			continue
+		}
+
+		if mark.IsBookmark() {
+			columns[column].mark = '|'
+			continue
+		}
+		columns[column].mark = []rune(mark.Name)[0]
+	}
+
+	maxActivity := 0
+	for _, column := range columns {
+		maxActivity = geom.Max(maxActivity, column.activity)
+	}
+
+	playhead, _ := t.column(r.currentTime)
+
+	var (
+		base = r.render.NewStyle().
+			Foreground(lipgloss.Color("7")).
+			Background(lipgloss.Color("0"))
+		commandStyle  = base.Copy().Foreground(lipgloss.Color("#4D9DE0"))
+		resizeStyle   = base.Copy().Foreground(lipgloss.Color("#E1BC29"))
+		matchStyle    = base.Copy().Foreground(lipgloss.Color("1"))
+		markStyle     = base.Copy().Foreground(lipgloss.Color("15")).Bold(true)
+		playheadStyle = base.Copy().
+				Foreground(lipgloss.Color("0")).
+				Background(lipgloss.Color("15"))
+	)
+
+	var strip strings.Builder
+	for i, column := range columns {
+		glyph := TIMELINE_LEVELS[0]
+		if column.activity > 0 {
+			level := 1 + (column.activity*(len(TIMELINE_LEVELS)-2))/geom.Max(maxActivity, 1)
+			glyph = TIMELINE_LEVELS[geom.Clamp(level, 1, len(TIMELINE_LEVELS)-1)]
+		}
+
+		style := base
+		hasMarker := true
+		switch {
+		case column.mark != 0:
+			glyph = column.mark
+			style = markStyle
+		case column.hasMatch:
+			style = matchStyle
+		case column.hasCommand:
+			style = commandStyle
+		case column.hasResize:
+			style = resizeStyle
+		default:
+			hasMarker = false
+		}
+
+		// Markers should be visible even when there was no output
+		if hasMarker && glyph == TIMELINE_LEVELS[0] {
+			glyph = '·'
+		}
+
+		if i == playhead {
+			style = playheadStyle
+		}
+
+		strip.WriteString(style.Render(string(glyph)))
+	}
+
+	r.render.RenderAt(state.Image, row, 0, strip.String())
+}
+
+// handleTimelineMouse seeks to the moment in time under the mouse if the
+// user clicked or dragged on the timeline.
+func (r *Replay) handleTimelineMouse(msg taro.MouseMsg) (handled bool, cmd tea.Cmd) {
+	if !r.showTimeline || r.timeline == nil {
+		r.timelineDrag = false
+		return false, nil
+	}
+
+	// The timeline sits right above the status bar
+	onTimeline := msg.R == r.size.R-2
+
+	if msg.Button != taro.MouseLeft || !msg.Down {
+		// Releasing the button ends a drag that began on the timeline
+		dragging := r.timelineDrag
+		r.timelineDrag = false
+		return dragging && !msg.Down, nil
+	}
+
+	switch msg.Type {
+	case taro.MousePress:
+		r.timelineDrag = onTimeline
+	case taro.MouseMotion:
+		// Once a drag has begun on the timeline, the cursor can
+		// leave it without interrupting the drag
+		r.timelineDrag = r.timelineDrag || onTimeline
+	default:
+		return false, nil
+	}
+
+	if !r.timelineDrag {
+		return false, nil
+	}
+
+	events := r.Events()
+	if len(events) == 0 {
+		return true, nil
+	}
+
+	target := r.timeline.time(geom.Clamp(msg.C, 0, len(r.timeline.columns)-1))
+
+	// Find the last event that occurred at or before the target
+	index := sort.Search(len(events), func(i int) bool {
+		return events[i].Stamp.After(target)
+	}) - 1
+
+	return true, r.gotoIndex(geom.Max(index, 0), -1)
+}
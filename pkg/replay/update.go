@@ -5,10 +5,10 @@ import (
 
 	"github.com/cfoust/cy/pkg/bind"
 	"github.com/cfoust/cy/pkg/geom"
-	"github.com/cfoust/cy/pkg/replay/motion"
 	"github.com/cfoust/cy/pkg/taro"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/rs/zerolog/log"
 )
 
 func (r *Replay) quit() (taro.Model, tea.Cmd) {
@@ -32,8 +32,6 @@ func (r *Replay) emit(event bind.BindEvent) tea.Cmd {
 }
 
 func (r *Replay) Update(msg tea.Msg) (taro.Model, tea.Cmd) {
-	viewport := r.viewport
-
 	switch msg := msg.(type) {
 	case applyOptions:
 		for _, option := range msg.options {
@@ -136,6 +134,10 @@ func (r *Replay) Update(msg tea.Msg) (taro.Model, tea.Cmd) {
 
 	switch msg := msg.(type) {
 	case taro.MouseMsg:
+		if handled, cmd := r.handleTimelineMouse(msg); handled {
+			return r, cmd
+		}
+
 		switch msg.Button {
 		case taro.MouseWheelUp:
 			r.scrollYDelta(-1)
@@ -148,6 +150,20 @@ func (r *Replay) Update(msg tea.Msg) (taro.Model, tea.Cmd) {
 		}
 	case ActionEvent:
 		r.isPlaying = false
+
+		if r.handleCount(msg) {
+			return r, nil
+		}
+
+		count := r.takeCount()
+		if r.isYanking {
+			return r.handleOperator(msg, count)
+		}
+
+		if _, ok := r.move(msg, count); ok {
+			return r, nil
+		}
+
 		switch msg.Type {
 		case ActionQuit:
 			// Ignore an in-progress search
@@ -167,30 +183,13 @@ func (r *Replay) Update(msg tea.Msg) (taro.Model, tea.Cmd) {
 
 			return r.quit()
 		case ActionBeginning:
-			if r.isCopyMode() {
-				r.movement.ScrollTop()
-				return r, nil
-			}
-
 			return r, r.gotoIndex(0, -1)
 		case ActionEnd:
-			if r.isCopyMode() {
-				r.movement.ScrollBottom()
-				return r, nil
-			}
 			return r, r.gotoIndex(-1, -1)
 		case ActionSwapScreen:
 			r.swapScreen()
 			return r, nil
 		case ActionSearchAgain, ActionSearchReverse:
-			if r.isCopyMode() {
-				r.incr.Next(
-					r.movement,
-					msg.Type == ActionSearchAgain,
-				)
-				return r, nil
-			}
-
 			return r, r.searchAgain(
 				msg.Type != ActionSearchReverse,
 			)
@@ -215,77 +214,57 @@ func (r *Replay) Update(msg tea.Msg) (taro.Model, tea.Cmd) {
 			return r, r.gotoIndex(r.Location().Index-1, -1)
 		case ActionTimeStepForward:
 			return r, r.gotoIndex(r.Location().Index+1, -1)
-		case ActionScrollUpHalf:
-			r.moveCursorY(-(viewport.R / 2))
-		case ActionScrollDownHalf:
-			r.moveCursorY((viewport.R / 2))
 		case ActionScrollUp:
-			r.scrollYDelta(-1)
+			r.scrollYDelta(-geom.Max(count, 1))
 		case ActionScrollDown:
-			r.scrollYDelta(+1)
-		case ActionCursorDown:
-			r.moveCursorY(1)
-		case ActionCursorUp:
-			r.moveCursorY(-1)
-		case ActionCursorLeft:
-			r.moveCursorX(-1)
-		case ActionCursorRight:
-			r.moveCursorX(1)
+			r.scrollYDelta(geom.Max(count, 1))
 		case ActionSelect:
-			if !r.isCopyMode() {
+			r.handleSelect(SelectChar)
+		case ActionSelectLine:
+			r.handleSelect(SelectLine)
+		case ActionSelectBlock:
+			r.handleSelect(SelectBlock)
+		case ActionCopy:
+			return r.handleCopy()
+		case ActionYank:
+			return r.handleYank(count)
+		case ActionInnerObject, ActionAroundObject:
+			return r.handleObject(msg)
+		case ActionRegister:
+			if !r.isCopyMode() || len(msg.Arg) == 0 {
 				return r, nil
 			}
 
-			if r.isSelecting {
-				r.isSelecting = false
+			// A count can come before or after the register, like
+			// in vim
+			r.register = msg.Arg
+			r.count = count
+		case ActionToggleTimeline:
+			r.toggleTimeline()
+		case ActionMark:
+			if len(msg.Arg) == 0 {
 				return r, nil
 			}
 
-			r.isSelecting = true
-			r.selectStart = r.movement.Cursor()
-		case ActionCopy:
-			return r.handleCopy()
-		case ActionJumpReverse, ActionJumpAgain:
-			if len(r.jumpChar) == 0 {
-				return r, nil
-			}
+			mark := markAt(r.Events(), r.Location())
+			mark.Name = msg.Arg
 
-			direction := r.wasJumpForward
-			if msg.Type == ActionJumpReverse {
-				direction = !direction
+			// There is nowhere to show an error in replay mode, and
+			// the mark still works if it could not be written to disk
+			err := r.marks.Set(mark)
+			if err != nil {
+				log.Error().Err(err).Msg("failed to save marks")
+			}
+		case ActionJumpMark:
+			mark, ok := r.marks.Get(msg.Arg)
+			if !ok {
+				return r, nil
 			}
 
-			r.handleJump(r.jumpChar, direction, r.wasJumpTo)
-			return r, nil
-		case ActionJumpForward, ActionJumpBackward, ActionJumpToForward, ActionJumpToBackward:
-			isForward := msg.Type == ActionJumpForward || msg.Type == ActionJumpToForward
-			isTo := msg.Type == ActionJumpToForward || msg.Type == ActionJumpToBackward
-			r.handleJump(
-				msg.Arg,
-				isForward,
-				isTo,
-			)
-			return r, nil
-		case ActionWordForward, ActionWordBackward, ActionWordEndForward, ActionWordEndBackward:
-			isForward := msg.Type == ActionWordForward || msg.Type == ActionWordEndForward
-			isEnd := msg.Type == ActionWordEndForward || msg.Type == ActionWordEndBackward
-			r.mode = ModeCopy
-			motion.Word(
-				r.movement,
-				isForward,
-				isEnd,
-			)
-			return r, nil
-		case ActionBigWordForward, ActionBigWordBackward, ActionBigWordEndForward, ActionBigWordEndBackward:
-			isForward := msg.Type == ActionBigWordForward || msg.Type == ActionBigWordEndForward
-			isEnd := msg.Type == ActionBigWordEndForward || msg.Type == ActionBigWordEndBackward
-			r.mode = ModeCopy
-			motion.WORD(
-				r.movement,
-				isForward,
-				isEnd,
+			return r, r.gotoIndex(
+				mark.Address.Index,
+				mark.Address.Offset,
 			)
-			return r, nil
 		case ActionCommandForward, ActionCommandBackward:
 			isForward := msg.Type == ActionCommandForward
 			if !r.isCopyMode() {
@@ -297,12 +276,6 @@ func (r *Replay) Update(msg tea.Msg) (taro.Model, tea.Cmd) {
 			isForward := msg.Type == ActionCommandSelectForward
 			return r.jumpSelectCommand(isForward)
 		}
-
-		if motion, ok := MOTIONS[msg.Type]; ok {
-			r.mode = ModeCopy
-			motion(r.movement)
-			return r, nil
-		}
 	}
 
 	return r, nil
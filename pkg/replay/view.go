@@ -90,6 +90,13 @@ func (r *Replay) drawStatusBar(state *tty.State) {
 		if r.isSelecting {
 			statusText = "VISUAL"
 			statusBG = lipgloss.Color("#3BB273")
+
+			switch r.selectMode {
+			case SelectLine:
+				statusText = "VISUAL LINE"
+			case SelectBlock:
+				statusText = "VISUAL BLOCK"
+			}
 		}
 	}
 	if r.isPlaying {
@@ -188,17 +195,36 @@ func (r *Replay) drawStatusBar(state *tty.State) {
 	)
 
 	progressWidth := size.C - lipgloss.Width(leftSide) - 3
-	percent := int((float64(r.Location().Index) / float64(len(events))) * float64(progressWidth))
-	progressBar := ""
-	for i := 0; i < progressWidth; i++ {
+	getOffset := func(index int) int {
+		return int((float64(index) / float64(len(events))) * float64(progressWidth))
+	}
+
+	percent := getOffset(r.Location().Index)
+	progress := make([]rune, geom.Max(progressWidth, 0))
+	for i := range progress {
 		if i <= percent {
-			progressBar += "▒"
+			progress[i] = '▒'
 		} else {
-			progressBar += "-"
+			progress[i] = '-'
+		}
+	}
+
+	// Show marks as ticks on the progress bar, using the name of the
+	// mark where it has one
+	for _, mark := range r.marks.All() {
+		offset := getOffset(mark.Address.Index)
+		if offset < 0 || offset >= len(progress) {
+			continue
+		}
+
+		tick := '|'
+		if !mark.IsBookmark() {
+			tick = []rune(mark.Name)[0]
 		}
+		progress[offset] = tick
 	}
 
-	progressBar = "[" + progressBar + "]"
+	progressBar := "[" + string(progress) + "]"
 	progressBar = statusBarStyle.
 		Copy().
 		Render(progressBar)
@@ -291,11 +317,13 @@ func (r *Replay) View(state *tty.State) {
 	// Show the selection state
 	////////////////////////////
 	if r.isCopyMode() && r.isSelecting {
+		from, to := r.getSelection()
 		highlights = append(
 			highlights,
 			movement.Highlight{
-				From: r.selectStart,
-				To:   r.movement.Cursor(),
+				From:  from,
+				To:    to,
+				Block: r.selectMode == SelectBlock,
 				FG: r.render.ConvertLipgloss(
 					lipgloss.Color("9"),
 				),
@@ -377,6 +405,10 @@ func (r *Replay) View(state *tty.State) {
 	///////////////////////////
 	r.drawStatusBar(state)
 
+	if r.showTimeline {
+		r.drawTimeline(state, state.Image.Size().R-2)
+	}
+
 	if r.incr.IsActive() {
 		state.CursorVisible = false
 		return
@@ -396,8 +428,8 @@ func (r *Replay) View(state *tty.State) {
 	inputSize := input.Size()
 	image.Copy(
 		geom.Vec2{
-			// -1 for the status bar
-			R: geom.Clamp(state.Cursor.R, 0, size.R-inputSize.R-1),
+			// Leave room for the status bar and timeline
+			R: geom.Clamp(state.Cursor.R, 0, size.R-inputSize.R-r.statusRows()),
 			C: geom.Clamp(state.Cursor.C, 0, size.C-inputSize.C),
 		},
 		state.Image,
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/cfoust/cy/pkg/geom"
 	P "github.com/cfoust/cy/pkg/io/protocol"
 )
 
@@ -17,12 +18,20 @@ func WriteAsciinema(filename string, events []Event) error {
 		return err
 	}
 
+	// The dimensions in the header are the initial size of the terminal
+	size := geom.DEFAULT_SIZE
+	for _, event := range events {
+		if msg, ok := event.Message.(P.SizeMessage); ok {
+			size = msg.Vec()
+			break
+		}
+	}
+
 	// First write the header
 	data, err := json.Marshal(map[string]interface{}{
 		"version": 2,
-		// Theoretically we could derive these, but who cares?
-		"width":  80,
-		"height": 26,
+		"width":   size.C,
+		"height":  size.R,
 	})
 	if err != nil {
 		return err
@@ -33,6 +33,11 @@ func (s *EventStream) Kill() {
 	s.stream.Kill()
 }
 
+// Handler returns the EventHandler that receives this EventStream's events.
+func (s *EventStream) Handler() EventHandler {
+	return s.handler
+}
+
 func (s *EventStream) process(data P.Message) error {
 	event := Event{
 		Stamp:   time.Now(),
@@ -108,13 +113,23 @@ func NewMemoryRecorder() *MemoryRecorder {
 	return &MemoryRecorder{}
 }
 
+// RECORDING_FLUSH_INTERVAL is how often a FileRecorder writes buffered events
+// to disk.
+const RECORDING_FLUSH_INTERVAL = time.Second
+
 // A FileRecorder writes incoming events to a file.
 type FileRecorder struct {
-	eventc chan Event
+	filename string
+	eventc   chan Event
 }
 
 var _ EventHandler = (*FileRecorder)(nil)
 
+// Filename returns the path of the file to which events are written.
+func (f *FileRecorder) Filename() string {
+	return f.filename
+}
+
 func (f *FileRecorder) Process(event Event) error {
 	f.eventc <- event
 	return nil
@@ -122,7 +137,8 @@ func (f *FileRecorder) Process(event Event) error {
 
 func NewFileRecorder(ctx context.Context, filename string) (*FileRecorder, error) {
 	f := &FileRecorder{
-		eventc: make(chan Event, 100),
+		filename: filename,
+		eventc:   make(chan Event, 100),
 	}
 
 	w, err := Create(filename)
@@ -134,11 +150,25 @@ func NewFileRecorder(ctx context.Context, filename string) (*FileRecorder, error
 
 	go func() {
 		defer w.Close()
+
+		// Events are flushed periodically so that the recording
+		// survives the server exiting unexpectedly
+		ticker := time.NewTicker(RECORDING_FLUSH_INTERVAL)
+		defer ticker.Stop()
+
+		dirty := false
 		for {
 			select {
 			case event := <-f.eventc:
 				// TODO(cfoust): 09/19/23 error handling
 				w.Write(event)
+				dirty = true
+			case <-ticker.C:
+				if !dirty {
+					continue
+				}
+				w.Flush()
+				dirty = false
 			case <-ctx.Done():
 				return
 			}
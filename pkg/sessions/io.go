@@ -3,6 +3,7 @@ package sessions
 import (
 	"compress/gzip"
 	"fmt"
+	"io"
 	"os"
 
 	P "github.com/cfoust/cy/pkg/io/protocol"
@@ -20,6 +21,9 @@ type header struct {
 
 type SessionWriter interface {
 	Write(event Event) error
+	// Flush writes any buffered events to disk so that they can be read
+	// even if the writer is never closed.
+	Flush() error
 	Close() error
 }
 
@@ -53,6 +57,10 @@ func (s *sessionWriter) Write(event Event) error {
 	}
 }
 
+func (s *sessionWriter) Flush() error {
+	return s.gz.Flush()
+}
+
 func (s *sessionWriter) Close() error {
 	if err := s.gz.Close(); err != nil {
 		return err
@@ -89,6 +97,7 @@ func Create(filename string) (SessionWriter, error) {
 
 type SessionReader interface {
 	Read() (Event, error)
+	Close() error
 }
 
 type sessionReader struct {
@@ -134,6 +143,15 @@ func (s *sessionReader) Read() (Event, error) {
 	return event, nil
 }
 
+func (s *sessionReader) Close() error {
+	if err := s.gz.Close(); err != nil {
+		s.file.Close()
+		return err
+	}
+
+	return s.file.Close()
+}
+
 func Open(filename string) (SessionReader, error) {
 	f, err := os.Open(filename)
 	if err != nil {
@@ -143,6 +161,7 @@ func Open(filename string) (SessionReader, error) {
 	handle := new(codec.MsgpackHandle)
 	gz, err := gzip.NewReader(f)
 	if err != nil {
+		f.Close()
 		return nil, err
 	}
 	decoder := codec.NewDecoder(gz, handle)
@@ -157,12 +176,37 @@ func Open(filename string) (SessionReader, error) {
 	var h header
 	err = decoder.Decode(&h)
 	if err != nil {
+		reader.Close()
 		return nil, err
 	}
 
 	if h.Version != SESSION_FILE_VERSION {
+		reader.Close()
 		return nil, fmt.Errorf("header version %d did not match %d", h.Version, SESSION_FILE_VERSION)
 	}
 
 	return &reader, nil
 }
+
+// ReadAll reads every event in the .borg file at `filename`.
+func ReadAll(filename string) ([]Event, error) {
+	reader, err := Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	events := make([]Event, 0)
+	for {
+		event, err := reader.Read()
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
@@ -44,10 +44,15 @@ func TestReadWrite(t *testing.T) {
 
 	r, err := Open(name)
 	require.NoError(t, err)
+	defer r.Close()
 
 	for _, before := range events {
 		after, err := r.Read()
 		require.NoError(t, err)
 		require.Equal(t, before, after)
 	}
+
+	read, err := ReadAll(name)
+	require.NoError(t, err)
+	require.Equal(t, events, read)
 }
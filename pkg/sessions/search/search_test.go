@@ -338,3 +338,30 @@ func TestSearcher(t *testing.T) {
 		},
 	}, matches)
 }
+
+func TestUnicode(t *testing.T) {
+	sim := sessions.NewSimulator().
+		Add(
+			"héllo ",
+			"wörld",
+		)
+	results, err := Search(sim.Events(), "wörld", nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(results))
+	require.Equal(t, 1, len(results[0].Appearances))
+	require.Equal(t, Selection{
+		From: geom.Vec2{C: 6},
+		To:   geom.Vec2{C: 10},
+	}, results[0].Appearances[0].Selection)
+
+	// Matches refer to the bytes of the input, even for multi-byte
+	// characters
+	s := NewSearcher()
+	s.Parse(sim.Events())
+	require.Equal(t, "héllo wörld", string(s.Bytes()))
+	matches := s.Find(regexp.MustCompile("llo wö"))
+	require.Equal(t, 1, len(matches))
+	require.Equal(t, Address{Index: 0, Offset: 3}, matches[0].Begin)
+	require.Equal(t, Address{Index: 1, Offset: 3}, matches[0].End)
+	require.True(t, matches[0].Continuous)
+}
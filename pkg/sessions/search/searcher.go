@@ -3,7 +3,9 @@ package search
 import (
 	"bytes"
 	"regexp"
+	"unicode/utf8"
 
+	"github.com/cfoust/cy/pkg/geom"
 	P "github.com/cfoust/cy/pkg/io/protocol"
 	"github.com/cfoust/cy/pkg/sessions"
 
@@ -31,9 +33,12 @@ type Match struct {
 // though it were a VT100 terminal, and then allows you to search through them
 // using regexp.
 type searcher struct {
-	buffer      bytes.Buffer
-	parser      *vtparser.Parser
-	didPrint    bool
+	buffer bytes.Buffer
+	parser *vtparser.Parser
+	// The number of bytes written to the buffer by the last byte of input
+	numPrinted int
+	// The number of bytes of input since something was last printed
+	numSkipped  int
 	lastPrinted bool
 	sections    []section
 }
@@ -114,43 +119,44 @@ func (s *searcher) Find(re *regexp.Regexp) (result []Match) {
 }
 
 func (s *searcher) parseData(index int, data []byte) {
-	isPrinted := false
-	count := 0
 	for offset, b := range data {
-		s.didPrint = false
+		s.numPrinted = 0
 		s.parser.Advance(b)
 
-		// If this is a direct continuation of the previous section, indicate that
-		if offset == 0 && s.didPrint && s.lastPrinted && len(s.sections) > 0 {
-			s.sections[len(s.sections)-1].Continuous = true
+		if s.numPrinted == 0 {
+			s.numSkipped++
+			s.lastPrinted = false
+			continue
 		}
 
-		s.lastPrinted = s.didPrint
+		// A multi-byte character is printed on its final byte, so the
+		// bytes it occupies begin earlier in the input
+		begin := geom.Max(offset-s.numPrinted+1, 0)
+		bufferStart := s.buffer.Len() - s.numPrinted
 
-		if s.didPrint == isPrinted {
-			count++
-			continue
+		// If this is a direct continuation of the previous section, indicate that
+		if begin == 0 && s.lastPrinted && len(s.sections) > 0 {
+			s.sections[len(s.sections)-1].Continuous = true
 		}
 
-		if count > 0 && isPrinted {
-			s.sections = append(s.sections, section{
-				Index:  index,
-				Offset: offset - count,
-				Start:  s.buffer.Len() - count,
-				Bytes:  count,
-			})
-		}
+		s.numSkipped = 0
+		s.lastPrinted = true
 
-		count = 1
-		isPrinted = s.didPrint
-	}
+		if numSections := len(s.sections); numSections > 0 {
+			last := &s.sections[numSections-1]
+			if last.Index == index &&
+				last.Offset+last.Bytes == begin &&
+				last.Start+last.Bytes == bufferStart {
+				last.Bytes += s.numPrinted
+				continue
+			}
+		}
 
-	if count > 0 && isPrinted {
 		s.sections = append(s.sections, section{
 			Index:  index,
-			Offset: len(data) - count,
-			Start:  s.buffer.Len() - count,
-			Bytes:  count,
+			Offset: begin,
+			Start:  bufferStart,
+			Bytes:  s.numPrinted,
 		})
 	}
 }
@@ -166,8 +172,14 @@ func (s *searcher) Parse(events []sessions.Event) {
 }
 
 func (s *searcher) print(c rune) {
-	s.buffer.Write([]byte{byte(c)})
-	s.didPrint = true
+	// Invalid UTF-8 is printed as a replacement character, which may be
+	// longer than the bytes it replaced. We need the searchable text to
+	// occupy the same number of bytes as the input.
+	if c == utf8.RuneError && s.numSkipped+1 < utf8.RuneLen(c) {
+		c = '?'
+	}
+
+	s.numPrinted, _ = s.buffer.WriteRune(c)
 }
 
 func (s *searcher) execute(b byte) {
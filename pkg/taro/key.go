@@ -29,9 +29,11 @@ SOFTWARE.
 package taro
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"regexp"
+	"unicode"
 	"unicode/utf8"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -91,6 +93,14 @@ type Key struct {
 	Type  KeyType
 	Runes []rune
 	Alt   bool
+	// Ctrl, Shift, and Super are only set for key combinations that
+	// cannot be represented by a KeyType, such as ctrl+shift+a or
+	// ctrl+enter. These can only be produced by terminals that support
+	// the kitty keyboard protocol.
+	Ctrl, Shift, Super bool
+	// Paste is true when Runes contains text that was pasted rather than
+	// typed, such as text received inside of a bracketed paste.
+	Paste bool
 }
 
 // String returns a friendly string representation for a key. It's safe (and
@@ -103,6 +113,15 @@ func (k Key) String() (str string) {
 	if k.Alt {
 		str += "alt+"
 	}
+	if k.Super {
+		str += "super+"
+	}
+	if k.Ctrl {
+		str += "ctrl+"
+	}
+	if k.Shift {
+		str += "shift+"
+	}
 	if k.Type == KeyRunes {
 		str += string(k.Runes)
 		return str
@@ -144,10 +163,8 @@ func (k KeyType) String() (str string) {
 // etc) into KeyMsg events. Unrecognized strings are represented as KeyRunes.
 func KeysToMsg(keys ...string) (msgs []KeyMsg) {
 	for _, key := range keys {
-		if _type, ok := keyRefs[key]; ok {
-			msgs = append(msgs, KeyMsg{
-				Type: _type,
-			})
+		if parsed, ok := parseKeyName(key); ok {
+			msgs = append(msgs, KeyMsg(parsed))
 			continue
 		}
 
@@ -161,10 +178,24 @@ func KeysToMsg(keys ...string) (msgs []KeyMsg) {
 
 func KeysToBytes(keys ...KeyMsg) (data []byte, err error) {
 	for _, key := range keys {
+		if key.Paste {
+			data = append(data, []byte(string(key.Runes))...)
+			continue
+		}
+
 		switch key.Type {
 		case KeySpace:
 			data = append(data, []byte(" ")...)
 		case KeyRunes:
+			// Without the kitty protocol the best we can do is
+			// send the control character
+			if key.Ctrl && len(key.Runes) == 1 {
+				if code, ok := controlCode(unicode.ToLower(key.Runes[0])); ok {
+					data = append(data, byte(code))
+					continue
+				}
+			}
+
 			data = append(data, []byte(string(key.Runes))...)
 		default:
 			if seq, ok := inverseSequences[keyLookup{
@@ -226,18 +257,64 @@ func readInputs(input io.Reader) (msgs []Msg, err error) {
 	return
 }
 
+const (
+	pasteStart = "\x1b[200~"
+	pasteEnd   = "\x1b[201~"
+)
+
+// PASTE_END is the sequence a terminal sends at the end of a bracketed
+// paste.
+const PASTE_END = pasteEnd
+
 var unknownCSIRe = regexp.MustCompile(`^\x1b\[[\x30-\x3f]*[\x20-\x2f]*[\x40-\x7e]`)
 
 func isMouseEvent(b []byte) bool {
 	return len(b) >= 6 && b[0] == '\x1b' && b[1] == '[' && b[2] == 'M'
 }
 
+// detectPaste finds text that was sent by the terminal inside of a bracketed
+// paste.
+func detectPaste(b []byte) (w int, msg Msg, ok bool) {
+	if !bytes.HasPrefix(b, []byte(pasteStart)) {
+		return
+	}
+
+	end := bytes.Index(b, []byte(pasteEnd))
+	if end == -1 {
+		return
+	}
+
+	text := b[len(pasteStart):end]
+	return end + len(pasteEnd), KeyMsg{
+		Type:  KeyRunes,
+		Runes: []rune(string(text)),
+		Paste: true,
+	}, true
+}
+
+// IsPartialPaste reports whether `b` contains the beginning of a bracketed
+// paste, but not its end.
+func IsPartialPaste(b []byte) bool {
+	return bytes.HasPrefix(b, []byte(pasteStart)) &&
+		!bytes.Contains(b, []byte(pasteEnd))
+}
+
+// TrimPasteStart removes the sequence that begins a bracketed paste from the
+// beginning of `b`, if it is present.
+func TrimPasteStart(b []byte) []byte {
+	return bytes.TrimPrefix(b, []byte(pasteStart))
+}
+
 func DetectOneMsg(b []byte) (w int, msg Msg) {
 	// Detect mouse events.
 	if isMouseEvent(b) {
 		return 6, MouseMsg(parseX10MouseEvent(b))
 	}
 
+	if w, msg, ok := detectPaste(b); ok {
+		return w, msg
+	}
+
 	// Detect escape sequence and control characters other than NUL,
 	// possibly with an escape character in front to mark the Alt
 	// modifier.
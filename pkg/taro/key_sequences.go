@@ -80,6 +80,15 @@ var seqLengths = func() []int {
 // sequence and a hash map.
 func detectSequence(input []byte) (hasSeq bool, width int, msg Msg) {
 	seqs := extSequences
+
+	// Is this a key encoded with the kitty keyboard protocol? Legacy
+	// sequences we already know about take precedence.
+	if hasSeq, width, msg = detectKitty(input); hasSeq {
+		if _, ok := seqs[string(input[:width])]; !ok {
+			return
+		}
+	}
+
 	for _, sz := range seqLengths {
 		if sz > len(input) {
 			continue
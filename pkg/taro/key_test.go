@@ -18,6 +18,19 @@ func TestKeysToMsg(t *testing.T) {
 	}, KeysToMsg("test", "ctrl+a"))
 }
 
+func TestParseKeyName(t *testing.T) {
+	key, ok := ParseKeyName("enter")
+	assert.True(t, ok)
+	assert.Equal(t, KeyMsg{Type: KeyEnter}, key)
+
+	key, ok = ParseKeyName("ctrl+c")
+	assert.True(t, ok)
+	assert.Equal(t, KeyMsg{Type: KeyCtrlC}, key)
+
+	_, ok = ParseKeyName("test")
+	assert.False(t, ok)
+}
+
 func TestKeysToBytes(t *testing.T) {
 	keys := []KeyMsg{
 		{
@@ -46,6 +59,20 @@ func TestKeysToBytes(t *testing.T) {
 	assert.Equal(t, keys, parsed)
 }
 
+func TestPaste(t *testing.T) {
+	w, msg := DetectOneMsg([]byte("\x1b[200~ctrl+a\x1b[201~b"))
+	assert.Equal(t, 18, w)
+	assert.Equal(t, KeyMsg{
+		Type:  KeyRunes,
+		Runes: []rune("ctrl+a"),
+		Paste: true,
+	}, msg)
+
+	data, err := KeysToBytes(msg.(KeyMsg))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("ctrl+a"), data)
+}
+
 func testMouseInput(t *testing.T, input string) {
 	bytes := []byte(input)
 	_, msg := DetectOneMsg(bytes)
@@ -0,0 +1,461 @@
+package taro
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/cfoust/cy/pkg/emu"
+)
+
+// This file implements support for the kitty keyboard protocol, which allows
+// terminals to report key combinations (such as ctrl+shift+a or ctrl+enter)
+// that cannot be represented with legacy escape sequences.
+// See: https://sw.kovidgoyal.net/kitty/keyboard-protocol/
+
+const (
+	// EnableKittyKeys asks the terminal to disambiguate escape codes.
+	EnableKittyKeys = "\x1b[>1u"
+	// DisableKittyKeys restores the terminal's previous keyboard mode.
+	DisableKittyKeys = "\x1b[<u"
+)
+
+// Modifier bits as they are encoded by the kitty keyboard protocol. The value
+// that appears in escape sequences is one greater than the bitfield.
+const (
+	kittyShift = 1 << iota
+	kittyAlt
+	kittyCtrl
+	kittySuper
+)
+
+// kittyCodes maps the key codes used in `CSI u` sequences to KeyTypes.
+var kittyCodes = map[int]KeyType{
+	9:   KeyTab,
+	13:  KeyEnter,
+	27:  KeyEscape,
+	32:  KeySpace,
+	127: KeyBackspace,
+}
+
+// kittyLetters maps the final byte of legacy-style sequences (`CSI 1 ; mods
+// X`) to KeyTypes.
+var kittyLetters = map[byte]KeyType{
+	'A': KeyUp,
+	'B': KeyDown,
+	'C': KeyRight,
+	'D': KeyLeft,
+	'H': KeyHome,
+	'F': KeyEnd,
+	'P': KeyF1,
+	'Q': KeyF2,
+	'R': KeyF3,
+	'S': KeyF4,
+}
+
+// kittyTildes maps the number in `CSI number ; mods ~` sequences to
+// KeyTypes.
+var kittyTildes = map[int]KeyType{
+	2:  KeyInsert,
+	3:  KeyDelete,
+	5:  KeyPgUp,
+	6:  KeyPgDown,
+	7:  KeyHome,
+	8:  KeyEnd,
+	15: KeyF5,
+	17: KeyF6,
+	18: KeyF7,
+	19: KeyF8,
+	20: KeyF9,
+	21: KeyF10,
+	23: KeyF11,
+	24: KeyF12,
+}
+
+var kittyRe = regexp.MustCompile(`^\x1b\[([0-9:]*)(?:;([0-9:]*))?(?:;([0-9:]*))?([u~ABCDHFPQRS])`)
+
+// newKey creates the Key that represents pressing `base` with the given
+// kitty modifiers. Whenever a combination can be represented with a legacy
+// KeyType (such as KeyCtrlA or KeyCtrlShiftUp) that representation is
+// preferred, so that bindings behave identically regardless of which
+// protocol the terminal uses.
+func newKey(base Key, mods int) Key {
+	key := base
+	key.Alt = key.Alt || mods&kittyAlt != 0
+	ctrl := mods&kittyCtrl != 0
+	shift := mods&kittyShift != 0
+	super := mods&kittySuper != 0
+
+	isRune := key.Type == KeyRunes && len(key.Runes) == 1
+	if isRune || key.Type == KeySpace {
+		r := ' '
+		if isRune {
+			r = key.Runes[0]
+		}
+
+		if shift && !ctrl && unicode.IsLetter(r) {
+			key.Runes = []rune{unicode.ToUpper(r)}
+			shift = false
+		}
+
+		if ctrl && !shift && !super {
+			if code, ok := controlCode(r); ok {
+				return Key{Type: code, Alt: key.Alt}
+			}
+		}
+	}
+
+	if key.Type == KeySpace {
+		key.Runes = spaceRunes
+	}
+
+	if name, ok := keyNames[key.Type]; ok && key.Type != KeyRunes && !super {
+		prefix := ""
+		if ctrl {
+			prefix += "ctrl+"
+		}
+		if shift {
+			prefix += "shift+"
+		}
+
+		if _type, ok := keyRefs[prefix+name]; ok && len(prefix) > 0 {
+			return Key{Type: _type, Alt: key.Alt}
+		}
+	}
+
+	key.Ctrl = ctrl
+	key.Shift = shift
+	key.Super = super
+	return key
+}
+
+// controlCode returns the legacy control character produced by pressing ctrl
+// and `r`.
+func controlCode(r rune) (KeyType, bool) {
+	switch {
+	case r >= 'a' && r <= 'z':
+		return KeyType(r - 'a' + 1), true
+	case r >= '@' && r <= '_':
+		return KeyType(r - '@'), true
+	case r == '?':
+		return keyDEL, true
+	case r == ' ':
+		return keyNUL, true
+	}
+
+	return 0, false
+}
+
+func parseKittyField(field string) (values []int) {
+	if len(field) == 0 {
+		return
+	}
+
+	for _, part := range strings.Split(field, ":") {
+		value, err := strconv.Atoi(part)
+		if err != nil {
+			value = 0
+		}
+		values = append(values, value)
+	}
+
+	return
+}
+
+// detectKitty parses key events encoded with the kitty keyboard protocol.
+func detectKitty(input []byte) (hasSeq bool, width int, msg Msg) {
+	match := kittyRe.FindSubmatch(input)
+	if match == nil {
+		return
+	}
+
+	var (
+		codes  = parseKittyField(string(match[1]))
+		params = parseKittyField(string(match[2]))
+		final  = match[4][0]
+		code   = 1
+		mods   = 0
+		event  = 1
+	)
+
+	if len(codes) > 0 {
+		code = codes[0]
+	}
+
+	if len(params) > 0 && params[0] > 0 {
+		mods = params[0] - 1
+	}
+
+	if len(params) > 1 {
+		event = params[1]
+	}
+
+	var base Key
+	switch final {
+	case 'u':
+		if _type, ok := kittyCodes[code]; ok {
+			base = Key{Type: _type}
+		} else if code > 0 && code < unicode.MaxRune && code < 0xE000 {
+			base = Key{Type: KeyRunes, Runes: []rune{rune(code)}}
+		} else {
+			// Functional keys in the private use area (such as
+			// keypad and media keys) are not supported
+			return
+		}
+	case '~':
+		_type, ok := kittyTildes[code]
+		if !ok {
+			return
+		}
+		base = Key{Type: _type}
+	default:
+		// Legacy sequences with no modifiers are handled elsewhere
+		if len(params) == 0 {
+			return
+		}
+
+		// Keys with letter finals always have a key code of 1, so
+		// anything else is some other sequence, such as a cursor
+		// position report ("\x1b[12;40R")
+		if len(codes) > 1 || code != 1 {
+			width = len(match[0])
+			hasSeq = true
+			msg = unknownCSISequenceMsg(input[:width])
+			return
+		}
+
+		base = Key{Type: kittyLetters[final]}
+	}
+
+	width = len(match[0])
+	hasSeq = true
+
+	// We only report key presses and repeats
+	if event == 3 {
+		msg = unknownCSISequenceMsg(input[:width])
+		return
+	}
+
+	msg = KeyMsg(newKey(base, mods))
+	return
+}
+
+// parseKeyName parses a human-readable key name (such as "ctrl+shift+a" or
+// "super+enter") into a Key.
+func parseKeyName(name string) (key Key, ok bool) {
+	if _type, ok := keyRefs[name]; ok {
+		return Key{Type: _type}, true
+	}
+
+	mods := 0
+	rest := name
+	for {
+		var found bool
+		for prefix, mod := range map[string]int{
+			"ctrl+":  kittyCtrl,
+			"alt+":   kittyAlt,
+			"shift+": kittyShift,
+			"super+": kittySuper,
+		} {
+			if !strings.HasPrefix(rest, prefix) || len(rest) == len(prefix) {
+				continue
+			}
+
+			mods |= mod
+			rest = rest[len(prefix):]
+			found = true
+		}
+
+		if !found {
+			break
+		}
+	}
+
+	if mods == 0 {
+		return
+	}
+
+	if _type, ok := keyRefs[rest]; ok {
+		return newKey(Key{Type: _type}, mods), true
+	}
+
+	if utf8.RuneCountInString(rest) != 1 {
+		return
+	}
+
+	r, _ := utf8.DecodeRuneInString(rest)
+	return newKey(Key{Type: KeyRunes, Runes: []rune{r}}, mods), true
+}
+
+// ParseKeyName translates a single human-readable key specifier (such as
+// "enter" or "ctrl+a") into a KeyMsg. Unlike KeysToMsg, it reports whether
+// `name` referred to a key instead of treating it as text.
+func ParseKeyName(name string) (KeyMsg, bool) {
+	key, ok := parseKeyName(name)
+	return KeyMsg(key), ok
+}
+
+// NormalizeKeyName converts a human-readable key name into the canonical
+// form produced by KeyMsg.String(), which allows modifiers to be specified
+// in any order. Names that cannot be parsed are returned unchanged.
+func NormalizeKeyName(name string) string {
+	key, ok := parseKeyName(name)
+	if !ok {
+		return name
+	}
+
+	return key.String()
+}
+
+// decompose splits a Key into the key that was pressed and the kitty
+// modifiers that were held.
+func (k Key) decompose() (base Key, mods int) {
+	if k.Alt {
+		mods |= kittyAlt
+	}
+	if k.Ctrl {
+		mods |= kittyCtrl
+	}
+	if k.Shift {
+		mods |= kittyShift
+	}
+	if k.Super {
+		mods |= kittySuper
+	}
+
+	switch {
+	case k.Type == KeyRunes || k.Type == KeySpace:
+		return Key{Type: k.Type, Runes: k.Runes}, mods
+	case k.Type == KeyTab || k.Type == KeyEnter || k.Type == KeyEscape || k.Type == KeyBackspace:
+		return Key{Type: k.Type}, mods
+	case k.Type == keyNUL:
+		return Key{Type: KeySpace, Runes: spaceRunes}, mods | kittyCtrl
+	case k.Type >= keySOH && k.Type <= keyUS:
+		r := rune(k.Type) + '@'
+		if r >= 'A' && r <= 'Z' {
+			r = unicode.ToLower(r)
+		}
+		return Key{Type: KeyRunes, Runes: []rune{r}}, mods | kittyCtrl
+	}
+
+	// Named keys such as "ctrl+shift+up"
+	name := keyNames[k.Type]
+	for {
+		if strings.HasPrefix(name, "ctrl+") {
+			mods |= kittyCtrl
+			name = name[5:]
+			continue
+		}
+		if strings.HasPrefix(name, "shift+") {
+			mods |= kittyShift
+			name = name[6:]
+			continue
+		}
+		break
+	}
+
+	if _type, ok := keyRefs[name]; ok {
+		return Key{Type: _type}, mods
+	}
+
+	return Key{Type: k.Type}, mods
+}
+
+func kittySequence(code int, mods int, final byte) []byte {
+	if mods == 0 {
+		if final == 'u' || final == '~' {
+			return []byte(fmt.Sprintf("\x1b[%d%c", code, final))
+		}
+		return []byte(fmt.Sprintf("\x1b[%c", final))
+	}
+
+	return []byte(fmt.Sprintf("\x1b[%d;%d%c", code, mods+1, final))
+}
+
+func kittyKeyToBytes(flags emu.KeyboardFlag, key KeyMsg) ([]byte, error) {
+	base, mods := Key(key).decompose()
+	reportAll := flags&emu.KeyboardReportAll != 0
+
+	// Legacy encoding is used for text and for unmodified keys
+	legacy := func() ([]byte, error) {
+		return KeysToBytes(KeyMsg(newKey(base, mods)))
+	}
+
+	switch base.Type {
+	case KeyRunes, KeySpace:
+		if len(base.Runes) != 1 {
+			return legacy()
+		}
+
+		if mods&^kittyShift == 0 && !reportAll {
+			return legacy()
+		}
+
+		r := base.Runes[0]
+		if unicode.IsUpper(r) {
+			r = unicode.ToLower(r)
+			mods |= kittyShift
+		}
+
+		return kittySequence(int(r), mods, 'u'), nil
+	case KeyEscape:
+		return kittySequence(27, mods, 'u'), nil
+	}
+
+	for code, _type := range kittyCodes {
+		if _type != base.Type {
+			continue
+		}
+
+		if mods == 0 && !reportAll {
+			return legacy()
+		}
+
+		return kittySequence(code, mods, 'u'), nil
+	}
+
+	if mods == 0 {
+		return legacy()
+	}
+
+	for final, _type := range kittyLetters {
+		if _type == base.Type {
+			return kittySequence(1, mods, final), nil
+		}
+	}
+
+	for code, _type := range kittyTildes {
+		if _type == base.Type {
+			return kittySequence(code, mods, '~'), nil
+		}
+	}
+
+	return legacy()
+}
+
+// KittyKeysToBytes encodes keys using the kitty keyboard protocol with the
+// enhancements described by `flags`.
+func KittyKeysToBytes(flags emu.KeyboardFlag, keys ...KeyMsg) (data []byte, err error) {
+	for _, key := range keys {
+		if key.Paste || flags == 0 {
+			var encoded []byte
+			encoded, err = KeysToBytes(key)
+			if err != nil {
+				return
+			}
+			data = append(data, encoded...)
+			continue
+		}
+
+		var encoded []byte
+		encoded, err = kittyKeyToBytes(flags, key)
+		if err != nil {
+			return
+		}
+		data = append(data, encoded...)
+	}
+	return
+}
@@ -0,0 +1,77 @@
+package taro
+
+import (
+	"testing"
+
+	"github.com/cfoust/cy/pkg/emu"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKittyDetect(t *testing.T) {
+	for input, expected := range map[string]string{
+		"\x1b[97;5u":  "ctrl+a",
+		"\x1b[97;6u":  "ctrl+shift+a",
+		"\x1b[97;7u":  "alt+ctrl+a",
+		"\x1b[97;9u":  "super+a",
+		"\x1b[13;5u":  "ctrl+enter",
+		"\x1b[27u":    "esc",
+		"\x1b[1;9A":   "super+up",
+		"\x1b[1;6A":   "ctrl+shift+up",
+		"\x1b[3;5~":   "ctrl+delete",
+		"\x1b[105;5u": "tab",
+		"\x1b[32;5u":  "ctrl+@",
+	} {
+		w, msg := DetectOneMsg([]byte(input))
+		assert.Equal(t, len(input), w, input)
+		key, ok := msg.(KeyMsg)
+		assert.True(t, ok, input)
+		assert.Equal(t, expected, key.String(), input)
+	}
+
+	// Cursor position reports are not keys
+	input := "\x1b[12;40R"
+	w, msg := DetectOneMsg([]byte(input))
+	assert.Equal(t, len(input), w)
+	assert.Equal(t, unknownCSISequenceMsg(input), msg)
+}
+
+func TestNormalizeKeyName(t *testing.T) {
+	for input, expected := range map[string]string{
+		"ctrl+a":           "ctrl+a",
+		"ctrl+i":           "tab",
+		"shift+ctrl+a":     "ctrl+shift+a",
+		"ctrl+alt+a":       "alt+ctrl+a",
+		"shift+ctrl+up":    "ctrl+shift+up",
+		"ctrl+super+enter": "super+ctrl+enter",
+		"test":             "test",
+		"+":                "+",
+	} {
+		assert.Equal(t, expected, NormalizeKeyName(input), input)
+	}
+}
+
+func TestKittyEncode(t *testing.T) {
+	flags := emu.KeyboardDisambiguate
+	for expected, key := range map[string]string{
+		"a":          "a",
+		"A":          "A",
+		"\x1b[97;5u": "ctrl+a",
+		"\x1b[97;6u": "ctrl+shift+a",
+		"\x1b[13;5u": "ctrl+enter",
+		"\r":         "enter",
+		"\x1b[27u":   "esc",
+		"\x1b[1;6A":  "ctrl+shift+up",
+		"\x1b[97;9u": "super+a",
+		"\x1b[3;5~":  "ctrl+delete",
+	} {
+		data, err := KittyKeysToBytes(flags, KeysToMsg(key)...)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, string(data), key)
+	}
+
+	// Without the kitty protocol we fall back to legacy sequences
+	data, err := KittyKeysToBytes(0, KeysToMsg("ctrl+shift+a")...)
+	assert.NoError(t, err)
+	assert.Equal(t, "\x01", string(data))
+}
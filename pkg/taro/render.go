@@ -52,8 +52,8 @@ func (r *Renderer) ConvertLipgloss(color lipgloss.Color) emu.Color {
 	case termenv.ANSI256Color:
 		return emu.Color(c)
 	case termenv.RGBColor:
-		r, g, b, _ := termenv.ConvertToRGB(c).RGBA()
-		return emu.Color(r<<16 | g<<8 | b)
+		r, g, b := termenv.ConvertToRGB(c).RGB255()
+		return emu.NewRGB(r, g, b)
 	}
 
 	return emu.DefaultFG